@@ -2,18 +2,36 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"text/tabwriter"
+	"time"
 
+	"github.com/fsoria-ttec/bne-converter/internal/admin"
+	"github.com/fsoria-ttec/bne-converter/internal/api"
+	"github.com/fsoria-ttec/bne-converter/internal/ckan"
 	"github.com/fsoria-ttec/bne-converter/internal/config"
 	"github.com/fsoria-ttec/bne-converter/internal/crawler"
 	"github.com/fsoria-ttec/bne-converter/internal/logger"
 	"github.com/fsoria-ttec/bne-converter/internal/logo"
+	"github.com/fsoria-ttec/bne-converter/internal/metadata"
+	"github.com/fsoria-ttec/bne-converter/internal/metrics"
 	"github.com/fsoria-ttec/bne-converter/internal/monitor"
+	"github.com/fsoria-ttec/bne-converter/internal/notify"
+	"github.com/fsoria-ttec/bne-converter/internal/oai"
+	"github.com/fsoria-ttec/bne-converter/internal/pipeline"
+	"github.com/fsoria-ttec/bne-converter/internal/report"
+	"github.com/fsoria-ttec/bne-converter/internal/schedule"
 	"github.com/fsoria-ttec/bne-converter/internal/spinner"
+	"github.com/fsoria-ttec/bne-converter/internal/status"
+	"github.com/fsoria-ttec/bne-converter/internal/storage"
 	"github.com/sirupsen/logrus" // logging
 )
 
@@ -23,6 +41,7 @@ type RunMode struct {
 	ForceUpdate bool
 	Debug       bool
 	Version     bool
+	ConfigPath  string
 }
 
 func main() {
@@ -30,6 +49,12 @@ func main() {
 	// Flags
 	mode := parseFlags()
 
+	// El comando status se atiende antes de la inicialización para que
+	// cualquier fallo devuelva UNKNOWN (3) y no 1
+	if flag.Arg(0) == "status" {
+		os.Exit(runStatus(mode, flag.Args()[1:]))
+	}
+
 	// Detectar si terminal soporta colores
 	useColors := true
 	if fileInfo, _ := os.Stdout.Stat(); (fileInfo.Mode() & os.ModeCharDevice) == 0 {
@@ -37,7 +62,7 @@ func main() {
 	}
 
 	// Configuración inicial
-	cfg, err := config.Load()
+	cfg, err := config.Load(mode.ConfigPath)
 
 	// Logger
 	log := logrus.New()
@@ -47,7 +72,11 @@ func main() {
 		log.Fatalf("Error al cargar configuración inicial: %v", err)
 	}
 
-	log.SetFormatter(logger.NewCustomFormatter(cfg.Logging, useColors))
+	logFile, err := logger.Configure(log, cfg.Logging, useColors)
+	if err != nil {
+		log.Fatalf("Error al configurar el log: %v", err)
+	}
+	defer logFile.Close()
 
 	if mode.Version {
 		logo.Print(log, cfg)
@@ -72,10 +101,64 @@ func main() {
 		log.Fatalf("Error al inicializar crawler: %v", err)
 	}
 
+	// Informe de ejecuciones
+	reports, err := report.NewStore(cfg.Crawler.DownloadPath)
+	if err != nil {
+		log.Fatalf("Error al inicializar informe de ejecución: %v", err)
+	}
+
+	// Manejar comando migrate
+	if flag.Arg(0) == "migrate" {
+		os.Exit(runMigrate(ctx, cfg, log, flag.Args()[1:]))
+	}
+
+	// Notificaciones de eventos
+	dispatcher, err := newDispatcher(cfg, log)
+	if err != nil {
+		log.Fatalf("Error al inicializar notificaciones: %v", err)
+	}
+	defer dispatcher.Close()
+
+	// Base de datos
+	var store *storage.Store
+	if cfg.Database.Enabled {
+		store, err = storage.New(cfg, log)
+		if err != nil {
+			log.Fatalf("Error al inicializar base de datos: %v", err)
+		}
+		defer store.Close()
+
+		// El esquema debe ser el que requiere esta versión. Las migraciones
+		// pendientes se aplican con migrate up salvo con database.auto_migrate
+		err := store.CheckSchema(ctx, cfg.Version)
+		if errors.Is(err, storage.ErrSchemaBehind) && cfg.Database.AutoMigrate {
+			log.Warnf("%v, aplicando migraciones (database.auto_migrate)", err)
+			_, err = store.Migrate(ctx, cfg.Version)
+		}
+		if errors.Is(err, storage.ErrSchemaBehind) {
+			log.Fatalf("Error al comprobar el esquema de base de datos: %v; ejecute migrate up", err)
+		}
+		if err != nil {
+			log.Fatalf("Error al comprobar el esquema de base de datos: %v", err)
+		}
+	}
+
+	// Publicación en CKAN
+	var publisher *ckan.Publisher
+	if cfg.CKAN.Enabled {
+		publisher = ckan.New(cfg, log)
+	}
+
+	pipe := pipeline.New(crw, reports, store, publisher, dispatcher, log)
+
 	// Manejar modo -manual
 	if mode.Manual {
 		log.Info("Modo Manual activo")
-		if err := runManualMode(ctx, cfg, crw, log); err != nil {
+		err := runManualMode(ctx, cfg, pipe, log)
+		if closeErr := dispatcher.Close(); closeErr != nil {
+			log.Warnf("Error al cerrar notificaciones: %v", closeErr)
+		}
+		if err != nil {
 			log.Fatalf("Error al ejecutar el modo manual: %v", err)
 		}
 		return
@@ -88,27 +171,74 @@ func main() {
 	// Manejar modo -forzar
 	if mode.ForceUpdate {
 		log.Info("Actualización forzada solicitada")
-		go func() {
-			results := crw.DownloadAll(ctx)
-			for _, result := range results {
-				if result.Error != nil {
-					log.Errorf("Error al descargar %s: %v", result.Category, result.Error)
-					continue
-				}
-				log.Infof("Descarga completada para %s en %s", result.Category, result.FilePath)
+		go pipe.Run(ctx, pipeline.Options{Mode: "forzar", Force: true})
+	}
 
-				if err := processDownloadedFile(ctx, result.FilePath, log); err != nil {
-					log.Errorf("Error al procesar %s: %v", result.FilePath, err)
-				}
-			}
-		}()
+	// Exponer métricas de Prometheus
+	if cfg.Metrics.Enabled {
+		metrics.Serve(ctx, cfg.Metrics.Address, log)
 	}
 
 	// Manejar modo monitor (opción por defecto)
-	mon := monitor.New(cfg, log)
+	mon, err := monitor.New(cfg, log)
+	if err != nil {
+		log.Fatalf("Error al inicializar monitor: %v", err)
+	}
 	changes, errs := mon.Start(ctx)
 	log.Info("Modo Monitor activo")
 
+	// API de administración
+	if cfg.Admin.Enabled {
+		admin.New(cfg, pipe, mon, crw, reports, log).Serve(ctx)
+	}
+
+	// API de consulta del catálogo
+	if cfg.API.Enabled {
+		api.New(cfg, store, reports, log).Serve(ctx)
+	}
+
+	// Proveedor de datos OAI-PMH
+	if cfg.OAI.Enabled {
+		oai.New(cfg, store, log).Serve(ctx)
+	}
+
+	// Ventana de descarga: los cambios detectados fuera de ella se aplazan
+	var window atomic.Pointer[downloadWindow]
+	window.Store(newDownloadWindow(&cfg.Monitor))
+	var pendingUpdate atomic.Bool
+
+	// Recargar configuración en caliente. Las recargas se serializan y cada
+	// componente recibe su propia copia de la configuración
+	var reloading sync.Mutex
+	current := cfg
+	config.Watch(func(next *config.Config) {
+		reloading.Lock()
+		defer reloading.Unlock()
+
+		merged, applied, refused := config.Reload(current, next)
+		for _, change := range refused {
+			log.Warnf("Cambio de configuración rechazado, requiere reinicio: %s", change)
+		}
+		if len(applied) == 0 {
+			return
+		}
+
+		current = merged
+		crawlerConfig, monitorConfig := current.Crawler, current.Monitor
+		crw.UpdateConfig(&crawlerConfig)
+		mon.UpdateConfig(&monitorConfig)
+		window.Store(newDownloadWindow(&monitorConfig))
+		if !mode.Debug {
+			log.SetLevel(current.Logging.GetLogLevel())
+		}
+
+		for _, change := range applied {
+			log.Infof("Configuración actualizada: %s", change)
+		}
+	}, func(err error) {
+		log.Errorf("Configuración recargada inválida, se mantiene la actual: %v", err)
+	})
+
 	// Iniciar spinner
 	spin := spinner.New("Monitorizando cambios...")
 	spin.Start(ctx)
@@ -123,19 +253,29 @@ func main() {
 			}
 			log.Infof("Cambio detectado en %s", change.URL)
 
-			go func() {
-				results := crw.DownloadAll(ctx)
-				for _, result := range results {
-					if result.Error != nil {
-						log.Errorf("Error al descargar %s: %v", result.Category, result.Error)
-						continue
-					}
-					log.Infof("Descarga completada para %s en %s", result.Category, result.FilePath)
+			event := notify.NewEvent(notify.EventChangeDetected)
+			event.URL = change.URL
+			dispatcher.Notify(event)
+
+			if !pendingUpdate.CompareAndSwap(false, true) {
+				log.Info("Ya hay una actualización aplazada pendiente")
+				continue
+			}
 
-					if err := processDownloadedFile(ctx, result.FilePath, log); err != nil {
-						log.Errorf("Error al procesar %s: %v", result.FilePath, err)
+			go func() {
+				downloads := window.Load()
+				now := time.Now()
+				if open := downloads.nextOpen(now); open.After(now) {
+					log.Infof("Descarga aplazada hasta la ventana %s (%s)", downloads.window, open.Format(cfg.Logging.TimestampFormat))
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(open.Sub(now)):
 					}
 				}
+
+				pendingUpdate.Store(false)
+				pipe.Run(ctx, pipeline.Options{Mode: "monitor"})
 			}()
 
 		case err, ok := <-errs:
@@ -155,6 +295,26 @@ func main() {
 
 }
 
+// downloadWindow es la ventana de descarga en la zona horaria del monitor; se
+// sustituye entera al recargar la configuración
+type downloadWindow struct {
+	window   *schedule.Window
+	location *time.Location
+}
+
+// newDownloadWindow interpreta la ventana de una configuración ya validada
+func newDownloadWindow(cfg *config.MonitorConfig) *downloadWindow {
+	window, _ := schedule.ParseWindow(cfg.DownloadWindow)
+	location, _ := schedule.Location(cfg.Timezone)
+	return &downloadWindow{window: window, location: location}
+}
+
+// nextOpen devuelve now si se puede descargar o la próxima apertura de la
+// ventana, en la zona horaria del monitor
+func (d *downloadWindow) nextOpen(now time.Time) time.Time {
+	return d.window.NextOpen(now.In(d.location))
+}
+
 func parseFlags() RunMode {
 	mode := RunMode{}
 
@@ -163,31 +323,39 @@ func parseFlags() RunMode {
 	flag.BoolVar(&mode.ForceUpdate, "forzar", false, "Forzar actualización y monitorizar")
 	flag.BoolVar(&mode.Debug, "debug", false, "Activar logs de debug")
 	flag.BoolVar(&mode.Version, "version", false, "Información de versión")
+	flag.StringVar(&mode.ConfigPath, "config", "", "Ruta del fichero de configuración (por defecto config.yaml en ./configs o el directorio actual)")
 
 	flag.Parse()
 	return mode
 }
 
-func runManualMode(ctx context.Context, cfg *config.Config, crw *crawler.Crawler,
-	log *logrus.Logger) error {
-	log.Infof("Ejecutando descarga en %s...", cfg.Crawler.DownloadPath)
-
-	results := crw.DownloadAll(ctx)
+func newDispatcher(cfg *config.Config, log *logrus.Logger) (*notify.Dispatcher, error) {
+	var notifiers []notify.Notifier
 
-	var hasErrors bool
-	for _, result := range results {
-		if result.Error != nil {
-			hasErrors = true
-			continue
+	if cfg.Notifications.Webhook.Enabled {
+		webhook, err := notify.NewWebhook(cfg, log)
+		if err != nil {
+			return nil, err
 		}
+		notifiers = append(notifiers, webhook)
+	}
 
-		if err := processDownloadedFile(ctx, result.FilePath, log); err != nil {
-			log.Errorf("Error al procesar %s: %v", result.FilePath, err)
-			hasErrors = true
+	if cfg.Notifications.Email.Enabled {
+		email, err := notify.NewEmail(cfg, log)
+		if err != nil {
+			return nil, err
 		}
+		notifiers = append(notifiers, email)
 	}
 
-	if hasErrors {
+	return notify.NewDispatcher(notifiers...), nil
+}
+
+func runManualMode(ctx context.Context, cfg *config.Config, pipe *pipeline.Pipeline,
+	log *logrus.Logger) error {
+	log.Infof("Ejecutando descarga en %s...", cfg.Crawler.DownloadPath)
+
+	if run := pipe.Run(ctx, pipeline.Options{Mode: "manual"}); run.HasErrors() {
 		return fmt.Errorf("algunas descargas o procesamientos fallaron, revisa los logs para más detalles")
 	}
 
@@ -195,11 +363,144 @@ func runManualMode(ctx context.Context, cfg *config.Config, crw *crawler.Crawler
 	return nil
 }
 
-func processDownloadedFile(ctx context.Context, filePath string, logger *logrus.Logger) error {
-	// TODO: Implementar procesamiento del archivo
-	// - Validar XML
-	// - Parsear contenido
-	// - Transformar datos
-	// - Almacenar en base de datos
-	return nil
+// runStatus muestra el estado de cada categoría y devuelve un código de salida
+// estilo Nagios. Los errores propios del comando devuelven UNKNOWN
+func runStatus(mode RunMode, args []string) int {
+	flags := flag.NewFlagSet("status", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "Mostrar el estado en formato JSON")
+	local := flags.Bool("local", false, "No consultar la web de la BNE")
+	if err := flags.Parse(args); err != nil {
+		return status.CodeUnknown
+	}
+
+	unknown := func(format string, args ...any) int {
+		fmt.Fprintf(os.Stderr, "UNKNOWN: "+format+"\n", args...)
+		return status.CodeUnknown
+	}
+
+	cfg, err := config.Load(mode.ConfigPath)
+	if err != nil {
+		return unknown("%v", err)
+	}
+
+	// Solo avisos y por la salida de errores, para no mezclarlos con el estado
+	log := logrus.New()
+	log.SetOutput(os.Stderr)
+	log.SetLevel(logrus.WarnLevel)
+
+	crw, err := crawler.New(cfg, log)
+	if err != nil {
+		return unknown("error al inicializar crawler: %v", err)
+	}
+	reports, err := report.NewStore(cfg.Crawler.DownloadPath)
+	if err != nil {
+		return unknown("error al inicializar informe de ejecución: %v", err)
+	}
+	files, err := metadata.NewMetadataStore(cfg.Crawler.DownloadPath)
+	if err != nil {
+		return unknown("%v", err)
+	}
+
+	var remote status.RemoteChecker = crw
+	if *local {
+		remote = nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Monitor.Timeout)
+	defer cancel()
+
+	summary := status.Collect(ctx, crw.Categories(), remote, files, reports)
+
+	if *asJSON {
+		err = status.WriteJSON(os.Stdout, summary)
+	} else {
+		err = status.WriteTable(os.Stdout, summary, cfg.Logging.TimestampFormat)
+	}
+	if err != nil {
+		return unknown("%v", err)
+	}
+
+	return summary.Code
+}
+
+// runMigrate gestiona el esquema de base de datos: status, up o down N
+func runMigrate(ctx context.Context, cfg *config.Config, log *logrus.Logger, args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Uso: migrate status | up | down N")
+	}
+	flags.Parse(args)
+
+	steps := 0
+	switch flags.Arg(0) {
+	case "status", "up":
+	case "down":
+		var err error
+		if steps, err = strconv.Atoi(flags.Arg(1)); err != nil || steps <= 0 {
+			flags.Usage()
+			return 2
+		}
+	default:
+		flags.Usage()
+		return 2
+	}
+
+	store, err := storage.New(cfg, log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error al inicializar base de datos: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	switch flags.Arg(0) {
+	case "status":
+		states, initialized, err := store.MigrationStatus(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error al consultar migraciones: %v\n", err)
+			return 1
+		}
+		if !initialized {
+			fmt.Println("Sin tabla de migraciones (schema_migrations): base de datos sin inicializar")
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSIÓN\tNOMBRE\tESTADO\tAPLICADA\tAPLICACIÓN")
+		pending := 0
+		for _, state := range states {
+			status, appliedAt := "pendiente", "-"
+			switch {
+			case state.Unknown:
+				status = "desconocida"
+			case !state.Applied:
+				pending++
+			default:
+				status = "aplicada"
+			}
+			if state.AppliedAt != nil {
+				appliedAt = state.AppliedAt.Format(cfg.Logging.TimestampFormat)
+			}
+			fmt.Fprintf(tw, "%04d\t%s\t%s\t%s\t%s\n", state.Version, state.Name, status, appliedAt, state.AppVersion)
+		}
+		tw.Flush()
+		fmt.Printf("Esquema esperado por la versión %s: %04d (%d pendientes)\n", cfg.Version, storage.LatestVersion(), pending)
+		return 0
+
+	case "up":
+		count, err := store.Migrate(ctx, cfg.Version)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error al aplicar migraciones: %v\n", err)
+			return 1
+		}
+		log.Infof("%d migraciones aplicadas", count)
+		return 0
+
+	default:
+		count, err := store.Rollback(ctx, steps, cfg.Version)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error al revertir migraciones: %v\n", err)
+			return 1
+		}
+		log.Infof("%d migraciones revertidas", count)
+		return 0
+	}
 }
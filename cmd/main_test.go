@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fsoria-ttec/bne-converter/internal/config"
+)
+
+func TestDownloadWindow(t *testing.T) {
+	utc := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	// Europe/Madrid: UTC+1 en invierno, UTC+2 del 29 de marzo (01:00 UTC) al
+	// 25 de octubre (01:00 UTC)
+	tests := []struct {
+		name   string
+		window string
+		now    time.Time
+		want   time.Time
+	}{
+		{name: "invierno, antes de abrir", window: "22:00-06:00", now: utc(1, 15, 12, 0), want: utc(1, 15, 21, 0)},
+		{name: "invierno, pasada la medianoche", window: "22:00-06:00", now: utc(1, 15, 23, 30), want: utc(1, 15, 23, 30)},
+		{name: "invierno, recién cerrada", window: "22:00-06:00", now: utc(1, 16, 5, 30), want: utc(1, 16, 21, 0)},
+		{name: "verano, abierta en hora local", window: "22:00-06:00", now: utc(7, 15, 20, 30), want: utc(7, 15, 20, 30)},
+		{name: "verano, antes de abrir", window: "22:00-06:00", now: utc(7, 15, 19, 30), want: utc(7, 15, 20, 0)},
+		{name: "cruza la medianoche el día del cambio", window: "23:00-01:00", now: utc(3, 28, 12, 0), want: utc(3, 28, 22, 0)},
+		// La apertura cae en la hora que se salta: 02:30 pasa a 03:30 CEST
+		{name: "primavera, apertura desplazada", window: "02:30-04:00", now: utc(3, 28, 23, 0), want: utc(3, 29, 1, 30)},
+		// La ventana entera cae en la hora que se salta: abre al día siguiente
+		{name: "primavera, ventana inexistente", window: "02:30-03:00", now: utc(3, 28, 23, 0), want: utc(3, 30, 0, 30)},
+		{name: "primavera, día siguiente", window: "02:30-03:00", now: utc(3, 29, 2, 0), want: utc(3, 30, 0, 30)},
+		// Las 02:30 se repiten: primero en CEST y después en CET
+		{name: "otoño, primera apertura", window: "02:30-04:00", now: utc(10, 24, 23, 0), want: utc(10, 25, 0, 30)},
+		{name: "otoño, segunda apertura", window: "02:30-02:45", now: utc(10, 25, 0, 50), want: utc(10, 25, 1, 30)},
+		{name: "otoño, día siguiente", window: "02:30-02:45", now: utc(10, 25, 1, 50), want: utc(10, 26, 1, 30)},
+		{name: "sin ventana", now: utc(3, 29, 1, 30), want: utc(3, 29, 1, 30)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			downloads := newDownloadWindow(&config.MonitorConfig{DownloadWindow: test.window, Timezone: "Europe/Madrid"})
+			if open := downloads.nextOpen(test.now); !open.Equal(test.want) {
+				t.Errorf("nextOpen(%s) = %s, se esperaba %s", test.now, open.UTC(), test.want)
+			}
+		})
+	}
+}
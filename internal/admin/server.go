@@ -0,0 +1,231 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fsoria-ttec/bne-converter/internal/config"
+	"github.com/fsoria-ttec/bne-converter/internal/constants"
+	"github.com/fsoria-ttec/bne-converter/internal/crawler"
+	"github.com/fsoria-ttec/bne-converter/internal/metadata"
+	"github.com/fsoria-ttec/bne-converter/internal/monitor"
+	"github.com/fsoria-ttec/bne-converter/internal/pipeline"
+	"github.com/fsoria-ttec/bne-converter/internal/report"
+	"github.com/fsoria-ttec/bne-converter/internal/status"
+	"github.com/sirupsen/logrus" // logging
+)
+
+// Server expone una API HTTP local para consultar y controlar el monitor
+type Server struct {
+	config       *config.AdminConfig
+	downloadPath string
+	pipeline     *pipeline.Pipeline
+	monitor      *monitor.Monitor
+	crawler      *crawler.Crawler
+	reports      *report.Store
+	logger       *logrus.Logger
+	ctx          context.Context
+}
+
+type runRequest struct {
+	Categories []string `json:"categories"`
+	Force      bool     `json:"force"`
+}
+
+type runResponse struct {
+	Run      *report.Run `json:"run"`
+	Progress progress    `json:"progress"`
+}
+
+type progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+func New(cfg *config.Config, pipe *pipeline.Pipeline, mon *monitor.Monitor, crw *crawler.Crawler,
+	reports *report.Store, logger *logrus.Logger) *Server {
+	return &Server{
+		config:       &cfg.Admin,
+		downloadPath: cfg.Crawler.DownloadPath,
+		pipeline:     pipe,
+		monitor:      mon,
+		crawler:      crw,
+		reports:      reports,
+		logger:       logger,
+	}
+}
+
+// Serve atiende peticiones en la dirección configurada hasta que se cancele el contexto
+func (s *Server) Serve(ctx context.Context) {
+	s.ctx = ctx
+
+	server := &http.Server{
+		Addr:              s.config.Address,
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	go func() {
+		s.logger.WithField("address", s.config.Address).Info("API de administración disponible")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.WithError(err).Error("Error en la API de administración")
+		}
+	}()
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	// Sondas sin autenticación
+	mux.HandleFunc("GET /health", s.handleHealth)
+	mux.HandleFunc("GET /ready", s.handleReady)
+
+	mux.Handle("GET /status", s.authenticate(s.handleStatus))
+	mux.Handle("POST /runs", s.authenticate(s.handleCreateRun))
+	mux.Handle("GET /runs/{id}", s.authenticate(s.handleGetRun))
+	mux.Handle("POST /monitor/pause", s.authenticate(s.handlePause))
+	mux.Handle("POST /monitor/resume", s.authenticate(s.handleResume))
+
+	return mux
+}
+
+func (s *Server) authenticate(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(s.config.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "token de acceso inválido")
+			return
+		}
+		next(w, r)
+	})
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if s.ctx.Err() != nil {
+		writeError(w, http.StatusServiceUnavailable, "finalizando ejecución")
+		return
+	}
+	if _, err := os.Stat(s.downloadPath); err != nil && !os.IsNotExist(err) {
+		writeError(w, http.StatusServiceUnavailable, fmt.Sprintf("directorio de descarga inaccesible (%v)", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	files, err := metadata.NewMetadataStore(s.downloadPath)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// La consulta a la BNE es opcional: ?remote=true
+	var remote status.RemoteChecker
+	if r.URL.Query().Get("remote") == "true" {
+		remote = s.crawler
+	}
+
+	summary := status.Collect(r.Context(), s.crawler.Categories(), remote, files, s.reports)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"monitor": map[string]bool{"paused": s.monitor.Paused()},
+		"status":  summary,
+	})
+}
+
+func (s *Server) handleCreateRun(w http.ResponseWriter, r *http.Request) {
+	var request runRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("cuerpo de petición inválido (%v)", err))
+		return
+	}
+
+	// Solo se admiten categorías con origen configurado
+	available := make(map[string]bool)
+	for _, category := range s.crawler.Categories() {
+		available[category.Id] = true
+	}
+	for _, category := range request.Categories {
+		switch {
+		case !constants.IsBNECategory(category):
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("categoría desconocida (%s)", category))
+			return
+		case !available[category]:
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("categoría sin origen configurado (%s)", category))
+			return
+		}
+	}
+
+	run := s.pipeline.Start(s.ctx, pipeline.Options{
+		Mode:       "api",
+		Categories: request.Categories,
+		Force:      request.Force,
+	})
+
+	s.logger.WithFields(logrus.Fields{
+		"run_id":     run.ID,
+		"categories": strings.Join(run.Requested, ","),
+		"force":      request.Force,
+	}).Info("Ejecución solicitada desde la API de administración")
+
+	w.Header().Set("Location", "/runs/"+run.ID)
+	writeJSON(w, http.StatusAccepted, newRunResponse(run))
+}
+
+func (s *Server) handleGetRun(w http.ResponseWriter, r *http.Request) {
+	run, exists := s.pipeline.Get(r.PathValue("id"))
+	if !exists {
+		writeError(w, http.StatusNotFound, "ejecución no encontrada")
+		return
+	}
+	writeJSON(w, http.StatusOK, newRunResponse(run))
+}
+
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	s.monitor.Pause()
+	s.logger.Info("Monitor en pausa desde la API de administración")
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": true})
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	s.monitor.Resume()
+	s.logger.Info("Monitor reanudado desde la API de administración")
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": false})
+}
+
+func newRunResponse(run *report.Run) runResponse {
+	done, total := run.Progress()
+	return runResponse{
+		Run:      run,
+		Progress: progress{Done: done, Total: total},
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]string{"error": message})
+}
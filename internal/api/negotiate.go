@@ -0,0 +1,61 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+)
+
+// negotiate elige el formato de offered que prefiere la cabecera Accept. A
+// igual calidad gana el primero de offered; sin cabecera, también
+func negotiate(accept string, offered []string) (string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return offered[0], true
+	}
+
+	best, bestQuality := "", 0.0
+	for _, mediaType := range offered {
+		if quality := acceptQuality(accept, mediaType); quality > bestQuality {
+			best, bestQuality = mediaType, quality
+		}
+	}
+	return best, bestQuality > 0
+}
+
+// acceptQuality devuelve la calidad que la cabecera Accept asigna a un
+// formato, tomando el rango más específico que lo incluye
+func acceptQuality(accept, mediaType string) float64 {
+	mainType, _, _ := strings.Cut(mediaType, "/")
+
+	quality, specificity := 0.0, -1
+	for _, entry := range strings.Split(accept, ",") {
+		params := strings.Split(entry, ";")
+		mediaRange := strings.ToLower(strings.TrimSpace(params[0]))
+
+		var rangeSpecificity int
+		switch mediaRange {
+		case mediaType:
+			rangeSpecificity = 2
+		case mainType + "/*":
+			rangeSpecificity = 1
+		case "*/*":
+			rangeSpecificity = 0
+		default:
+			continue
+		}
+		if rangeSpecificity <= specificity {
+			continue
+		}
+
+		rangeQuality := 1.0
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					rangeQuality = parsed
+				}
+			}
+		}
+		quality, specificity = rangeQuality, rangeSpecificity
+	}
+	return quality
+}
@@ -0,0 +1,273 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fsoria-ttec/bne-converter/internal/config"
+	"github.com/fsoria-ttec/bne-converter/internal/constants"
+	"github.com/fsoria-ttec/bne-converter/internal/report"
+	"github.com/fsoria-ttec/bne-converter/internal/storage"
+	"github.com/fsoria-ttec/bne-converter/pkg/models"
+	"github.com/sirupsen/logrus" // logging
+)
+
+// Formatos de registro que admite GET /records/{id}, por orden de preferencia
+const (
+	MediaJSON     = "application/json"
+	MediaMARCJSON = "application/marc+json"
+	MediaMARCXML  = "application/marcxml+xml"
+	MediaISO2709  = "application/marc"
+)
+
+var recordMediaTypes = []string{MediaJSON, MediaMARCJSON, MediaMARCXML, MediaISO2709}
+
+// Catalog consulta los registros almacenados que expone la API
+type Catalog interface {
+	Record(ctx context.Context, controlNumber string) (*storage.RecordVersion, error)
+	Search(ctx context.Context, query string, filters storage.SearchFilters, page storage.Page) (*storage.SearchResult, error)
+	CategoryCounts(ctx context.Context) (map[string]storage.CategoryCount, error)
+}
+
+// Server expone el catálogo convertido en una API HTTP de solo lectura
+type Server struct {
+	config  *config.APIConfig
+	store   Catalog
+	reports *report.Store
+	logger  *logrus.Logger
+}
+
+type categoryResponse struct {
+	Id          string     `json:"id"`
+	Description string     `json:"description"`
+	Family      string     `json:"family"`
+	Records     int        `json:"records"`
+	Deleted     int        `json:"deleted"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`   // último cambio en los registros almacenados
+	LastSuccess *time.Time `json:"last_success,omitempty"` // último procesamiento correcto del fichero
+}
+
+func New(cfg *config.Config, store Catalog, reports *report.Store, logger *logrus.Logger) *Server {
+	return &Server{
+		config:  &cfg.API,
+		store:   store,
+		reports: reports,
+		logger:  logger,
+	}
+}
+
+// Serve atiende peticiones en la dirección configurada hasta que se cancele el contexto
+func (s *Server) Serve(ctx context.Context) {
+	server := &http.Server{
+		Addr:              s.config.Address,
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	go func() {
+		s.logger.WithField("address", s.config.Address).Info("API de consulta disponible")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.WithError(err).Error("Error en la API de consulta")
+		}
+	}()
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /records", s.handleSearch)
+	mux.HandleFunc("GET /records/{id}", s.handleRecord)
+	mux.HandleFunc("GET /categories", s.handleCategories)
+
+	return mux
+}
+
+func (s *Server) handleRecord(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Vary", "Accept")
+
+	mediaType, acceptable := negotiate(r.Header.Get("Accept"), recordMediaTypes)
+	if !acceptable {
+		writeError(w, http.StatusNotAcceptable, "formato no disponible; se admite "+strings.Join(recordMediaTypes, ", "))
+		return
+	}
+
+	version, err := s.store.Record(r.Context(), r.PathValue("id"))
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		writeError(w, http.StatusNotFound, "registro no encontrado")
+		return
+	case err != nil:
+		s.internalError(w, err)
+		return
+	case version.Deleted:
+		writeError(w, http.StatusGone, "registro eliminado de los ficheros de la BNE")
+		return
+	}
+
+	w.Header().Set("Last-Modified", version.ValidFrom.UTC().Format(http.TimeFormat))
+
+	switch mediaType {
+	case MediaMARCJSON:
+		writeBody(w, mediaType, version.Record.MARCJSON(), json.Marshal)
+
+	case MediaMARCXML:
+		writeBody(w, mediaType, version.Record.MARCXML(), func(v any) ([]byte, error) {
+			data, err := xml.Marshal(v)
+			return append([]byte(xml.Header), data...), err
+		})
+
+	case MediaISO2709:
+		data, err := version.Record.ISO2709()
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", mediaType)
+		w.Write(data)
+
+	default:
+		var body any
+		if version.Family == constants.FamilyAuthority {
+			if body, err = models.NewAuthority(version.Category, version.Record); err != nil {
+				s.internalError(w, err)
+				return
+			}
+		} else {
+			body = models.NewBibliographic(version.Category, version.Record)
+		}
+		writeJSON(w, http.StatusOK, body)
+	}
+}
+
+// handleSearch busca registros bibliográficos: ?q=&category=&language=&year=&limit=&cursor=.
+// year admite un año (1990) o un rango (1990-2000, 1990-, -2000)
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filters := storage.SearchFilters{
+		Category: query.Get("category"),
+		Language: query.Get("language"),
+	}
+	if filters.Category != "" && !constants.IsBNECategory(filters.Category) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("categoría desconocida (%s)", filters.Category))
+		return
+	}
+
+	var err error
+	if filters.YearFrom, filters.YearTo, err = parseYears(query.Get("year")); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page := storage.Page{Cursor: query.Get("cursor")}
+	if limit := query.Get("limit"); limit != "" {
+		if page.Limit, err = strconv.Atoi(limit); err != nil || page.Limit <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("límite inválido (%s)", limit))
+			return
+		}
+	}
+
+	result, err := s.store.Search(r.Context(), query.Get("q"), filters, page)
+	switch {
+	case errors.Is(err, storage.ErrInvalidCursor):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case err != nil:
+		s.internalError(w, err)
+	default:
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+func (s *Server) handleCategories(w http.ResponseWriter, r *http.Request) {
+	counts, err := s.store.CategoryCounts(r.Context())
+	if err != nil {
+		s.internalError(w, err)
+		return
+	}
+
+	categories := make([]categoryResponse, 0, len(constants.BNECategories))
+	for _, category := range constants.BNECategories {
+		response := categoryResponse{
+			Id:          category.Id,
+			Description: category.Description,
+			Family:      category.Family,
+		}
+		if count, exists := counts[category.Id]; exists {
+			response.Records, response.Deleted = count.Records, count.Deleted
+			response.UpdatedAt = &count.UpdatedAt
+		}
+		if result, exists := s.reports.Category(category.Id); exists && !result.LastSuccess.IsZero() {
+			response.LastSuccess = &result.LastSuccess
+		}
+		categories = append(categories, response)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"categories": categories})
+}
+
+func (s *Server) internalError(w http.ResponseWriter, err error) {
+	s.logger.WithError(err).Error("Error en la API de consulta")
+	writeError(w, http.StatusInternalServerError, "error interno")
+}
+
+// parseYears interpreta el filtro de años; 0 indica sin límite
+func parseYears(value string) (int, int, error) {
+	if value == "" {
+		return 0, 0, nil
+	}
+
+	from, to, isRange := strings.Cut(value, "-")
+	if !isRange {
+		to = from
+	}
+
+	var years [2]int
+	for i, part := range []string{from, to} {
+		if part == "" {
+			continue
+		}
+		year, err := strconv.Atoi(part)
+		if err != nil || year <= 0 {
+			return 0, 0, fmt.Errorf("año inválido (%s)", value)
+		}
+		years[i] = year
+	}
+	if years[0] == 0 && years[1] == 0 || years[1] != 0 && years[0] > years[1] {
+		return 0, 0, fmt.Errorf("rango de años inválido (%s)", value)
+	}
+	return years[0], years[1], nil
+}
+
+func writeBody(w http.ResponseWriter, mediaType string, body any, marshal func(any) ([]byte, error)) {
+	data, err := marshal(body)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.Write(data)
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]string{"error": message})
+}
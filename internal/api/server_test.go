@@ -0,0 +1,336 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fsoria-ttec/bne-converter/internal/config"
+	"github.com/fsoria-ttec/bne-converter/internal/constants"
+	"github.com/fsoria-ttec/bne-converter/internal/parser"
+	"github.com/fsoria-ttec/bne-converter/internal/report"
+	"github.com/fsoria-ttec/bne-converter/internal/storage"
+	"github.com/fsoria-ttec/bne-converter/pkg/models"
+	"github.com/sirupsen/logrus" // logging
+)
+
+// catalog es un catálogo en memoria que guarda la última búsqueda recibida
+type catalog struct {
+	versions map[string]*storage.RecordVersion
+	counts   map[string]storage.CategoryCount
+
+	filters storage.SearchFilters
+	page    storage.Page
+}
+
+func (c *catalog) Record(ctx context.Context, controlNumber string) (*storage.RecordVersion, error) {
+	version, exists := c.versions[controlNumber]
+	if !exists {
+		return nil, storage.ErrNotFound
+	}
+	return version, nil
+}
+
+func (c *catalog) Search(ctx context.Context, query string, filters storage.SearchFilters, page storage.Page) (*storage.SearchResult, error) {
+	c.filters, c.page = filters, page
+	if page.Cursor == "no-válido" {
+		return nil, storage.ErrInvalidCursor
+	}
+	return &storage.SearchResult{Hits: []storage.SearchHit{}, Next: "c2lndWllbnRl"}, nil
+}
+
+func (c *catalog) CategoryCounts(ctx context.Context) (map[string]storage.CategoryCount, error) {
+	return c.counts, nil
+}
+
+var modified = time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC)
+
+func quijote() *models.Record {
+	return &models.Record{
+		Leader: "00000nam a2200000 i 4500",
+		ControlFields: []models.ControlField{
+			{Tag: "001", Value: "bimo0000000001"},
+			{Tag: "008", Value: "850101s1605    sp            000 1 spa d"},
+		},
+		DataFields: []models.DataField{
+			{Tag: "100", Ind1: "1", Ind2: " ", Subfields: []models.Subfield{
+				{Code: "a", Value: "Cervantes Saavedra, Miguel de"},
+				{Code: "d", Value: "1547-1616"},
+			}},
+			{Tag: "245", Ind1: "1", Ind2: "0", Subfields: []models.Subfield{
+				{Code: "a", Value: "El ingenioso hidalgo don Quijote de la Mancha /"},
+				{Code: "c", Value: "compuesto por Miguel de Ceruantes Saauedra ; edición y notas de Francisco Rico"},
+			}},
+			{Tag: "264", Ind1: " ", Ind2: "1", Subfields: []models.Subfield{
+				{Code: "a", Value: "En Madrid :"},
+				{Code: "b", Value: "por Iuan de la Cuesta, a costa de Francisco de Robles, librero del Rey nuestro señor,"},
+				{Code: "c", Value: "1605"},
+			}},
+		},
+	}
+}
+
+func newTestServer(t *testing.T) (*httptest.Server, *catalog) {
+	t.Helper()
+
+	store := &catalog{
+		versions: map[string]*storage.RecordVersion{
+			"bimo0000000001": {
+				Family: constants.FamilyBibliographic, ControlNumber: "bimo0000000001",
+				Category: "MONOMODERN", Record: quijote(), ValidFrom: modified,
+			},
+			"bimo0000000002": {
+				Family: constants.FamilyBibliographic, ControlNumber: "bimo0000000002",
+				Category: "MONOMODERN", Record: quijote(), Deleted: true, ValidFrom: modified,
+			},
+		},
+		counts: map[string]storage.CategoryCount{
+			"MONOMODERN": {Category: "MONOMODERN", Records: 1, Deleted: 1, UpdatedAt: modified},
+		},
+	}
+
+	reports, err := report.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	server := httptest.NewServer(New(&config.Config{}, store, reports, logger).routes())
+	t.Cleanup(server.Close)
+	return server, store
+}
+
+func get(t *testing.T, url, accept string) (*http.Response, []byte) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	return resp, body
+}
+
+func TestRecordNegotiation(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	tests := []struct {
+		accept      string
+		status      int
+		contentType string
+	}{
+		{accept: "", status: http.StatusOK, contentType: MediaJSON},
+		{accept: "*/*", status: http.StatusOK, contentType: MediaJSON},
+		{accept: MediaMARCJSON, status: http.StatusOK, contentType: MediaMARCJSON},
+		{accept: MediaMARCXML, status: http.StatusOK, contentType: MediaMARCXML},
+		{accept: MediaISO2709, status: http.StatusOK, contentType: MediaISO2709},
+		{accept: "text/html, application/marc", status: http.StatusOK, contentType: MediaISO2709},
+		{accept: "application/json;q=0.1, application/marcxml+xml;q=0.9", status: http.StatusOK, contentType: MediaMARCXML},
+		// El rango más específico prevalece sobre application/*
+		{accept: "application/*, application/json;q=0", status: http.StatusOK, contentType: MediaMARCJSON},
+		{accept: "text/html", status: http.StatusNotAcceptable, contentType: MediaJSON},
+		{accept: "application/json;q=0", status: http.StatusNotAcceptable, contentType: MediaJSON},
+	}
+
+	for _, test := range tests {
+		resp, _ := get(t, server.URL+"/records/bimo0000000001", test.accept)
+		if resp.StatusCode != test.status || resp.Header.Get("Content-Type") != test.contentType {
+			t.Errorf("Accept %q: %d %s, se esperaba %d %s", test.accept,
+				resp.StatusCode, resp.Header.Get("Content-Type"), test.status, test.contentType)
+		}
+		if resp.Header.Get("Vary") != "Accept" {
+			t.Errorf("Accept %q: Vary = %q", test.accept, resp.Header.Get("Vary"))
+		}
+	}
+}
+
+func TestRecordFormats(t *testing.T) {
+	server, _ := newTestServer(t)
+	url := server.URL + "/records/bimo0000000001"
+
+	resp, body := get(t, url, MediaJSON)
+	if resp.Header.Get("Last-Modified") != modified.Format(http.TimeFormat) {
+		t.Errorf("Last-Modified = %q", resp.Header.Get("Last-Modified"))
+	}
+	var bibliographic models.Bibliographic
+	if err := json.Unmarshal(body, &bibliographic); err != nil {
+		t.Fatalf("JSON no válido: %v", err)
+	}
+	if bibliographic.ControlNumber != "bimo0000000001" || bibliographic.Category != "MONOMODERN" {
+		t.Errorf("registro JSON %+v", bibliographic)
+	}
+
+	_, body = get(t, url, MediaMARCXML)
+	if !strings.HasPrefix(string(body), xml.Header) {
+		t.Errorf("MARCXML sin declaración XML:\n%s", body)
+	}
+	var marcxml models.MARCXML
+	if err := xml.Unmarshal(body, &marcxml); err != nil {
+		t.Fatalf("MARCXML no válido: %v", err)
+	}
+	if marcxml.Leader != quijote().Leader {
+		t.Errorf("cabecera MARCXML %q", marcxml.Leader)
+	}
+
+	_, body = get(t, url, MediaMARCJSON)
+	var marcjson models.MARCJSON
+	if err := json.Unmarshal(body, &marcjson); err != nil {
+		t.Fatalf("MARC-in-JSON no válido: %v", err)
+	}
+	if len(marcjson.Fields) != 5 {
+		t.Errorf("MARC-in-JSON con %d campos", len(marcjson.Fields))
+	}
+}
+
+// El ISO 2709 servido se lee con el mismo lector que los ficheros de la BNE
+func TestRecordISO2709RoundTrip(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	_, body := get(t, server.URL+"/records/bimo0000000001", MediaISO2709)
+	reader := parser.NewReader(strings.NewReader(string(body)))
+	record, err := reader.Next()
+	if err != nil {
+		t.Fatalf("ISO 2709 no válido: %v", err)
+	}
+	if _, err := reader.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("se esperaba un solo registro: %v", err)
+	}
+
+	original := quijote()
+	if !reflect.DeepEqual(record.ControlFields, original.ControlFields) ||
+		!reflect.DeepEqual(record.DataFields, original.DataFields) {
+		t.Errorf("registro leído\n%+v\nse esperaba\n%+v", record, original)
+	}
+	// La longitud (00-04) y la dirección base (12-16) se recalculan
+	if len(record.Leader) != 24 || record.Leader[5:12] != original.Leader[5:12] || record.Leader[17:] != original.Leader[17:] {
+		t.Errorf("cabecera %q", record.Leader)
+	}
+	// La longitud cuenta bytes, no caracteres: el registro lleva diacríticos
+	if length, err := strconv.Atoi(record.Leader[0:5]); err != nil || length != len(body) {
+		t.Errorf("longitud de registro %q para %d bytes", record.Leader[0:5], len(body))
+	}
+}
+
+func TestRecordNotFoundAndDeleted(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	if resp, _ := get(t, server.URL+"/records/bimo9999999999", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("registro inexistente: %d", resp.StatusCode)
+	}
+
+	// Una marca de eliminación no se sirve en ningún formato
+	for _, accept := range []string{"", MediaISO2709} {
+		resp, body := get(t, server.URL+"/records/bimo0000000002", accept)
+		if resp.StatusCode != http.StatusGone || !strings.Contains(string(body), "eliminado") {
+			t.Errorf("registro eliminado con Accept %q: %d %s", accept, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestSearchYears(t *testing.T) {
+	server, store := newTestServer(t)
+
+	tests := []struct {
+		year     string
+		from, to int
+		status   int
+	}{
+		{year: "", status: http.StatusOK},
+		{year: "1990", from: 1990, to: 1990, status: http.StatusOK},
+		{year: "1990-", from: 1990, status: http.StatusOK},
+		{year: "-2000", to: 2000, status: http.StatusOK},
+		{year: "1990-2000", from: 1990, to: 2000, status: http.StatusOK},
+		{year: "2000-1990", status: http.StatusBadRequest},
+		{year: "-", status: http.StatusBadRequest},
+		{year: "0", status: http.StatusBadRequest},
+		{year: "mil", status: http.StatusBadRequest},
+		{year: "1990-2000-2010", status: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		store.filters = storage.SearchFilters{}
+		resp, body := get(t, server.URL+"/records?q=quijote&year="+test.year, "")
+		if resp.StatusCode != test.status {
+			t.Errorf("year=%s: %d %s, se esperaba %d", test.year, resp.StatusCode, body, test.status)
+			continue
+		}
+		if test.status == http.StatusOK && (store.filters.YearFrom != test.from || store.filters.YearTo != test.to) {
+			t.Errorf("year=%s: filtro %d-%d, se esperaba %d-%d", test.year,
+				store.filters.YearFrom, store.filters.YearTo, test.from, test.to)
+		}
+	}
+}
+
+func TestSearchParameters(t *testing.T) {
+	server, store := newTestServer(t)
+
+	// El cursor de la respuesta se devuelve tal cual en la siguiente página
+	resp, body := get(t, server.URL+"/records?q=quijote&category=MONOMODERN&language=spa&limit=5", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("búsqueda: %d %s", resp.StatusCode, body)
+	}
+	var result storage.SearchResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatalf("respuesta no válida: %v", err)
+	}
+	if store.filters.Category != "MONOMODERN" || store.filters.Language != "spa" || store.page.Limit != 5 {
+		t.Errorf("filtros %+v, página %+v", store.filters, store.page)
+	}
+	get(t, server.URL+"/records?q=quijote&cursor="+result.Next, "")
+	if store.page.Cursor != result.Next {
+		t.Errorf("cursor %q, se esperaba %q", store.page.Cursor, result.Next)
+	}
+
+	for _, query := range []string{"cursor=no-válido", "category=DESCONOCIDA", "limit=0", "limit=diez"} {
+		if resp, body := get(t, server.URL+"/records?"+query, ""); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: %d %s, se esperaba 400", query, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestCategories(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	_, body := get(t, server.URL+"/categories", "")
+	var response struct {
+		Categories []categoryResponse `json:"categories"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("respuesta no válida: %v", err)
+	}
+	if len(response.Categories) != len(constants.BNECategories) {
+		t.Fatalf("%d categorías, se esperaban %d", len(response.Categories), len(constants.BNECategories))
+	}
+	for _, category := range response.Categories {
+		if category.Id != "MONOMODERN" {
+			if category.Records != 0 || category.UpdatedAt != nil {
+				t.Errorf("categoría sin registros %+v", category)
+			}
+			continue
+		}
+		if category.Records != 1 || category.Deleted != 1 || category.UpdatedAt == nil || !category.UpdatedAt.Equal(modified) {
+			t.Errorf("categoría %+v", category)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package ckan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fsoria-ttec/bne-converter/internal/config"
+	"github.com/sirupsen/logrus" // logging
+)
+
+// Formato de last_modified que acepta CKAN
+const timestampLayout = "2006-01-02T15:04:05"
+
+// Publisher mantiene en el conjunto de datos configurado un recurso por
+// categoría, identificado por su nombre, con los datos del último fichero
+// procesado
+type Publisher struct {
+	config *config.CKANConfig
+	client *http.Client
+	logger *logrus.Logger
+}
+
+// Resource es el fichero de una categoría tal como se publica en CKAN
+type Resource struct {
+	Category     string
+	Description  string
+	URL          string
+	Size         int64
+	Checksum     string
+	RecordCount  int
+	LastModified time.Time
+}
+
+// apiResponse es la respuesta común de la API de acciones de CKAN
+type apiResponse struct {
+	Success bool            `json:"success"`
+	Result  json.RawMessage `json:"result"`
+	Error   *struct {
+		Type    string `json:"__type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type dataset struct {
+	ID        string `json:"id"`
+	Resources []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"resources"`
+}
+
+func New(cfg *config.Config, logger *logrus.Logger) *Publisher {
+	return &Publisher{
+		config: &cfg.CKAN,
+		client: &http.Client{
+			Timeout: cfg.CKAN.Timeout,
+		},
+		logger: logger,
+	}
+}
+
+// Publish crea o actualiza el recurso de la categoría
+func (p *Publisher) Publish(ctx context.Context, resource Resource) error {
+	var pkg dataset
+	if err := p.call(ctx, "package_show", map[string]any{"id": p.config.Dataset}, &pkg); err != nil {
+		return err
+	}
+
+	fields := map[string]any{
+		"url":          resource.URL,
+		"description":  resource.Description,
+		"format":       "MARC21",
+		"size":         resource.Size,
+		"hash":         "sha256:" + resource.Checksum,
+		"record_count": resource.RecordCount,
+	}
+	if !resource.LastModified.IsZero() {
+		fields["last_modified"] = resource.LastModified.UTC().Format(timestampLayout)
+	}
+
+	action := "resource_create"
+	fields["package_id"] = pkg.ID
+	fields["name"] = resource.Category
+	for _, existing := range pkg.Resources {
+		if existing.Name == resource.Category {
+			action = "resource_patch"
+			fields["id"] = existing.ID
+			break
+		}
+	}
+
+	if err := p.call(ctx, action, fields, nil); err != nil {
+		return err
+	}
+	p.logger.WithFields(logrus.Fields{
+		"category": resource.Category,
+		"dataset":  p.config.Dataset,
+		"action":   action,
+	}).Debug("Recurso publicado en CKAN")
+	return nil
+}
+
+// call invoca una acción de la API y decodifica su resultado en result
+func (p *Publisher) call(ctx context.Context, action string, params map[string]any, result any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("error serializando petición a CKAN (%w)", err)
+	}
+
+	endpoint := strings.TrimSuffix(p.config.URL, "/") + "/api/3/action/" + action
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error al crear petición a CKAN (%w)", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", p.config.APIKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error al realizar petición a CKAN (%w)", err)
+	}
+	defer resp.Body.Close()
+
+	var response apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("respuesta de CKAN no válida en %s (%d)", action, resp.StatusCode)
+	}
+	if !response.Success {
+		message := fmt.Sprintf("código de respuesta %d", resp.StatusCode)
+		if response.Error != nil {
+			message = strings.TrimSpace(response.Error.Type + " " + response.Error.Message)
+		}
+		return fmt.Errorf("CKAN rechazó %s (%s)", action, message)
+	}
+
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(response.Result, result); err != nil {
+		return fmt.Errorf("resultado de CKAN no válido en %s (%w)", action, err)
+	}
+	return nil
+}
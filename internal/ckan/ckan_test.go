@@ -0,0 +1,149 @@
+package ckan
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsoria-ttec/bne-converter/internal/config"
+	"github.com/sirupsen/logrus" // logging
+)
+
+// portal simula la API de acciones de CKAN con un conjunto de datos que ya
+// tiene el recurso de MONOMODERN
+type portal struct {
+	mu    sync.Mutex
+	calls []call
+}
+
+type call struct {
+	action        string
+	authorization string
+	params        map[string]any
+}
+
+func (p *portal) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		action := strings.TrimPrefix(r.URL.Path, "/api/3/action/")
+		var params map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			t.Errorf("%s: cuerpo no válido: %v", action, err)
+		}
+
+		p.mu.Lock()
+		p.calls = append(p.calls, call{action: action, authorization: r.Header.Get("Authorization"), params: params})
+		p.mu.Unlock()
+
+		switch action {
+		case "package_show":
+			if params["id"] != "registros-marc-bne" {
+				w.WriteHeader(http.StatusNotFound)
+				io.WriteString(w, `{"success": false, "error": {"__type": "Not Found Error", "message": "No encontrado"}}`)
+				return
+			}
+			io.WriteString(w, `{"success": true, "result": {"id": "pkg-1", "resources": [{"id": "res-1", "name": "MONOMODERN"}]}}`)
+		default:
+			io.WriteString(w, `{"success": true, "result": {}}`)
+		}
+	}
+}
+
+func (p *portal) last() call {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls[len(p.calls)-1]
+}
+
+func newTestPublisher(t *testing.T, url, dataset string) *Publisher {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.CKAN = config.CKANConfig{
+		Enabled: true,
+		URL:     url + "/",
+		APIKey:  "token",
+		Dataset: dataset,
+		Timeout: 5 * time.Second,
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return New(cfg, logger)
+}
+
+func TestPublishUpdatesExistingResource(t *testing.T) {
+	portal := &portal{}
+	server := httptest.NewServer(portal.handler(t))
+	defer server.Close()
+
+	publisher := newTestPublisher(t, server.URL, "registros-marc-bne")
+	err := publisher.Publish(context.Background(), Resource{
+		Category:     "MONOMODERN",
+		URL:          "https://www.bne.es/MONOMODERN-mrc_new.mrc",
+		Size:         1024,
+		Checksum:     "abc",
+		RecordCount:  3,
+		LastModified: time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	patch := portal.last()
+	if patch.action != "resource_patch" || patch.params["id"] != "res-1" {
+		t.Fatalf("se esperaba resource_patch de res-1: %+v", patch)
+	}
+	if patch.authorization != "token" {
+		t.Errorf("Authorization = %q", patch.authorization)
+	}
+	for key, want := range map[string]any{
+		"hash":          "sha256:abc",
+		"size":          float64(1024),
+		"record_count":  float64(3),
+		"last_modified": "2026-03-01T10:30:00",
+	} {
+		if got := patch.params[key]; got != want {
+			t.Errorf("%s = %v, se esperaba %v", key, got, want)
+		}
+	}
+}
+
+func TestPublishCreatesMissingResource(t *testing.T) {
+	portal := &portal{}
+	server := httptest.NewServer(portal.handler(t))
+	defer server.Close()
+
+	publisher := newTestPublisher(t, server.URL, "registros-marc-bne")
+	if err := publisher.Publish(context.Background(), Resource{Category: "VIDEO", Description: "Videograbaciones"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	create := portal.last()
+	if create.action != "resource_create" || create.params["package_id"] != "pkg-1" || create.params["name"] != "VIDEO" {
+		t.Fatalf("se esperaba resource_create de VIDEO en pkg-1: %+v", create)
+	}
+	if _, exists := create.params["last_modified"]; exists {
+		t.Errorf("last_modified sin fecha de modificación: %v", create.params["last_modified"])
+	}
+}
+
+func TestPublishReportsAPIErrors(t *testing.T) {
+	portal := &portal{}
+	server := httptest.NewServer(portal.handler(t))
+	defer server.Close()
+
+	publisher := newTestPublisher(t, server.URL, "otro")
+	err := publisher.Publish(context.Background(), Resource{Category: "VIDEO"})
+	if err == nil || !strings.Contains(err.Error(), "No encontrado") {
+		t.Fatalf("error = %v, se esperaba el mensaje de CKAN", err)
+	}
+	if len(portal.calls) != 1 {
+		t.Errorf("%d llamadas tras fallar package_show", len(portal.calls))
+	}
+}
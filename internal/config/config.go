@@ -2,31 +2,54 @@ package config
 
 import (
 	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/fsoria-ttec/bne-converter/internal/constants"
+	"github.com/fsoria-ttec/bne-converter/internal/schedule"
+	"github.com/fsoria-ttec/bne-converter/internal/source"
 	"github.com/sirupsen/logrus" // logging
 	"github.com/spf13/viper"     // config
 )
 
+const (
+	envPrefix  = "BNE"
+	fileSuffix = "_file" // indirección para secretos: <clave>_file apunta a un fichero con el valor
+)
+
 type Config struct {
-	Version  string `mapstructure:"version"`
-	Database DatabaseConfig
-	Crawler  CrawlerConfig
-	Monitor  MonitorConfig
-	Logging  LoggingConfig
+	Version       string              `mapstructure:"version"`
+	Database      DatabaseConfig      `mapstructure:"database"`
+	Crawler       CrawlerConfig       `mapstructure:"crawler"`
+	Monitor       MonitorConfig       `mapstructure:"monitor"`
+	Logging       LoggingConfig       `mapstructure:"logging"`
+	Metrics       MetricsConfig       `mapstructure:"metrics"`
+	Admin         AdminConfig         `mapstructure:"admin"`
+	API           APIConfig           `mapstructure:"api"`
+	OAI           OAIConfig           `mapstructure:"oai"`
+	CKAN          CKANConfig          `mapstructure:"ckan"`
+	Notifications NotificationsConfig `mapstructure:"notifications"`
 }
 
 type DatabaseConfig struct {
-	Host     string
-	Port     int
-	User     string
-	Password string
-	Name     string
-	SSLMode  string
+	Enabled     bool   `mapstructure:"enabled"` // cargar los registros en PostgreSQL
+	Host        string `mapstructure:"host"`
+	Port        int    `mapstructure:"port"`
+	User        string `mapstructure:"user"`
+	Password    string `mapstructure:"password"`
+	Name        string `mapstructure:"name"`
+	SSLMode     string `mapstructure:"sslmode"`
+	AutoMigrate bool   `mapstructure:"auto_migrate"` // aplicar las migraciones pendientes al arrancar
 }
 
 type CrawlerConfig struct {
 	BaseURL                string           `mapstructure:"base_url"`
+	AuthorityURL           string           `mapstructure:"authority_url"` // vacío: sin registros de autoridad
 	CheckInterval          time.Duration    `mapstructure:"check_interval"`
 	DownloadPath           string           `mapstructure:"download_path"`
 	MaxConcurrentDownloads int              `mapstructure:"max_concurrent_downloads"`
@@ -42,15 +65,99 @@ type ManualModeConfig struct {
 }
 
 type MonitorConfig struct {
-	CheckInterval time.Duration `mapstructure:"check_interval"`
-	Timeout       time.Duration `mapstructure:"timeout"`
+	CheckInterval  time.Duration `mapstructure:"check_interval"`
+	Timeout        time.Duration `mapstructure:"timeout"`
+	Schedule       string        `mapstructure:"schedule"` // expresión cron, sustituye a check_interval
+	Timezone       string        `mapstructure:"timezone"`
+	CheckOnStart   bool          `mapstructure:"check_on_start"`
+	CheckWindow    string        `mapstructure:"check_window"`    // HH:MM-HH:MM
+	DownloadWindow string        `mapstructure:"download_window"` // HH:MM-HH:MM
 }
 
 type LoggingConfig struct {
-	Level           string
-	Format          string
-	Output          string
-	TimestampFormat string `mapstructure:"timestamp_format"`
+	Level           string         `mapstructure:"level"`
+	Format          string         `mapstructure:"format"` // custom o json
+	Output          string         `mapstructure:"output"` // stdout, file o both
+	FileOutput      string         `mapstructure:"file_output"`
+	TimestampFormat string         `mapstructure:"timestamp_format"`
+	ShowCaller      bool           `mapstructure:"show_caller"`
+	ShowFunction    bool           `mapstructure:"show_function"`
+	Rotation        RotationConfig `mapstructure:"rotation"`
+}
+
+type RotationConfig struct {
+	MaxSizeMB  int  `mapstructure:"max_size_mb"`
+	MaxAgeDays int  `mapstructure:"max_age_days"`
+	MaxBackups int  `mapstructure:"max_backups"`
+	Compress   bool `mapstructure:"compress"`
+}
+
+type MetricsConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Address string `mapstructure:"address"`
+}
+
+type AdminConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Address string `mapstructure:"address"`
+	Token   string `mapstructure:"token"`
+}
+
+// APIConfig configura la API de consulta del catálogo, de solo lectura
+type APIConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Address string `mapstructure:"address"`
+}
+
+// OAIConfig configura el proveedor de datos OAI-PMH
+type OAIConfig struct {
+	Enabled        bool   `mapstructure:"enabled"`
+	Address        string `mapstructure:"address"`
+	BaseURL        string `mapstructure:"base_url"` // URL pública del punto de acceso
+	RepositoryName string `mapstructure:"repository_name"`
+	AdminEmail     string `mapstructure:"admin_email"`
+	Namespace      string `mapstructure:"namespace"` // identificadores oai:<namespace>:<número de control>
+	PageSize       int    `mapstructure:"page_size"`
+}
+
+// CKANConfig configura la publicación de los ficheros procesados en CKAN
+type CKANConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	URL     string        `mapstructure:"url"`     // URL base del portal
+	APIKey  string        `mapstructure:"api_key"` // token con permisos de edición del conjunto de datos
+	Dataset string        `mapstructure:"dataset"` // conjunto de datos con un recurso por categoría
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+type NotificationsConfig struct {
+	Webhook WebhookConfig `mapstructure:"webhook"`
+	Email   EmailConfig   `mapstructure:"email"`
+}
+
+type WebhookConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	URLs           []string      `mapstructure:"urls"`
+	Secret         string        `mapstructure:"secret"` // clave HMAC para firmar los envíos, obligatoria
+	Events         []string      `mapstructure:"events"` // vacío: todos los eventos
+	Timeout        time.Duration `mapstructure:"timeout"`
+	RetryAttempts  int           `mapstructure:"retry_attempts"`
+	RetryDelay     time.Duration `mapstructure:"retry_delay"`
+	DeadLetterPath string        `mapstructure:"dead_letter_path"`
+}
+
+// EmailConfig define el resumen por correo de las ejecuciones fallidas
+type EmailConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	Host          string        `mapstructure:"host"`
+	Port          int           `mapstructure:"port"`
+	Username      string        `mapstructure:"username"`
+	Password      string        `mapstructure:"password"`
+	From          string        `mapstructure:"from"`
+	To            []string      `mapstructure:"to"`
+	SubjectPrefix string        `mapstructure:"subject_prefix"`
+	MinInterval   time.Duration `mapstructure:"min_interval"` // tiempo mínimo entre dos correos
+	Timeout       time.Duration `mapstructure:"timeout"`
+	StatePath     string        `mapstructure:"state_path"` // último envío y fallos pendientes entre ejecuciones
 }
 
 func (l *LoggingConfig) GetLogLevel() logrus.Level {
@@ -74,27 +181,343 @@ func (l *LoggingConfig) GetLogLevel() logrus.Level {
 	}
 }
 
-func Load() (*Config, error) {
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath("./configs")
-	viper.AddConfigPath(".")
+// Load lee la configuración de path o, si está vacío, de config.yaml en
+// ./configs o el directorio actual. Las variables BNE_<SECCIÓN>_<CLAVE>
+// sobrescriben los valores del fichero
+func Load(path string) (*Config, error) {
+	if path != "" {
+		viper.SetConfigFile(path)
+	} else {
+		viper.SetConfigName("config")
+		viper.SetConfigType("yaml")
+		viper.AddConfigPath("./configs")
+		viper.AddConfigPath(".")
+	}
+
+	viper.SetEnvPrefix(envPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	for _, key := range configKeys(reflect.TypeOf(Config{}), "") {
+		viper.BindEnv(key)
+		viper.BindEnv(key + fileSuffix)
+	}
 
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("Error leyendo archivo de configuración: %w", err)
 	}
 
+	return decode()
+}
+
+func decode() (*Config, error) {
+	if err := checkUnknownKeys(); err != nil {
+		return nil, err
+	}
+
+	if err := resolveFileKeys(); err != nil {
+		return nil, err
+	}
+
 	var config Config
 	if err := viper.Unmarshal(&config); err != nil {
-		return nil, fmt.Errorf("Error en el unmarshaling de la configuración")
+		return nil, fmt.Errorf("Error en el unmarshaling de la configuración (%w)", err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
 	}
 
 	return &config, nil
 }
 
-func (d *DatabaseConfig) ConnectionString() string {
-	return fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		d.Host, d.Port, d.User, d.Password, d.Name, d.SSLMode,
-	)
+// configKeys obtiene las claves válidas a partir de las etiquetas mapstructure
+func configKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("mapstructure")
+		if key == "" {
+			key = strings.ToLower(field.Name)
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			keys = append(keys, configKeys(field.Type, prefix+key+".")...)
+			continue
+		}
+		keys = append(keys, prefix+key)
+	}
+	return keys
+}
+
+func checkUnknownKeys() error {
+	known := make(map[string]bool)
+	for _, key := range configKeys(reflect.TypeOf(Config{}), "") {
+		known[key] = true
+	}
+
+	var unknown []string
+	for _, key := range viper.AllKeys() {
+		if known[key] || known[strings.TrimSuffix(key, fileSuffix)] {
+			continue
+		}
+		unknown = append(unknown, key)
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("claves de configuración desconocidas: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// resolveFileKeys sustituye cada clave <clave>_file por el contenido del fichero indicado
+func resolveFileKeys() error {
+	for _, key := range configKeys(reflect.TypeOf(Config{}), "") {
+		path := viper.GetString(key + fileSuffix)
+		if path == "" {
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error leyendo %s%s (%w)", key, fileSuffix, err)
+		}
+		viper.Set(key, strings.TrimRight(string(data), "\r\n"))
+	}
+	return nil
+}
+
+// Validate comprueba que los valores de la configuración son utilizables
+func (c *Config) Validate() error {
+	var errs []string
+	invalid := func(key string, format string, args ...any) {
+		errs = append(errs, fmt.Sprintf("%s %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if c.Database.Port <= 0 || c.Database.Port > 65535 {
+		invalid("database.port", "puerto fuera de rango (%d)", c.Database.Port)
+	}
+	if c.Database.Enabled {
+		if c.Database.Host == "" {
+			invalid("database.host", "obligatorio si la base de datos está activa")
+		}
+		if c.Database.Name == "" {
+			invalid("database.name", "obligatorio si la base de datos está activa")
+		}
+		switch c.Database.SSLMode {
+		case "", "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+		default:
+			invalid("database.sslmode", "valor no soportado (%s)", c.Database.SSLMode)
+		}
+	}
+
+	if err := source.Validate(c.Crawler.BaseURL); err != nil {
+		invalid("crawler.base_url", "%v", err)
+	}
+	if c.Crawler.AuthorityURL != "" {
+		if err := source.Validate(c.Crawler.AuthorityURL); err != nil {
+			invalid("crawler.authority_url", "%v", err)
+		}
+	}
+	if c.Crawler.MaxConcurrentDownloads <= 0 {
+		invalid("crawler.max_concurrent_downloads", "debe ser mayor que 0 (%d)", c.Crawler.MaxConcurrentDownloads)
+	}
+	if c.Crawler.RetryAttempts <= 0 {
+		invalid("crawler.retry_attempts", "debe ser mayor que 0 (%d)", c.Crawler.RetryAttempts)
+	}
+	if c.Crawler.RetryDelay < 0 {
+		invalid("crawler.retry_delay", "no puede ser negativo (%s)", c.Crawler.RetryDelay)
+	}
+	if c.Crawler.DownloadPath == "" {
+		invalid("crawler.download_path", "no puede estar vacío")
+	}
+	for _, category := range c.Crawler.Categories {
+		family, exists := constants.CategoryFamily(category)
+		if !exists {
+			invalid("crawler.categories", "categoría desconocida (%s)", category)
+		} else if family == constants.FamilyAuthority && c.Crawler.AuthorityURL == "" {
+			invalid("crawler.categories", "la categoría %s requiere crawler.authority_url", category)
+		}
+	}
+	for _, category := range c.Crawler.ManualMode.SelectedCategories {
+		family, exists := constants.CategoryFamily(category)
+		if !exists {
+			invalid("crawler.manual_mode.selected_categories", "categoría desconocida (%s)", category)
+		} else if family == constants.FamilyAuthority && c.Crawler.AuthorityURL == "" {
+			invalid("crawler.manual_mode.selected_categories", "la categoría %s requiere crawler.authority_url", category)
+		}
+	}
+
+	if c.Monitor.CheckInterval <= 0 {
+		invalid("monitor.check_interval", "debe ser mayor que 0 (%s)", c.Monitor.CheckInterval)
+	}
+	if c.Monitor.Timeout <= 0 {
+		invalid("monitor.timeout", "debe ser mayor que 0 (%s)", c.Monitor.Timeout)
+	}
+	if c.Monitor.Schedule != "" {
+		if _, err := schedule.ParseCron(c.Monitor.Schedule); err != nil {
+			invalid("monitor.schedule", "%v", err)
+		}
+	}
+	if _, err := schedule.Location(c.Monitor.Timezone); err != nil {
+		invalid("monitor.timezone", "%v", err)
+	}
+	if _, err := schedule.ParseWindow(c.Monitor.CheckWindow); err != nil {
+		invalid("monitor.check_window", "%v", err)
+	}
+	if _, err := schedule.ParseWindow(c.Monitor.DownloadWindow); err != nil {
+		invalid("monitor.download_window", "%v", err)
+	}
+
+	if _, err := logrus.ParseLevel(c.Logging.Level); err != nil {
+		invalid("logging.level", "nivel desconocido (%s)", c.Logging.Level)
+	}
+	switch c.Logging.Format {
+	case "", "custom", "json":
+	default:
+		invalid("logging.format", "formato desconocido (%s), se espera custom o json", c.Logging.Format)
+	}
+	switch c.Logging.Output {
+	case "", "stdout":
+	case "file", "both":
+		if c.Logging.FileOutput == "" {
+			invalid("logging.file_output", "obligatorio con output %s", c.Logging.Output)
+		}
+	default:
+		invalid("logging.output", "salida desconocida (%s), se espera stdout, file o both", c.Logging.Output)
+	}
+	if c.Logging.Rotation.MaxSizeMB < 0 || c.Logging.Rotation.MaxAgeDays < 0 || c.Logging.Rotation.MaxBackups < 0 {
+		invalid("logging.rotation", "los límites de rotación no pueden ser negativos")
+	}
+
+	if c.Metrics.Enabled && c.Metrics.Address == "" {
+		invalid("metrics.address", "obligatorio si las métricas están activas")
+	}
+
+	if c.Admin.Enabled {
+		if c.Admin.Address == "" {
+			invalid("admin.address", "obligatorio si la API de administración está activa")
+		}
+		if c.Admin.Token == "" {
+			invalid("admin.token", "obligatorio si la API de administración está activa")
+		}
+	}
+
+	if c.API.Enabled {
+		if c.API.Address == "" {
+			invalid("api.address", "obligatorio si la API de consulta está activa")
+		}
+		if !c.Database.Enabled {
+			invalid("api.enabled", "la API de consulta requiere la base de datos activa")
+		}
+	}
+
+	if c.OAI.Enabled {
+		if c.OAI.Address == "" {
+			invalid("oai.address", "obligatorio si OAI-PMH está activo")
+		}
+		if err := validateURL(c.OAI.BaseURL); err != nil {
+			invalid("oai.base_url", "%v", err)
+		}
+		if _, err := mail.ParseAddress(c.OAI.AdminEmail); err != nil {
+			invalid("oai.admin_email", "dirección inválida %q", c.OAI.AdminEmail)
+		}
+		if c.OAI.RepositoryName == "" {
+			invalid("oai.repository_name", "obligatorio si OAI-PMH está activo")
+		}
+		if c.OAI.Namespace == "" {
+			invalid("oai.namespace", "obligatorio si OAI-PMH está activo")
+		}
+		if c.OAI.PageSize <= 0 {
+			invalid("oai.page_size", "debe ser mayor que cero (%d)", c.OAI.PageSize)
+		}
+		if !c.Database.Enabled {
+			invalid("oai.enabled", "OAI-PMH requiere la base de datos activa")
+		}
+	}
+
+	if c.CKAN.Enabled {
+		if err := validateURL(c.CKAN.URL); err != nil {
+			invalid("ckan.url", "%v", err)
+		}
+		if c.CKAN.APIKey == "" {
+			invalid("ckan.api_key", "obligatorio si la publicación en CKAN está activa")
+		}
+		if c.CKAN.Dataset == "" {
+			invalid("ckan.dataset", "obligatorio si la publicación en CKAN está activa")
+		}
+		if c.CKAN.Timeout <= 0 {
+			invalid("ckan.timeout", "debe ser mayor que 0 (%s)", c.CKAN.Timeout)
+		}
+	}
+
+	if webhook := c.Notifications.Webhook; webhook.Enabled {
+		if len(webhook.URLs) == 0 {
+			invalid("notifications.webhook.urls", "obligatorio si los webhooks están activos")
+		}
+		for _, raw := range webhook.URLs {
+			if err := validateURL(raw); err != nil {
+				invalid("notifications.webhook.urls", "%v", err)
+			}
+		}
+		if webhook.Secret == "" {
+			invalid("notifications.webhook.secret", "obligatorio si los webhooks están activos (o secret_file)")
+		}
+		if webhook.Timeout <= 0 {
+			invalid("notifications.webhook.timeout", "debe ser mayor que 0 (%s)", webhook.Timeout)
+		}
+		if webhook.RetryAttempts <= 0 {
+			invalid("notifications.webhook.retry_attempts", "debe ser mayor que 0 (%d)", webhook.RetryAttempts)
+		}
+		if webhook.DeadLetterPath == "" {
+			invalid("notifications.webhook.dead_letter_path", "obligatorio si los webhooks están activos")
+		}
+	}
+
+	if email := c.Notifications.Email; email.Enabled {
+		if email.Host == "" {
+			invalid("notifications.email.host", "obligatorio si el correo está activo")
+		}
+		if email.Port <= 0 || email.Port > 65535 {
+			invalid("notifications.email.port", "puerto fuera de rango (%d)", email.Port)
+		}
+		if _, err := mail.ParseAddress(email.From); err != nil {
+			invalid("notifications.email.from", "dirección inválida %q", email.From)
+		}
+		if len(email.To) == 0 {
+			invalid("notifications.email.to", "obligatorio si el correo está activo")
+		}
+		for _, to := range email.To {
+			if _, err := mail.ParseAddress(to); err != nil {
+				invalid("notifications.email.to", "dirección inválida %q", to)
+			}
+		}
+		if email.MinInterval < 0 {
+			invalid("notifications.email.min_interval", "no puede ser negativo (%s)", email.MinInterval)
+		}
+		if email.Timeout <= 0 {
+			invalid("notifications.email.timeout", "debe ser mayor que 0 (%s)", email.Timeout)
+		}
+		if email.StatePath == "" {
+			invalid("notifications.email.state_path", "obligatorio si el correo está activo")
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("configuración inválida: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("URL inválida (%w)", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("esquema no soportado en %q (se espera http o https)", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("falta el host en %q", raw)
+	}
+	return nil
 }
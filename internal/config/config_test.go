@@ -0,0 +1,276 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper" // config
+)
+
+// validConfig devuelve una configuración válida con todos los servicios activos
+func validConfig() *Config {
+	return &Config{
+		Database: DatabaseConfig{Enabled: true, Host: "localhost", Port: 5432, Name: "bne_converter", SSLMode: "disable"},
+		Crawler: CrawlerConfig{
+			BaseURL:                "https://www.bne.es/redBNE/alma/SuministroRegistros/Bibliograficos/",
+			AuthorityURL:           "https://www.bne.es/redBNE/alma/SuministroRegistros/Autoridades/",
+			DownloadPath:           "./mrc",
+			MaxConcurrentDownloads: 10,
+			RetryAttempts:          3,
+			RetryDelay:             time.Second,
+			Categories:             []string{"MONOMODERN", "PERSONAS"},
+			ManualMode:             ManualModeConfig{SelectedCategories: []string{"MATERIAS"}},
+		},
+		Monitor: MonitorConfig{
+			CheckInterval:  time.Hour,
+			Timeout:        30 * time.Second,
+			Schedule:       "0 */2 * * *",
+			Timezone:       "Europe/Madrid",
+			CheckWindow:    "08:00-20:00",
+			DownloadWindow: "22:00-06:00",
+		},
+		Logging: LoggingConfig{Level: "info", Format: "json", Output: "both", FileOutput: "./logs/bne-converter.log"},
+		Metrics: MetricsConfig{Enabled: true, Address: ":9090"},
+		Admin:   AdminConfig{Enabled: true, Address: "127.0.0.1:8081", Token: "token"},
+		API:     APIConfig{Enabled: true, Address: "127.0.0.1:8082"},
+		OAI: OAIConfig{
+			Enabled:        true,
+			Address:        "127.0.0.1:8083",
+			BaseURL:        "http://127.0.0.1:8083/oai",
+			RepositoryName: "Registros MARC de la Biblioteca Nacional de España",
+			AdminEmail:     "admin@example.org",
+			Namespace:      "bne.es",
+			PageSize:       100,
+		},
+		CKAN: CKANConfig{Enabled: true, URL: "https://datos.example.org", APIKey: "clave", Dataset: "registros-marc-bne", Timeout: 30 * time.Second},
+		Notifications: NotificationsConfig{
+			Webhook: WebhookConfig{
+				Enabled:        true,
+				URLs:           []string{"https://hooks.example.org/bne"},
+				Secret:         "secreto",
+				Timeout:        10 * time.Second,
+				RetryAttempts:  3,
+				DeadLetterPath: "./logs/webhooks-dead-letter.jsonl",
+			},
+			Email: EmailConfig{
+				Enabled:     true,
+				Host:        "localhost",
+				Port:        25,
+				From:        "bne-converter@localhost",
+				To:          []string{"catalogacion@example.org"},
+				MinInterval: time.Hour,
+				Timeout:     30 * time.Second,
+				StatePath:   "./logs/email-digest.json",
+			},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatalf("configuración válida rechazada: %v", err)
+	}
+
+	tests := []struct {
+		key    string
+		change func(*Config)
+	}{
+		{"database.port", func(c *Config) { c.Database.Port = 0 }},
+		{"database.port", func(c *Config) { c.Database.Port = 65536 }},
+		{"database.host", func(c *Config) { c.Database.Host = "" }},
+		{"database.name", func(c *Config) { c.Database.Name = "" }},
+		{"database.sslmode", func(c *Config) { c.Database.SSLMode = "always" }},
+		{"crawler.base_url", func(c *Config) { c.Crawler.BaseURL = "ftp://www.bne.es/" }},
+		{"crawler.authority_url", func(c *Config) { c.Crawler.AuthorityURL = "https://" }},
+		{"crawler.max_concurrent_downloads", func(c *Config) { c.Crawler.MaxConcurrentDownloads = 0 }},
+		{"crawler.retry_attempts", func(c *Config) { c.Crawler.RetryAttempts = 0 }},
+		{"crawler.retry_delay", func(c *Config) { c.Crawler.RetryDelay = -time.Second }},
+		{"crawler.download_path", func(c *Config) { c.Crawler.DownloadPath = "" }},
+		{"crawler.categories", func(c *Config) { c.Crawler.Categories = []string{"LIBROS"} }},
+		{"crawler.categories", func(c *Config) { c.Crawler.AuthorityURL, c.Crawler.ManualMode.SelectedCategories = "", nil }},
+		{"crawler.manual_mode.selected_categories", func(c *Config) { c.Crawler.ManualMode.SelectedCategories = []string{"LIBROS"} }},
+		{"crawler.manual_mode.selected_categories", func(c *Config) { c.Crawler.AuthorityURL, c.Crawler.Categories = "", nil }},
+		{"monitor.check_interval", func(c *Config) { c.Monitor.CheckInterval = 0 }},
+		{"monitor.timeout", func(c *Config) { c.Monitor.Timeout = 0 }},
+		{"monitor.schedule", func(c *Config) { c.Monitor.Schedule = "cada hora" }},
+		{"monitor.timezone", func(c *Config) { c.Monitor.Timezone = "Europe/Atlantis" }},
+		{"monitor.check_window", func(c *Config) { c.Monitor.CheckWindow = "08:00" }},
+		{"monitor.download_window", func(c *Config) { c.Monitor.DownloadWindow = "22:00-25:00" }},
+		{"logging.level", func(c *Config) { c.Logging.Level = "verbose" }},
+		{"logging.format", func(c *Config) { c.Logging.Format = "text" }},
+		{"logging.file_output", func(c *Config) { c.Logging.FileOutput = "" }},
+		{"logging.output", func(c *Config) { c.Logging.Output = "syslog" }},
+		{"logging.rotation", func(c *Config) { c.Logging.Rotation.MaxBackups = -1 }},
+		{"metrics.address", func(c *Config) { c.Metrics.Address = "" }},
+		{"admin.address", func(c *Config) { c.Admin.Address = "" }},
+		{"admin.token", func(c *Config) { c.Admin.Token = "" }},
+		{"api.address", func(c *Config) { c.API.Address = "" }},
+		{"api.enabled", func(c *Config) { c.Database.Enabled, c.OAI.Enabled = false, false }},
+		{"oai.address", func(c *Config) { c.OAI.Address = "" }},
+		{"oai.base_url", func(c *Config) { c.OAI.BaseURL = "127.0.0.1:8083/oai" }},
+		{"oai.admin_email", func(c *Config) { c.OAI.AdminEmail = "admin" }},
+		{"oai.repository_name", func(c *Config) { c.OAI.RepositoryName = "" }},
+		{"oai.namespace", func(c *Config) { c.OAI.Namespace = "" }},
+		{"oai.page_size", func(c *Config) { c.OAI.PageSize = 0 }},
+		{"oai.enabled", func(c *Config) { c.Database.Enabled, c.API.Enabled = false, false }},
+		{"ckan.url", func(c *Config) { c.CKAN.URL = "" }},
+		{"ckan.api_key", func(c *Config) { c.CKAN.APIKey = "" }},
+		{"ckan.dataset", func(c *Config) { c.CKAN.Dataset = "" }},
+		{"ckan.timeout", func(c *Config) { c.CKAN.Timeout = 0 }},
+		{"notifications.webhook.urls", func(c *Config) { c.Notifications.Webhook.URLs = nil }},
+		{"notifications.webhook.urls", func(c *Config) { c.Notifications.Webhook.URLs = []string{"hooks.example.org"} }},
+		{"notifications.webhook.secret", func(c *Config) { c.Notifications.Webhook.Secret = "" }},
+		{"notifications.webhook.timeout", func(c *Config) { c.Notifications.Webhook.Timeout = 0 }},
+		{"notifications.webhook.retry_attempts", func(c *Config) { c.Notifications.Webhook.RetryAttempts = 0 }},
+		{"notifications.webhook.dead_letter_path", func(c *Config) { c.Notifications.Webhook.DeadLetterPath = "" }},
+		{"notifications.email.host", func(c *Config) { c.Notifications.Email.Host = "" }},
+		{"notifications.email.port", func(c *Config) { c.Notifications.Email.Port = 70000 }},
+		{"notifications.email.from", func(c *Config) { c.Notifications.Email.From = "" }},
+		{"notifications.email.to", func(c *Config) { c.Notifications.Email.To = nil }},
+		{"notifications.email.to", func(c *Config) { c.Notifications.Email.To = []string{"catalogacion"} }},
+		{"notifications.email.min_interval", func(c *Config) { c.Notifications.Email.MinInterval = -time.Minute }},
+		{"notifications.email.timeout", func(c *Config) { c.Notifications.Email.Timeout = 0 }},
+		{"notifications.email.state_path", func(c *Config) { c.Notifications.Email.StatePath = "" }},
+	}
+
+	for _, test := range tests {
+		cfg := validConfig()
+		test.change(cfg)
+
+		err := cfg.Validate()
+		if err == nil {
+			t.Errorf("%s: se esperaba un error", test.key)
+			continue
+		}
+		// Cada caso rompe una sola comprobación
+		message := strings.TrimPrefix(err.Error(), "configuración inválida: ")
+		if !strings.HasPrefix(message, test.key+" ") || strings.Contains(message, "; ") {
+			t.Errorf("%s: error %q", test.key, err)
+		}
+	}
+}
+
+// Los servicios desactivados no exigen su configuración
+func TestValidateDisabled(t *testing.T) {
+	cfg := validConfig()
+	cfg.Database = DatabaseConfig{Port: 5432}
+	cfg.Metrics = MetricsConfig{}
+	cfg.Admin = AdminConfig{}
+	cfg.API = APIConfig{}
+	cfg.OAI = OAIConfig{}
+	cfg.CKAN = CKANConfig{}
+	cfg.Notifications = NotificationsConfig{}
+	cfg.Monitor.Schedule, cfg.Monitor.Timezone, cfg.Monitor.CheckWindow, cfg.Monitor.DownloadWindow = "", "", "", ""
+	cfg.Logging.Output, cfg.Logging.Format = "", ""
+
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("configuración rechazada: %v", err)
+	}
+}
+
+// load lee el config.yaml del repositorio, opcionalmente modificado, con el
+// estado global de viper limpio
+func load(t *testing.T, replacer *strings.Replacer) (*Config, error) {
+	t.Helper()
+
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	path := filepath.Join("..", "..", "configs", "config.yaml")
+	if replacer != nil {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path = filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte(replacer.Replace(string(data))), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return Load(path)
+}
+
+func writeSecret(t *testing.T, value string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "secreto")
+	if err := os.WriteFile(path, []byte(value), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	cfg, err := load(t, nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Database.Port != 5432 || cfg.Crawler.RetryDelay != time.Second || len(cfg.Crawler.Categories) != 16 {
+		t.Errorf("configuración leída %+v", cfg)
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	t.Setenv("BNE_DATABASE_HOST", "db.example.org")
+	t.Setenv("BNE_CRAWLER_RETRY_DELAY", "5s")
+	t.Setenv("BNE_NOTIFICATIONS_EMAIL_PORT", "587")
+
+	cfg, err := load(t, nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Database.Host != "db.example.org" || cfg.Crawler.RetryDelay != 5*time.Second ||
+		cfg.Notifications.Email.Port != 587 {
+		t.Errorf("variables de entorno no aplicadas: %+v %+v %+v", cfg.Database, cfg.Crawler, cfg.Notifications.Email)
+	}
+
+	// Las variables también se validan
+	t.Setenv("BNE_LOGGING_LEVEL", "verbose")
+	if _, err := load(t, nil); err == nil || !strings.Contains(err.Error(), "logging.level") {
+		t.Errorf("se esperaba un error de logging.level: %v", err)
+	}
+}
+
+func TestLoadFileSecrets(t *testing.T) {
+	// Clave _file en el fichero; el salto de línea final no forma parte del secreto
+	secret := writeSecret(t, "clave-ckan\n")
+	cfg, err := load(t, strings.NewReplacer(`  api_key: ""`, "  api_key_file: "+secret))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.CKAN.APIKey != "clave-ckan" {
+		t.Errorf("ckan.api_key = %q", cfg.CKAN.APIKey)
+	}
+
+	// Variable _file de entorno, que prevalece sobre el valor del fichero
+	t.Setenv("BNE_DATABASE_PASSWORD_FILE", writeSecret(t, "contraseña\r\n"))
+	t.Setenv("BNE_NOTIFICATIONS_WEBHOOK_SECRET_FILE", writeSecret(t, "firma"))
+	cfg, err = load(t, strings.NewReplacer(`  password: ""`, `  password: "en claro"`))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Database.Password != "contraseña" || cfg.Notifications.Webhook.Secret != "firma" {
+		t.Errorf("secretos %q y %q", cfg.Database.Password, cfg.Notifications.Webhook.Secret)
+	}
+
+	t.Setenv("BNE_DATABASE_PASSWORD_FILE", filepath.Join(t.TempDir(), "no-existe"))
+	if _, err := load(t, nil); err == nil || !strings.Contains(err.Error(), "database.password_file") {
+		t.Errorf("se esperaba un error de database.password_file: %v", err)
+	}
+}
+
+func TestLoadUnknownKeys(t *testing.T) {
+	_, err := load(t, strings.NewReplacer("  retry_attempts: 3\n  retry_delay", "  retry_attempts: 3\n  retries: 3\n  retry_delay"))
+	if err == nil || !strings.Contains(err.Error(), "crawler.retries") {
+		t.Errorf("se esperaba un error de clave desconocida: %v", err)
+	}
+
+	// La variante _file solo existe para claves conocidas
+	_, err = load(t, strings.NewReplacer(`  token: ""`, "  token: \"\"\n  tokens_file: /run/secrets/admin"))
+	if err == nil || !strings.Contains(err.Error(), "admin.tokens_file") {
+		t.Errorf("se esperaba un error de clave desconocida: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// Claves que pueden cambiar sin reiniciar; el resto requiere reinicio
+var reloadableKeys = map[string]bool{
+	"monitor.check_interval":                  true,
+	"monitor.schedule":                        true,
+	"monitor.timezone":                        true,
+	"monitor.check_window":                    true,
+	"monitor.download_window":                 true,
+	"crawler.retry_attempts":                  true,
+	"crawler.retry_delay":                     true,
+	"crawler.manual_mode.selected_categories": true,
+	"logging.level":                           true,
+}
+
+// Claves con secretos, incluidas sus variantes <clave>_file: sus valores no
+// se muestran en los registros
+var secretKeys = map[string]bool{
+	"database.password":            true,
+	"admin.token":                  true,
+	"ckan.api_key":                 true,
+	"notifications.webhook.secret": true,
+	"notifications.email.password": true,
+}
+
+type Change struct {
+	Key string
+	Old any
+	New any
+}
+
+func (c Change) String() string {
+	if secretKeys[strings.TrimSuffix(c.Key, fileSuffix)] {
+		return fmt.Sprintf("%s: valor oculto", c.Key)
+	}
+	return fmt.Sprintf("%s: %v -> %v", c.Key, c.Old, c.New)
+}
+
+// Watch vigila el fichero de configuración y entrega cada nueva versión válida
+func Watch(onChange func(*Config), onError func(error)) {
+	viper.OnConfigChange(func(event fsnotify.Event) {
+		cfg, err := decode()
+		if err != nil {
+			onError(err)
+			return
+		}
+		onChange(cfg)
+	})
+	viper.WatchConfig()
+}
+
+// Diff devuelve los valores que difieren entre dos configuraciones
+func Diff(old, new *Config) []Change {
+	var changes []Change
+	for _, key := range configKeys(reflect.TypeOf(Config{}), "") {
+		oldValue := fieldByKey(reflect.ValueOf(old).Elem(), key)
+		newValue := fieldByKey(reflect.ValueOf(new).Elem(), key)
+
+		if !reflect.DeepEqual(oldValue.Interface(), newValue.Interface()) {
+			changes = append(changes, Change{
+				Key: key,
+				Old: oldValue.Interface(),
+				New: newValue.Interface(),
+			})
+		}
+	}
+	return changes
+}
+
+// Reload aplica sobre current los cambios recargables de next. Devuelve la
+// configuración resultante junto con los cambios aplicados y los rechazados
+func Reload(current, next *Config) (*Config, []Change, []Change) {
+	merged := *current
+	var applied, refused []Change
+
+	for _, change := range Diff(current, next) {
+		if !reloadableKeys[change.Key] {
+			refused = append(refused, change)
+			continue
+		}
+
+		fieldByKey(reflect.ValueOf(&merged).Elem(), change.Key).Set(reflect.ValueOf(change.New))
+		applied = append(applied, change)
+	}
+
+	return &merged, applied, refused
+}
+
+func fieldByKey(value reflect.Value, key string) reflect.Value {
+	for _, part := range strings.Split(key, ".") {
+		t := value.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name := t.Field(i).Tag.Get("mapstructure")
+			if name == "" {
+				name = strings.ToLower(t.Field(i).Name)
+			}
+			if name == part {
+				value = value.Field(i)
+				break
+			}
+		}
+	}
+	return value
+}
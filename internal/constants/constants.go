@@ -1,28 +1,54 @@
 package constants
 
+// Familias de registros publicadas por la BNE, cada una bajo su propia URL
+const (
+	FamilyBibliographic = "Bibliograficos"
+	FamilyAuthority     = "Autoridades"
+)
+
 type Category struct {
 	Id          string
 	Description string
+	Family      string
 }
 
 var BNECategories = []Category{
-	{Id: "GRAFNOPRO", Description: "Dibujos, carteles, efímera, grabados, fotografías"},
-	{Id: "GRAFPRO", Description: "Filminas, transparencias"},
-	{Id: "GRABSONORA", Description: "Grabaciones sonoras"},
-	{Id: "KIT", Description: "Kit o multimedia"},
-	{Id: "MANUSCRITO", Description: "Manuscritos y archivos personales"},
-	{Id: "CARTOGRAFI", Description: "Mapas"},
-	{Id: "MATEMIXTO", Description: "Materiales mixtos"},
-	{Id: "MONOANTIGU", Description: "Monografías antiguas"},
-	{Id: "MONOMODERN", Description: "Monografías modernas"},
-	{Id: "MUSICAESC", Description: "Partituras"},
-	{Id: "RECELECTRO", Description: "Recursos electrónicos"},
-	{Id: "SERIADA", Description: "Prensa y revistas"},
-	{Id: "VIDEO", Description: "Videograbaciones"},
+	{Id: "GRAFNOPRO", Description: "Dibujos, carteles, efímera, grabados, fotografías", Family: FamilyBibliographic},
+	{Id: "GRAFPRO", Description: "Filminas, transparencias", Family: FamilyBibliographic},
+	{Id: "GRABSONORA", Description: "Grabaciones sonoras", Family: FamilyBibliographic},
+	{Id: "KIT", Description: "Kit o multimedia", Family: FamilyBibliographic},
+	{Id: "MANUSCRITO", Description: "Manuscritos y archivos personales", Family: FamilyBibliographic},
+	{Id: "CARTOGRAFI", Description: "Mapas", Family: FamilyBibliographic},
+	{Id: "MATEMIXTO", Description: "Materiales mixtos", Family: FamilyBibliographic},
+	{Id: "MONOANTIGU", Description: "Monografías antiguas", Family: FamilyBibliographic},
+	{Id: "MONOMODERN", Description: "Monografías modernas", Family: FamilyBibliographic},
+	{Id: "MUSICAESC", Description: "Partituras", Family: FamilyBibliographic},
+	{Id: "RECELECTRO", Description: "Recursos electrónicos", Family: FamilyBibliographic},
+	{Id: "SERIADA", Description: "Prensa y revistas", Family: FamilyBibliographic},
+	{Id: "VIDEO", Description: "Videograbaciones", Family: FamilyBibliographic},
+	{Id: "PERSONAS", Description: "Autoridades de personas", Family: FamilyAuthority},
+	{Id: "ENTIDADES", Description: "Autoridades de entidades", Family: FamilyAuthority},
+	{Id: "MATERIAS", Description: "Autoridades de materias", Family: FamilyAuthority},
+}
+
+func IsBNECategory(id string) bool {
+	_, exists := CategoryFamily(id)
+	return exists
+}
+
+// CategoryFamily devuelve la familia de registros a la que pertenece una categoría
+func CategoryFamily(id string) (string, bool) {
+	for _, category := range BNECategories {
+		if category.Id == id {
+			return category.Family, true
+		}
+	}
+	return "", false
 }
 
 const (
 	BaseURL       = "https://www.bne.es/redBNE/alma/SuministroRegistros/Bibliograficos"
+	AuthorityURL  = "https://www.bne.es/redBNE/alma/SuministroRegistros/Autoridades"
 	MRCFileSuffix = "-mrc_new.mrc"
 	Version       = `mapstructure:"version"`
 )
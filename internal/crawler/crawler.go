@@ -2,28 +2,42 @@ package crawler
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fsoria-ttec/bne-converter/internal/config"
 	"github.com/fsoria-ttec/bne-converter/internal/constants"
 	"github.com/fsoria-ttec/bne-converter/internal/metadata"
+	"github.com/fsoria-ttec/bne-converter/internal/metrics"
+	"github.com/fsoria-ttec/bne-converter/internal/source"
 	"github.com/sirupsen/logrus" // logging
 )
 
 type Crawler struct {
-	client    *http.Client
-	config    *config.CrawlerConfig
+	sources   map[string]source.Source // por familia de registros
+	config    atomic.Pointer[config.CrawlerConfig]
 	logger    *logrus.Logger
 	semaphore chan struct{}
 	metadata  *metadata.MetadataStore
 }
 
+var errInvalidLastModified = errors.New("fecha de modificación ausente o inválida")
+
+// Sufijos de la descarga en curso y de la versión anterior del fichero, que
+// se conserva para calcular los cambios entre versiones
+const (
+	partialSuffix  = ".part"
+	previousSuffix = ".prev"
+)
+
 type DownloadResult struct {
 	Category     string
 	URL          string
@@ -31,6 +45,10 @@ type DownloadResult struct {
 	Error        error
 	Timestamp    time.Time
 	LastModified time.Time
+	Skipped      bool   // la versión local ya estaba al día
+	PreviousPath string // versión sustituida por la descarga, si existía
+	Size         int64
+	Checksum     string
 }
 
 func New(cfg *config.Config, logger *logrus.Logger) (*Crawler, error) {
@@ -39,28 +57,75 @@ func New(cfg *config.Config, logger *logrus.Logger) (*Crawler, error) {
 		return nil, fmt.Errorf("error al inicializar el almacén de metadatos (%w)", err)
 	}
 
-	return &Crawler{
-		client: &http.Client{
-			Timeout: time.Minute * 10, // timeout largo para archivos grandes
-		},
-		config:    &cfg.Crawler,
+	// timeout largo para archivos grandes
+	sources, err := NewSources(&cfg.Crawler, time.Minute*10)
+	if err != nil {
+		return nil, err
+	}
+
+	crawler := &Crawler{
+		sources:   sources,
 		logger:    logger,
 		semaphore: make(chan struct{}, cfg.Crawler.MaxConcurrentDownloads),
 		metadata:  metadataStore,
-	}, nil
+	}
+	crawler.config.Store(&cfg.Crawler)
+
+	return crawler, nil
+}
+
+// UpdateConfig sustituye la configuración usada a partir de la siguiente descarga
+func (c *Crawler) UpdateConfig(cfg *config.CrawlerConfig) {
+	c.config.Store(cfg)
 }
 
 func (c *Crawler) DownloadAll(ctx context.Context) []DownloadResult {
-	var wg sync.WaitGroup
-	results := make([]DownloadResult, 0)
-	resultsChan := make(chan DownloadResult, len(constants.BNECategories))
+	return c.DownloadCategories(ctx, c.SelectedCategories(), false)
+}
 
-	// Iniciar descargas concurrentes
+// NewSources crea un origen por cada familia de registros configurada
+func NewSources(cfg *config.CrawlerConfig, timeout time.Duration) (map[string]source.Source, error) {
+	locations := map[string]string{
+		constants.FamilyBibliographic: cfg.BaseURL,
+		constants.FamilyAuthority:     cfg.AuthorityURL,
+	}
+
+	sources := make(map[string]source.Source)
+	for family, location := range locations {
+		if location == "" {
+			continue
+		}
+		src, err := source.New(location, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("error al inicializar el origen de %s (%w)", family, err)
+		}
+		sources[family] = src
+	}
+	return sources, nil
+}
+
+// Categories devuelve las categorías de las familias con origen configurado
+func (c *Crawler) Categories() []constants.Category {
+	var categories []constants.Category
 	for _, category := range constants.BNECategories {
+		if _, exists := c.sources[category.Family]; exists {
+			categories = append(categories, category)
+		}
+	}
+	return categories
+}
+
+// SelectedCategories devuelve las categorías seleccionadas en la configuración
+// o, si no hay selección, todas las de las familias con origen configurado
+func (c *Crawler) SelectedCategories() []string {
+	cfg := c.config.Load()
+
+	var categories []string
+	for _, category := range c.Categories() {
 		// Comprobar lista de categorias seleccionadas
-		if len(c.config.ManualMode.SelectedCategories) > 0 {
+		if len(cfg.ManualMode.SelectedCategories) > 0 {
 			found := false
-			for _, selectedCat := range c.config.ManualMode.SelectedCategories {
+			for _, selectedCat := range cfg.ManualMode.SelectedCategories {
 				if category.Id == selectedCat {
 					found = true
 					break
@@ -70,18 +135,32 @@ func (c *Crawler) DownloadAll(ctx context.Context) []DownloadResult {
 				continue
 			}
 		}
+		categories = append(categories, category.Id)
+	}
+
+	return categories
+}
 
+// DownloadCategories descarga las categorías indicadas de forma concurrente.
+// Con force se descargan aunque la versión local esté al día
+func (c *Crawler) DownloadCategories(ctx context.Context, categories []string, force bool) []DownloadResult {
+	var wg sync.WaitGroup
+	results := make([]DownloadResult, 0)
+	resultsChan := make(chan DownloadResult, len(categories))
+
+	// Iniciar descargas concurrentes
+	for _, category := range categories {
 		wg.Add(1)
-		go func(cat constants.Category) {
+		go func(category string) {
 			defer wg.Done()
 
 			// Adquirir semáforo
 			c.semaphore <- struct{}{}
 			defer func() { <-c.semaphore }()
 
-			url := fmt.Sprintf("%s%s%s", c.config.BaseURL, cat.Id, constants.MRCFileSuffix)
-			c.logger.Debugf("URL: %s", url)
-			result := c.Download(ctx, cat.Id, url)
+			url := c.CategoryURL(category)
+			c.fields(category, url).Debug("Iniciando descarga")
+			result := c.Download(ctx, category, url, force)
 
 			select {
 			case resultsChan <- result:
@@ -104,72 +183,125 @@ func (c *Crawler) DownloadAll(ctx context.Context) []DownloadResult {
 	return results
 }
 
-func (c *Crawler) Download(ctx context.Context, category, url string) DownloadResult {
+func (c *Crawler) Download(ctx context.Context, category, url string, force bool) DownloadResult {
 	result := DownloadResult{
 		Category:  category,
 		URL:       url,
 		Timestamp: time.Now(),
 	}
+	cfg := c.config.Load()
+	log := c.fields(category, url)
 
 	// Obtener información de archivo remoto y comparar
 	needsUpdate, remoteLastModified, err := c.checkIfNeedsUpdate(ctx, category, url)
 	if err != nil {
+		metrics.ObserveDownload(category, metrics.ResultError, 0, 0)
 		result.Error = fmt.Errorf("error comprobando actualizaciones (%w)", err)
 		return result
 	}
 
-	if !needsUpdate {
-		c.logger.Infof("%s ya es la versión más reciente, omitiendo descarga", category)
-		result.FilePath = filepath.Join(c.config.DownloadPath, category, fmt.Sprintf("%s%s", category, constants.MRCFileSuffix))
-		result.LastModified, _ = c.metadata.GetLastModified(category)
+	if !needsUpdate && !force {
+		metrics.ObserveDownload(category, metrics.ResultSkipped, 0, 0)
+		log.Info("Ya es la versión más reciente, omitiendo descarga")
+		result.FilePath = filepath.Join(cfg.DownloadPath, category, fileName(category))
+		result.Skipped = true
+		if file, exists := c.metadata.Get(category); exists {
+			result.LastModified = file.LastModified
+			result.Size = file.Size
+			result.Checksum = file.Checksum
+		}
 		return result
 	}
 
 	var downloadErr error
-	for attempt := 1; attempt <= c.config.RetryAttempts; attempt++ {
-		result.FilePath, downloadErr = c.downloadFile(ctx, category, url, remoteLastModified)
+	for attempt := 1; attempt <= cfg.RetryAttempts; attempt++ {
+		start := time.Now()
+		var size int64
+		result.FilePath, result.PreviousPath, size, downloadErr = c.downloadFile(ctx, category, url, remoteLastModified)
 		if downloadErr == nil {
-			c.logger.Infof("Descarga completada: %s", category)
+			duration := time.Since(start)
+			metrics.ObserveDownload(category, metrics.ResultOK, size, duration)
+			log.WithField("duration_ms", duration.Milliseconds()).Info("Descarga completada")
 			result.LastModified = remoteLastModified
+			result.Size = size
+			if file, exists := c.metadata.Get(category); exists {
+				result.Checksum = file.Checksum
+			}
 			return result
 		}
+		metrics.ObserveDownloadAttemptFailure(category)
 
-		if attempt < c.config.RetryAttempts {
-			c.logger.Warnf("Intento nº%d fallido para %s: %v. Reintentando...", attempt, url, downloadErr)
+		if attempt < cfg.RetryAttempts {
+			log.WithFields(logrus.Fields{
+				"attempt": attempt,
+				"error":   downloadErr,
+			}).Warn("Intento de descarga fallido, reintentando...")
 			select {
 			case <-ctx.Done():
+				metrics.ObserveDownload(category, metrics.ResultError, 0, 0)
 				result.Error = ctx.Err()
 				return result
-			case <-time.After(c.config.RetryDelay):
+			case <-time.After(cfg.RetryDelay):
 			}
 		}
 	}
 
-	result.Error = fmt.Errorf("todos los intentos de descarga han fallado: %w", err)
+	metrics.ObserveDownload(category, metrics.ResultError, 0, 0)
+	result.Error = fmt.Errorf("todos los intentos de descarga han fallado: %w", downloadErr)
 	return result
 }
 
-func (c *Crawler) checkIfNeedsUpdate(ctx context.Context, category, url string) (bool, time.Time, error) {
-	// Crear petición HEAD
-	req, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
+// CategoryURL devuelve la URL o ruta en el origen del fichero MARC de una categoría
+func (c *Crawler) CategoryURL(category string) string {
+	src, err := c.source(category)
 	if err != nil {
-		return false, time.Time{}, fmt.Errorf("error creando petición HEAD (%w)", err)
+		return fileName(category)
 	}
+	return src.Location(fileName(category))
+}
 
-	resp, err := c.client.Do(req)
+// RemoteLastModified obtiene la fecha de modificación en el origen del fichero de una categoría
+func (c *Crawler) RemoteLastModified(ctx context.Context, category string) (time.Time, error) {
+	src, err := c.source(category)
 	if err != nil {
-		return false, time.Time{}, fmt.Errorf("error realizando petición HEAD (%w)", err)
+		return time.Time{}, err
 	}
-	defer resp.Body.Close()
+	info, err := src.Stat(ctx, fileName(category))
+	if err != nil {
+		return time.Time{}, err
+	}
+	if info.LastModified.IsZero() {
+		return time.Time{}, errInvalidLastModified
+	}
+	return info.LastModified, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return false, time.Time{}, fmt.Errorf("código de estado inesperado en HEAD (%d)", resp.StatusCode)
+// source devuelve el origen de la familia a la que pertenece la categoría
+func (c *Crawler) source(category string) (source.Source, error) {
+	family, exists := constants.CategoryFamily(category)
+	if !exists {
+		return nil, fmt.Errorf("categoría desconocida (%s)", category)
 	}
+	src, exists := c.sources[family]
+	if !exists {
+		return nil, fmt.Errorf("sin origen configurado para %s (%s)", family, category)
+	}
+	return src, nil
+}
 
-	// Obtener Last-Modified del servidor
-	remoteLastModified, err := time.Parse(time.RFC1123, resp.Header.Get("Last-Modified"))
+func (c *Crawler) checkIfNeedsUpdate(ctx context.Context, category, url string) (bool, time.Time, error) {
+	src, err := c.source(category)
 	if err != nil {
-		remoteLastModified = time.Now() // si no se puede parsear fecha, actualizar
+		return false, time.Time{}, err
+	}
+	info, err := src.Stat(ctx, fileName(category))
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	remoteLastModified := info.LastModified
+	if remoteLastModified.IsZero() {
+		remoteLastModified = time.Now() // si no se puede obtener la fecha, actualizar
 	}
 
 	// Comprobar Last-Modified guardado
@@ -180,63 +312,87 @@ func (c *Crawler) checkIfNeedsUpdate(ctx context.Context, category, url string)
 
 	// Comparar timestamps
 	needsUpdate := remoteLastModified.After(localLastModified)
-	c.logger.Debugf("%s: Remoto ->%v, Local ->%v)", category, remoteLastModified, localLastModified)
+	c.fields(category, url).WithFields(logrus.Fields{
+		"remote_last_modified": remoteLastModified,
+		"local_last_modified":  localLastModified,
+	}).Debug("Fechas de modificación comparadas")
 
 	return needsUpdate, remoteLastModified, nil
 }
 
-func (c *Crawler) downloadFile(ctx context.Context, category, url string, lastModified time.Time) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+func (c *Crawler) downloadFile(ctx context.Context, category, url string, lastModified time.Time) (string, string, int64, error) {
+	src, err := c.source(category)
 	if err != nil {
-		return "", fmt.Errorf("error al crear petición (%w)", err)
+		return "", "", 0, err
 	}
-
-	resp, err := c.client.Do(req)
+	reader, err := src.Open(ctx, fileName(category))
 	if err != nil {
-		return "", fmt.Errorf("error al realizar petición (%w)", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("código de respuesta inesperado (%d)", resp.StatusCode)
+		return "", "", 0, err
 	}
+	defer reader.Close()
 
 	// Crear directorios específicos para cada categoría
-	categoryDir := filepath.Join(c.config.DownloadPath, category)
+	categoryDir := filepath.Join(c.config.Load().DownloadPath, category)
 	if err := os.MkdirAll(categoryDir, 0755); err != nil {
-		return "", fmt.Errorf("error creando directorio de descarga (%w)", err)
+		return "", "", 0, fmt.Errorf("error creando directorio de descarga (%w)", err)
 	}
 
-	// Generar nombre de archivo: ID de categoria + URL
-	filePath := filepath.Join(categoryDir, filepath.Base(url))
+	// Generar nombre de archivo: ID de categoria + sufijo
+	filePath := filepath.Join(categoryDir, fileName(category))
 
-	// Comprobar si el archivo ya existe
-	if _, err := os.Stat(filePath); err == nil {
-		if err := os.Remove(filePath); err != nil {
-			return "", fmt.Errorf("error eliminando archivo existente (%w)", err)
-		}
-		c.logger.Debugf("Archivo preexistente eliminado: %s", filePath)
+	// Descargar a un fichero temporal para no perder la versión actual si falla
+	partialPath := filePath + partialSuffix
+	file, err := os.Create(partialPath)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("error creando archivo (%w)", err)
 	}
+	defer file.Close()
 
-	// Crear archivo
-	file, err := os.Create(filePath)
+	// Copiar contenido calculando el checksum
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hasher), reader)
+	if err == nil {
+		err = file.Close()
+	}
 	if err != nil {
-		return "", fmt.Errorf("error creando archivo (%w)", err)
+		os.Remove(partialPath) // limpiar archivo parcial en caso de error
+		return "", "", 0, fmt.Errorf("error copiando contenido (%w)", err)
 	}
-	defer file.Close()
 
-	// Copiar contenido
-	if _, err := io.Copy(file, resp.Body); err != nil {
-		os.Remove(filePath) // limpiar archivo parcial en caso de error
-		return "", fmt.Errorf("error copiando contenido (%w)", err)
+	// La versión existente pasa a ser la anterior
+	var previousPath string
+	if _, err := os.Stat(filePath); err == nil {
+		previousPath = filePath + previousSuffix
+		if err := os.Rename(filePath, previousPath); err != nil {
+			os.Remove(partialPath)
+			return "", "", 0, fmt.Errorf("error conservando versión anterior (%w)", err)
+		}
+		c.fields(category, url).WithField("file", previousPath).Debug("Versión anterior conservada")
+	}
+	if err := os.Rename(partialPath, filePath); err != nil {
+		return "", "", 0, fmt.Errorf("error moviendo archivo descargado (%w)", err)
 	}
 
 	// Actualizar metadatos
-	if err := c.metadata.UpdateLastModified(category, lastModified); err != nil {
-		c.logger.Warnf("Error al actualizar metadatos de %s: %v", category, err)
+	checksum := hex.EncodeToString(hasher.Sum(nil))
+	if err := c.metadata.UpdateLastModified(category, lastModified, size, checksum); err != nil {
+		c.fields(category, url).WithError(err).Warn("Error al actualizar metadatos")
 	}
 
-	return filePath, nil
+	return filePath, previousPath, size, nil
+}
+
+// fileName devuelve el nombre del fichero MARC de una categoría
+func fileName(category string) string {
+	return category + constants.MRCFileSuffix
+}
+
+func (c *Crawler) fields(category, url string) *logrus.Entry {
+	return c.logger.WithFields(logrus.Fields{
+		"category": category,
+		"url":      url,
+		"stage":    "download",
+	})
 }
 
 func (c *Crawler) ValidateXML(filePath string) error {
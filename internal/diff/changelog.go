@@ -0,0 +1,59 @@
+package diff
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Write guarda el registro de cambios en dir como name.json y name.csv
+func (c *Changeset) Write(dir, name string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creando directorio de cambios (%w)", err)
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error serializando cambios (%w)", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".json"), data, 0644); err != nil {
+		return fmt.Errorf("error guardando cambios (%w)", err)
+	}
+
+	return c.writeCSV(filepath.Join(dir, name+".csv"))
+}
+
+// writeCSV escribe una fila por registro añadido, eliminado o repetido y una
+// por campo de los registros modificados
+func (c *Changeset) writeCSV(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error guardando cambios (%w)", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"control_number", "change", "tag", "old", "new"})
+	for _, controlNumber := range c.Added {
+		writer.Write([]string{controlNumber, ChangeAdded, "", "", ""})
+	}
+	for _, controlNumber := range c.Deleted {
+		writer.Write([]string{controlNumber, ChangeDeleted, "", "", ""})
+	}
+	for _, controlNumber := range c.Duplicates {
+		writer.Write([]string{controlNumber, ChangeDuplicate, "", "", ""})
+	}
+	for _, record := range c.Modified {
+		for _, field := range record.Fields {
+			writer.Write([]string{record.ControlNumber, ChangeModified, field.Tag, field.Old, field.New})
+		}
+	}
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error guardando cambios (%w)", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,328 @@
+package diff
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fsoria-ttec/bne-converter/internal/parser"
+	"github.com/fsoria-ttec/bne-converter/pkg/models"
+)
+
+// Tipos de cambio de un registro
+const (
+	ChangeAdded     = "added"
+	ChangeDeleted   = "deleted"
+	ChangeModified  = "modified"
+	ChangeDuplicate = "duplicate"
+)
+
+// Pseudocampo con la cabecera del registro en las diferencias por campo
+const LeaderTag = "LDR"
+
+// Changeset son las diferencias entre dos versiones del fichero de una
+// categoría, por número de control. Si la versión anterior es la cargada, la
+// carga incremental solo compara con la base de datos los registros añadidos
+// o modificados; si no, compara todos con sus huellas almacenadas
+type Changeset struct {
+	Category  string         `json:"category"`
+	Previous  string         `json:"previous"`
+	Current   string         `json:"current"`
+	Added     []string       `json:"added"`
+	Deleted   []string       `json:"deleted"`
+	Modified  []RecordChange `json:"modified"`
+	Unchanged int            `json:"unchanged"`
+	// Números de control repetidos en la versión nueva; se compara su primera
+	// aparición
+	Duplicates []string `json:"duplicates"`
+}
+
+type RecordChange struct {
+	ControlNumber string        `json:"control_number"`
+	Fields        []FieldChange `json:"fields"`
+}
+
+// FieldChange es un campo añadido (sin Old), eliminado (sin New) o modificado
+type FieldChange struct {
+	Tag string `json:"tag"`
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+}
+
+// Huella de un registro de la versión anterior y su posición en el fichero
+type entry struct {
+	hash   uint64
+	offset int64
+}
+
+// Compare lee las dos versiones y devuelve sus diferencias. De la versión
+// anterior solo se mantiene en memoria una huella por registro; los
+// registros modificados se releen para obtener las diferencias por campo
+func Compare(ctx context.Context, category, previous, current string) (*Changeset, error) {
+	index, err := indexFile(ctx, previous)
+	if err != nil {
+		return nil, err
+	}
+
+	old, err := os.Open(previous)
+	if err != nil {
+		return nil, fmt.Errorf("error abriendo versión anterior (%w)", err)
+	}
+	defer old.Close()
+
+	changes := &Changeset{
+		Category:   category,
+		Previous:   previous,
+		Current:    current,
+		Added:      []string{},
+		Deleted:    []string{},
+		Modified:   []RecordChange{},
+		Duplicates: []string{},
+	}
+
+	// Resultado de cada número de control de la versión nueva, por orden de
+	// aparición; si se repite prevalece la primera, como en la carga
+	outcomes := make(map[string]outcome)
+	var order []string
+	err = eachRecord(ctx, current, func(record *models.Record, _ int64) error {
+		controlNumber := record.ControlNumber()
+		if previousOutcome, seen := outcomes[controlNumber]; seen {
+			if !previousOutcome.duplicate {
+				changes.Duplicates = append(changes.Duplicates, controlNumber)
+				previousOutcome.duplicate = true
+				outcomes[controlNumber] = previousOutcome
+			}
+			return nil
+		}
+
+		result, err := compareRecord(old, index, record)
+		if err != nil {
+			return err
+		}
+		order = append(order, controlNumber)
+		outcomes[controlNumber] = result
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, controlNumber := range order {
+		switch result := outcomes[controlNumber]; result.change {
+		case ChangeAdded:
+			changes.Added = append(changes.Added, controlNumber)
+		case ChangeModified:
+			changes.Modified = append(changes.Modified, RecordChange{ControlNumber: controlNumber, Fields: result.fields})
+		default:
+			changes.Unchanged++
+		}
+	}
+
+	for controlNumber := range index {
+		if _, exists := outcomes[controlNumber]; !exists {
+			changes.Deleted = append(changes.Deleted, controlNumber)
+		}
+	}
+	sort.Strings(changes.Deleted)
+	sort.Strings(changes.Duplicates)
+
+	return changes, nil
+}
+
+// outcome es el resultado de comparar un registro con la versión anterior;
+// sin change, el registro no ha cambiado
+type outcome struct {
+	change    string
+	fields    []FieldChange
+	duplicate bool
+}
+
+func compareRecord(old *os.File, index map[string]entry, record *models.Record) (outcome, error) {
+	previousEntry, exists := index[record.ControlNumber()]
+	if !exists {
+		return outcome{change: ChangeAdded}, nil
+	}
+
+	hash, err := fingerprint(record)
+	if err != nil {
+		return outcome{}, err
+	}
+	if hash == previousEntry.hash {
+		return outcome{}, nil
+	}
+
+	previousRecord, err := readAt(old, previousEntry.offset)
+	if err != nil {
+		return outcome{}, err
+	}
+	return outcome{change: ChangeModified, fields: compareFields(previousRecord, record)}, nil
+}
+
+func indexFile(ctx context.Context, path string) (map[string]entry, error) {
+	index := make(map[string]entry)
+	err := eachRecord(ctx, path, func(record *models.Record, offset int64) error {
+		if _, exists := index[record.ControlNumber()]; exists {
+			return nil
+		}
+		hash, err := fingerprint(record)
+		if err != nil {
+			return err
+		}
+		index[record.ControlNumber()] = entry{hash: hash, offset: offset}
+		return nil
+	})
+	return index, err
+}
+
+// eachRecord recorre los registros con número de control, omitiendo los mal formados
+func eachRecord(ctx context.Context, path string, fn func(*models.Record, int64) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error abriendo fichero MARC (%w)", err)
+	}
+	defer file.Close()
+
+	reader := parser.NewReader(file)
+	for count := 0; ; count++ {
+		if count%1000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		record, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		var recordErr *parser.RecordError
+		if errors.As(err, &recordErr) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if record.ControlNumber() == "" {
+			continue
+		}
+		if err := fn(record, reader.Offset()); err != nil {
+			return err
+		}
+	}
+}
+
+func fingerprint(record *models.Record) (uint64, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return 0, fmt.Errorf("error serializando registro %s (%w)", record.ControlNumber(), err)
+	}
+	hash := fnv.New64a()
+	hash.Write(data)
+	return hash.Sum64(), nil
+}
+
+func readAt(file *os.File, offset int64) (*models.Record, error) {
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("error leyendo versión anterior (%w)", err)
+	}
+	record, err := parser.NewReader(file).Next()
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo versión anterior (%w)", err)
+	}
+	return record, nil
+}
+
+// compareFields empareja los campos por etiqueta: los que solo están en una
+// versión se dan como añadidos o eliminados y, si una etiqueta tiene tantos
+// eliminados como añadidos, se emparejan como modificados
+func compareFields(previous, current *models.Record) []FieldChange {
+	before, after := fieldValues(previous), fieldValues(current)
+
+	tags := make(map[string]bool)
+	for tag := range before {
+		tags[tag] = true
+	}
+	for tag := range after {
+		tags[tag] = true
+	}
+	sorted := make([]string, 0, len(tags))
+	for tag := range tags {
+		sorted = append(sorted, tag)
+	}
+	// La cabecera va antes que los campos
+	sort.Slice(sorted, func(i, j int) bool {
+		if (sorted[i] == LeaderTag) != (sorted[j] == LeaderTag) {
+			return sorted[i] == LeaderTag
+		}
+		return sorted[i] < sorted[j]
+	})
+
+	var changes []FieldChange
+	for _, tag := range sorted {
+		removed := subtract(before[tag], after[tag])
+		added := subtract(after[tag], before[tag])
+
+		if len(removed) == len(added) {
+			for i := range removed {
+				changes = append(changes, FieldChange{Tag: tag, Old: removed[i], New: added[i]})
+			}
+			continue
+		}
+		for _, value := range removed {
+			changes = append(changes, FieldChange{Tag: tag, Old: value})
+		}
+		for _, value := range added {
+			changes = append(changes, FieldChange{Tag: tag, New: value})
+		}
+	}
+	return changes
+}
+
+// fieldValues representa cada campo como texto: "1 $aCervantes$d1547-1616".
+// La cabecera se incluye sin la longitud del registro (00-04) ni la dirección
+// base de los datos (12-16), que cambian con cualquier otro campo
+func fieldValues(record *models.Record) map[string][]string {
+	values := make(map[string][]string)
+	if leader := record.Leader; leader != "" {
+		if len(leader) >= 17 {
+			leader = "#####" + leader[5:12] + "#####" + leader[17:]
+		}
+		values[LeaderTag] = []string{leader}
+	}
+	for _, field := range record.ControlFields {
+		values[field.Tag] = append(values[field.Tag], field.Value)
+	}
+	for _, field := range record.DataFields {
+		var value strings.Builder
+		value.WriteString(field.Ind1 + field.Ind2 + " ")
+		for _, subfield := range field.Subfields {
+			value.WriteString("$" + subfield.Code + subfield.Value)
+		}
+		values[field.Tag] = append(values[field.Tag], value.String())
+	}
+	return values
+}
+
+// subtract devuelve los valores de a que no están en b, respetando repeticiones
+func subtract(a, b []string) []string {
+	pending := make(map[string]int)
+	for _, value := range b {
+		pending[value]++
+	}
+
+	var result []string
+	for _, value := range a {
+		if pending[value] > 0 {
+			pending[value]--
+			continue
+		}
+		result = append(result, value)
+	}
+	return result
+}
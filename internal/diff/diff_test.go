@@ -0,0 +1,189 @@
+package diff
+
+import (
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fsoria-ttec/bne-converter/pkg/models"
+)
+
+func TestCompare(t *testing.T) {
+	changes, err := Compare(context.Background(), "MONOMODERN", "testdata/previous.mrc", "testdata/current.mrc")
+	if err != nil {
+		t.Fatalf("Compare: %v", err)
+	}
+
+	if want := []string{"bimo0000000006"}; !reflect.DeepEqual(changes.Added, want) {
+		t.Errorf("Added = %v, se esperaba %v", changes.Added, want)
+	}
+	if want := []string{"bimo0000000003"}; !reflect.DeepEqual(changes.Deleted, want) {
+		t.Errorf("Deleted = %v, se esperaba %v", changes.Deleted, want)
+	}
+	// Se compara la primera aparición y cada repetido se anota una vez
+	if want := []string{"bimo0000000001", "bimo0000000006"}; !reflect.DeepEqual(changes.Duplicates, want) {
+		t.Errorf("Duplicates = %v, se esperaba %v", changes.Duplicates, want)
+	}
+	if changes.Unchanged != 2 {
+		t.Errorf("Unchanged = %d, se esperaba 2", changes.Unchanged)
+	}
+
+	want := []RecordChange{
+		{ControlNumber: "bimo0000000002", Fields: []FieldChange{{Tag: "650", Old: "1  $aArte", New: "1  $aMúsica"}}},
+		{ControlNumber: "bimo0000000004", Fields: []FieldChange{{
+			Tag: LeaderTag,
+			Old: "#####nam a22##### i 4500",
+			New: "#####cam a22##### i 4500",
+		}}},
+	}
+	if !reflect.DeepEqual(changes.Modified, want) {
+		t.Errorf("Modified = %+v, se esperaba %+v", changes.Modified, want)
+	}
+}
+
+func TestCompareMissingPrevious(t *testing.T) {
+	if _, err := Compare(context.Background(), "MONOMODERN", "testdata/no-existe.mrc", "testdata/current.mrc"); err == nil {
+		t.Error("se esperaba error sin versión anterior")
+	}
+}
+
+func TestCompareFields(t *testing.T) {
+	field := func(tag, ind1 string, subfields ...string) models.DataField {
+		field := models.DataField{Tag: tag, Ind1: ind1, Ind2: " "}
+		for i := 0; i+1 < len(subfields); i += 2 {
+			field.Subfields = append(field.Subfields, models.Subfield{Code: subfields[i], Value: subfields[i+1]})
+		}
+		return field
+	}
+	record := func(leader string, fields ...models.DataField) *models.Record {
+		return &models.Record{
+			Leader:        leader,
+			ControlFields: []models.ControlField{{Tag: "001", Value: "bimo0000000001"}},
+			DataFields:    fields,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		previous *models.Record
+		current  *models.Record
+		want     []FieldChange
+	}{
+		{
+			name:     "sin cambios",
+			previous: record("00100nam", field("245", "1", "a", "Título")),
+			current:  record("00100nam", field("245", "1", "a", "Título")),
+		},
+		{
+			name:     "longitud y dirección base de la cabecera",
+			previous: record("00100nam a2200049 i 4500", field("245", "1", "a", "Título")),
+			current:  record("00200nam a2200061 i 4500", field("245", "1", "a", "Título")),
+		},
+		{
+			name:     "campo modificado",
+			previous: record("", field("245", "1", "a", "Título")),
+			current:  record("", field("245", "0", "a", "Título")),
+			want:     []FieldChange{{Tag: "245", Old: "1  $aTítulo", New: "0  $aTítulo"}},
+		},
+		{
+			name:     "campos añadidos y eliminados",
+			previous: record("", field("500", " ", "a", "Nota"), field("650", " ", "a", "Arte")),
+			current:  record("", field("100", "1", "a", "Autor"), field("650", " ", "a", "Arte"), field("650", " ", "a", "Música")),
+			want: []FieldChange{
+				{Tag: "100", New: "1  $aAutor"},
+				{Tag: "500", Old: "   $aNota"},
+				{Tag: "650", New: "   $aMúsica"},
+			},
+		},
+		{
+			name:     "orden distinto de campos repetidos",
+			previous: record("", field("650", " ", "a", "Arte"), field("650", " ", "a", "Historia")),
+			current:  record("", field("650", " ", "a", "Historia"), field("650", " ", "a", "Arte")),
+		},
+		{
+			name:     "distinto número de eliminados y añadidos",
+			previous: record("", field("700", "1", "a", "Uno"), field("700", "1", "a", "Dos")),
+			current:  record("", field("700", "1", "a", "Tres")),
+			want: []FieldChange{
+				{Tag: "700", Old: "1  $aUno"},
+				{Tag: "700", Old: "1  $aDos"},
+				{Tag: "700", New: "1  $aTres"},
+			},
+		},
+		{
+			name:     "la cabecera va antes que los campos",
+			previous: record("00100nam", field("100", "1", "a", "Autor")),
+			current:  record("00100cam", field("100", "1", "a", "Otro autor")),
+			want: []FieldChange{
+				{Tag: LeaderTag, Old: "00100nam", New: "00100cam"},
+				{Tag: "100", Old: "1  $aAutor", New: "1  $aOtro autor"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := compareFields(test.previous, test.current); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("compareFields = %+v, se esperaba %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSubtract(t *testing.T) {
+	tests := []struct {
+		a, b []string
+		want []string
+	}{
+		{a: nil, b: []string{"x"}, want: nil},
+		{a: []string{"x", "y"}, b: nil, want: []string{"x", "y"}},
+		{a: []string{"x", "y"}, b: []string{"y"}, want: []string{"x"}},
+		// Las repeticiones se descuentan una a una
+		{a: []string{"x", "x", "x"}, b: []string{"x"}, want: []string{"x", "x"}},
+		{a: []string{"x"}, b: []string{"x", "x"}, want: nil},
+		{a: []string{"y", "x", "y"}, b: []string{"y", "z"}, want: []string{"x", "y"}},
+	}
+
+	for _, test := range tests {
+		if got := subtract(test.a, test.b); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("subtract(%q, %q) = %q, se esperaba %q", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestWriteChangelog(t *testing.T) {
+	changes, err := Compare(context.Background(), "MONOMODERN", "testdata/previous.mrc", "testdata/current.mrc")
+	if err != nil {
+		t.Fatalf("Compare: %v", err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "changelog")
+	if err := changes.Write(dir, "run-1"); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "run-1.json")); err != nil {
+		t.Errorf("sin registro de cambios JSON: %v", err)
+	}
+
+	file, err := os.Open(filepath.Join(dir, "run-1.csv"))
+	if err != nil {
+		t.Fatalf("abriendo registro de cambios CSV: %v", err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("CSV no válido: %v", err)
+	}
+
+	counts := make(map[string]int)
+	for _, row := range rows[1:] {
+		counts[row[1]]++
+	}
+	want := map[string]int{ChangeAdded: 1, ChangeDeleted: 1, ChangeDuplicate: 2, ChangeModified: 2}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("filas por cambio = %v, se esperaba %v", counts, want)
+	}
+}
@@ -3,6 +3,8 @@ package logger
 import (
 	"bytes"
 	"fmt"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/fsoria-ttec/bne-converter/internal/config"
@@ -12,6 +14,8 @@ import (
 type CustomFormatter struct {
 	TimestampFormat string
 	ColorEnabled    bool
+	ShowCaller      bool
+	ShowFunction    bool
 }
 
 const (
@@ -25,6 +29,8 @@ func NewCustomFormatter(config config.LoggingConfig, enableColors bool) *CustomF
 	return &CustomFormatter{
 		TimestampFormat: config.TimestampFormat,
 		ColorEnabled:    enableColors,
+		ShowCaller:      config.ShowCaller,
+		ShowFunction:    config.ShowFunction,
 	}
 }
 
@@ -58,13 +64,33 @@ func (f *CustomFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 	}
 
 	// Formato base: [timestamp] [level] message
-	fmt.Fprintf(b, "[%s] [%s] %s", timestamp, levelText, entry.Message)
+	fmt.Fprintf(b, "[%s] [%s] ", timestamp, levelText)
 
-	// Añadir campos adicionales si existen
+	// Origen de la llamada: [archivo:línea función]
+	if entry.HasCaller() && (f.ShowCaller || f.ShowFunction) {
+		var origin []string
+		if f.ShowCaller {
+			origin = append(origin, fmt.Sprintf("%s:%d", filepath.Base(entry.Caller.File), entry.Caller.Line))
+		}
+		if f.ShowFunction {
+			origin = append(origin, entry.Caller.Function[strings.LastIndex(entry.Caller.Function, "/")+1:])
+		}
+		fmt.Fprintf(b, "[%s] ", strings.Join(origin, " "))
+	}
+
+	b.WriteString(entry.Message)
+
+	// Añadir campos adicionales si existen, en orden alfabético
 	if len(entry.Data) > 0 {
+		keys := make([]string, 0, len(entry.Data))
+		for key := range entry.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
 		fmt.Fprintf(b, " |")
-		for key, value := range entry.Data {
-			fmt.Fprintf(b, " %s=%v", key, value)
+		for _, key := range keys {
+			fmt.Fprintf(b, " %s=%v", key, entry.Data[key])
 		}
 	}
 
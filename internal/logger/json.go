@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsoria-ttec/bne-converter/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Campos con posición fija en la salida JSON, tras ts, level y msg
+var orderedFields = []string{"category", "url", "run_id", "stage", "duration_ms"}
+
+// JSONFormatter genera una línea JSON por entrada con claves en orden estable,
+// pensada para su ingesta en Loki o ELK
+type JSONFormatter struct {
+	ShowCaller   bool
+	ShowFunction bool
+}
+
+func NewJSONFormatter(config config.LoggingConfig) *JSONFormatter {
+	return &JSONFormatter{
+		ShowCaller:   config.ShowCaller,
+		ShowFunction: config.ShowFunction,
+	}
+}
+
+func (f *JSONFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	var b *bytes.Buffer
+
+	if entry.Buffer != nil {
+		b = entry.Buffer
+	} else {
+		b = &bytes.Buffer{}
+	}
+
+	b.WriteByte('{')
+	writeJSONField(b, "ts", entry.Time.Format(time.RFC3339Nano), true)
+	writeJSONField(b, "level", entry.Level.String(), false)
+	writeJSONField(b, "msg", entry.Message, false)
+
+	written := make(map[string]bool, len(orderedFields))
+	for _, key := range orderedFields {
+		if value, exists := entry.Data[key]; exists {
+			writeJSONField(b, key, value, false)
+			written[key] = true
+		}
+	}
+
+	keys := make([]string, 0, len(entry.Data))
+	for key := range entry.Data {
+		if !written[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		writeJSONField(b, key, entry.Data[key], false)
+	}
+
+	if entry.HasCaller() {
+		if f.ShowCaller {
+			writeJSONField(b, "caller", fmt.Sprintf("%s:%d", filepath.Base(entry.Caller.File), entry.Caller.Line), false)
+		}
+		if f.ShowFunction {
+			writeJSONField(b, "func", entry.Caller.Function[strings.LastIndex(entry.Caller.Function, "/")+1:], false)
+		}
+	}
+
+	b.WriteString("}\n")
+	return b.Bytes(), nil
+}
+
+func writeJSONField(b *bytes.Buffer, key string, value interface{}, first bool) {
+	if !first {
+		b.WriteByte(',')
+	}
+
+	// Los errores no se serializan como JSON, se usa su mensaje
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+
+	encodedKey, _ := json.Marshal(key)
+	encodedValue, err := json.Marshal(value)
+	if err != nil {
+		encodedValue, _ = json.Marshal(fmt.Sprintf("%v", value))
+	}
+
+	b.Write(encodedKey)
+	b.WriteByte(':')
+	b.Write(encodedValue)
+}
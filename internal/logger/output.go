@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/fsoria-ttec/bne-converter/internal/config"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2" // rotación de logs
+)
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// fileHook replica cada entrada en el fichero de log, sin colores
+type fileHook struct {
+	writer    io.Writer
+	formatter logrus.Formatter
+}
+
+func (h *fileHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *fileHook) Fire(entry *logrus.Entry) error {
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = h.writer.Write(line)
+	return err
+}
+
+// Configure aplica la salida, el formato y el origen de llamada definidos en
+// la configuración. El io.Closer devuelto cierra el fichero de log, si lo hay
+func Configure(log *logrus.Logger, cfg config.LoggingConfig, enableColors bool) (io.Closer, error) {
+	log.SetReportCaller(cfg.ShowCaller || cfg.ShowFunction)
+
+	switch cfg.Output {
+	case "file":
+		file, err := newRotatingFile(cfg)
+		if err != nil {
+			return nil, err
+		}
+		log.SetOutput(file)
+		log.SetFormatter(newFormatter(cfg, false))
+		return file, nil
+
+	case "both":
+		file, err := newRotatingFile(cfg)
+		if err != nil {
+			return nil, err
+		}
+		log.SetOutput(os.Stdout)
+		log.SetFormatter(newFormatter(cfg, enableColors))
+		log.AddHook(&fileHook{writer: file, formatter: newFormatter(cfg, false)})
+		return file, nil
+
+	default:
+		log.SetOutput(os.Stdout)
+		log.SetFormatter(newFormatter(cfg, enableColors))
+		return nopCloser{}, nil
+	}
+}
+
+func newFormatter(cfg config.LoggingConfig, enableColors bool) logrus.Formatter {
+	if cfg.Format == "json" {
+		return NewJSONFormatter(cfg)
+	}
+	return NewCustomFormatter(cfg, enableColors)
+}
+
+func newRotatingFile(cfg config.LoggingConfig) (*lumberjack.Logger, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.FileOutput), 0755); err != nil {
+		return nil, err
+	}
+
+	return &lumberjack.Logger{
+		Filename:   cfg.FileOutput,
+		MaxSize:    cfg.Rotation.MaxSizeMB,
+		MaxAge:     cfg.Rotation.MaxAgeDays,
+		MaxBackups: cfg.Rotation.MaxBackups,
+		Compress:   cfg.Rotation.Compress,
+		LocalTime:  true,
+	}, nil
+}
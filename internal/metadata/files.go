@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
@@ -12,11 +13,14 @@ type FileMetadata struct {
 	Category     string    `json:"category"`
 	LastModified time.Time `json:"last_modified"`
 	LastChecked  time.Time `json:"last_checked"`
+	Size         int64     `json:"size"`
+	Checksum     string    `json:"checksum"` // SHA-256 del fichero descargado
 }
 
 type MetadataStore struct {
 	Files map[string]FileMetadata `json:"files"`
 	path  string
+	mu    sync.RWMutex
 }
 
 func NewMetadataStore(basePath string) (*MetadataStore, error) {
@@ -52,18 +56,34 @@ func (m *MetadataStore) save() error {
 	return os.WriteFile(m.path, data, 0644)
 }
 
-func (m *MetadataStore) UpdateLastModified(category string, lastModified time.Time) error {
+func (m *MetadataStore) UpdateLastModified(category string, lastModified time.Time, size int64, checksum string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.Files[category] = FileMetadata{
 		Category:     category,
 		LastModified: lastModified,
 		LastChecked:  time.Now(),
+		Size:         size,
+		Checksum:     checksum,
 	}
 	return m.save()
 }
 
 func (m *MetadataStore) GetLastModified(category string) (time.Time, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	if metadata, exists := m.Files[category]; exists {
 		return metadata.LastModified, true
 	}
 	return time.Time{}, false
 }
+
+func (m *MetadataStore) Get(category string) (FileMetadata, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	metadata, exists := m.Files[category]
+	return metadata, exists
+}
@@ -0,0 +1,214 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus" // logging
+)
+
+const namespace = "bne"
+
+// Resultados usados como etiqueta
+const (
+	ResultOK      = "ok"
+	ResultError   = "error"
+	ResultSkipped = "skipped"
+	ResultChanged = "changed"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	downloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "crawler",
+		Name:      "downloads_total",
+		Help:      "Descargas por categoría y resultado (ok, error, skipped).",
+	}, []string{"category", "result"})
+
+	downloadBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "crawler",
+		Name:      "download_bytes_total",
+		Help:      "Bytes descargados por categoría.",
+	}, []string{"category"})
+
+	downloadDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "crawler",
+		Name:      "download_duration_seconds",
+		Help:      "Duración de las descargas completadas por categoría.",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 12),
+	}, []string{"category"})
+
+	downloadFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "crawler",
+		Name:      "download_attempt_failures_total",
+		Help:      "Intentos de descarga fallidos por categoría, incluidos los reintentados.",
+	}, []string{"category"})
+
+	monitorChecks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "monitor",
+		Name:      "checks_total",
+		Help:      "Comprobaciones de la web de la BNE por resultado (ok, changed, error).",
+	}, []string{"result"})
+
+	monitorLastSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "monitor",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Momento de la última comprobación correcta.",
+	})
+
+	categoryLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "pipeline",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Momento del último procesamiento correcto por categoría.",
+	}, []string{"category"})
+
+	parsedRecords = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "parser",
+		Name:      "records_total",
+		Help:      "Registros MARC leídos por categoría.",
+	}, []string{"category"})
+
+	parseErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "parser",
+		Name:      "errors_total",
+		Help:      "Registros MARC mal formados por categoría.",
+	}, []string{"category"})
+
+	storageRows = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "rows_total",
+		Help:      "Registros de las cargas confirmadas por categoría y operación (added, updated, unchanged, deleted).",
+	}, []string{"category", "operation"})
+
+	storageLoads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "loads_total",
+		Help:      "Cargas en base de datos por categoría y resultado: swapped (transacción confirmada) o rolled_back.",
+	}, []string{"category", "outcome"})
+
+	publishes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ckan",
+		Name:      "publish_total",
+		Help:      "Publicaciones en CKAN por categoría y resultado (ok, error).",
+	}, []string{"category", "outcome"})
+
+	publishLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "ckan",
+		Name:      "last_publish_timestamp_seconds",
+		Help:      "Momento de la última publicación correcta en CKAN por categoría.",
+	}, []string{"category"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		downloads,
+		downloadBytes,
+		downloadDuration,
+		downloadFailures,
+		monitorChecks,
+		monitorLastSuccess,
+		categoryLastSuccess,
+		parsedRecords,
+		parseErrors,
+		storageRows,
+		storageLoads,
+		publishes,
+		publishLastSuccess,
+	)
+}
+
+func ObserveDownload(category, result string, bytes int64, duration time.Duration) {
+	downloads.WithLabelValues(category, result).Inc()
+	if result == ResultOK {
+		downloadBytes.WithLabelValues(category).Add(float64(bytes))
+		downloadDuration.WithLabelValues(category).Observe(duration.Seconds())
+	}
+}
+
+func ObserveDownloadAttemptFailure(category string) {
+	downloadFailures.WithLabelValues(category).Inc()
+}
+
+func ObserveMonitorCheck(result string) {
+	monitorChecks.WithLabelValues(result).Inc()
+	if result != ResultError {
+		monitorLastSuccess.SetToCurrentTime()
+	}
+}
+
+func ObserveParse(category string, records, errors int) {
+	parsedRecords.WithLabelValues(category).Add(float64(records))
+	parseErrors.WithLabelValues(category).Add(float64(errors))
+}
+
+// ObserveLoad registra el resultado de la carga de una categoría; los
+// registros solo cuentan si la carga se ha confirmado
+func ObserveLoad(category, outcome string, added, updated, unchanged, deleted int) {
+	storageLoads.WithLabelValues(category, outcome).Inc()
+	for operation, rows := range map[string]int{
+		"added":     added,
+		"updated":   updated,
+		"unchanged": unchanged,
+		"deleted":   deleted,
+	} {
+		storageRows.WithLabelValues(category, operation).Add(float64(rows))
+	}
+}
+
+func ObservePublish(category, outcome string) {
+	publishes.WithLabelValues(category, outcome).Inc()
+	if outcome == ResultOK {
+		publishLastSuccess.WithLabelValues(category).SetToCurrentTime()
+	}
+}
+
+func ObserveCategorySuccess(category string) {
+	categoryLastSuccess.WithLabelValues(category).SetToCurrentTime()
+}
+
+// Serve expone /metrics en address hasta que se cancele el contexto
+func Serve(ctx context.Context, address string, logger *logrus.Logger) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	server := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	go func() {
+		logger.WithField("address", address).Info("Métricas disponibles en /metrics")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.WithError(err).Error("Error en el servidor de métricas")
+		}
+	}()
+}
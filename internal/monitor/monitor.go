@@ -5,41 +5,70 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
-	"io"
-	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/fsoria-ttec/bne-converter/internal/config"
+	"github.com/fsoria-ttec/bne-converter/internal/constants"
+	"github.com/fsoria-ttec/bne-converter/internal/crawler"
+	"github.com/fsoria-ttec/bne-converter/internal/metrics"
+	"github.com/fsoria-ttec/bne-converter/internal/schedule"
+	"github.com/fsoria-ttec/bne-converter/internal/source"
 	"github.com/sirupsen/logrus" // logging
 )
 
 type Monitor struct {
-	client        *http.Client
-	config        *config.MonitorConfig
-	baseURL       string
+	sources       []source.Source
+	config        atomic.Pointer[config.MonitorConfig]
 	logger        *logrus.Logger
 	lastCheckHash map[string]string
+	paused        atomic.Bool
 }
 
 type FileChange struct {
 	URL          string
 	IsNew        bool
 	LastModified time.Time
-	ETag         string
 }
 
-func New(cfg *config.Config, logger *logrus.Logger) *Monitor {
-	client := &http.Client{
-		Timeout: cfg.Monitor.Timeout,
+func New(cfg *config.Config, logger *logrus.Logger) (*Monitor, error) {
+	sources, err := crawler.NewSources(&cfg.Crawler, cfg.Monitor.Timeout)
+	if err != nil {
+		return nil, err
 	}
 
-	return &Monitor{
-		client:        client,
-		config:        &cfg.Monitor,
-		baseURL:       cfg.Crawler.BaseURL,
+	monitor := &Monitor{
 		logger:        logger,
 		lastCheckHash: make(map[string]string),
 	}
+	for _, family := range []string{constants.FamilyBibliographic, constants.FamilyAuthority} {
+		if src, exists := sources[family]; exists {
+			monitor.sources = append(monitor.sources, src)
+		}
+	}
+	monitor.config.Store(&cfg.Monitor)
+
+	return monitor, nil
+}
+
+// UpdateConfig sustituye la configuración usada a partir del siguiente ciclo
+func (m *Monitor) UpdateConfig(cfg *config.MonitorConfig) {
+	m.config.Store(cfg)
+}
+
+// Pause suspende las comprobaciones hasta que se llame a Resume
+func (m *Monitor) Pause() {
+	m.paused.Store(true)
+}
+
+func (m *Monitor) Resume() {
+	m.paused.Store(false)
+}
+
+func (m *Monitor) Paused() bool {
+	return m.paused.Load()
 }
 
 func (m *Monitor) Start(ctx context.Context) (<-chan FileChange, <-chan error) {
@@ -50,17 +79,22 @@ func (m *Monitor) Start(ctx context.Context) (<-chan FileChange, <-chan error) {
 		defer close(changes)
 		defer close(errs)
 
-		ticker := time.NewTicker(m.config.CheckInterval)
-		defer ticker.Stop()
+		// Primera comprobación inmediata, sin esperar al primer ciclo
+		if m.config.Load().CheckOnStart {
+			m.check(ctx, changes, errs)
+		}
+
+		// Temporizador en lugar de ticker para recalcular la planificación en cada ciclo
+		timer := time.NewTimer(m.untilNextCheck())
+		defer timer.Stop()
 
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case <-ticker.C:
-				if err := m.checkForChanges(ctx, changes); err != nil {
-					errs <- err
-				}
+			case <-timer.C:
+				m.check(ctx, changes, errs)
+				timer.Reset(m.untilNextCheck())
 			}
 		}
 	}()
@@ -68,42 +102,105 @@ func (m *Monitor) Start(ctx context.Context) (<-chan FileChange, <-chan error) {
 	return changes, errs
 }
 
-func (m *Monitor) checkForChanges(ctx context.Context, changes chan<- FileChange) error {
-	req, err := http.NewRequestWithContext(ctx, "GET", m.baseURL, nil)
-	if err != nil {
-		return fmt.Errorf("Error al crear petición: %w", err)
+func (m *Monitor) check(ctx context.Context, changes chan<- FileChange, errs chan<- error) {
+	if m.Paused() {
+		m.logger.WithField("stage", "monitor").Debug("Monitor en pausa, comprobación omitida")
+		return
 	}
 
-	resp, err := m.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("Error al realizar petición: %w", err)
+	for _, src := range m.sources {
+		start := time.Now()
+		changed, err := m.checkForChanges(ctx, src, changes)
+		switch {
+		case err != nil:
+			metrics.ObserveMonitorCheck(metrics.ResultError)
+			errs <- err
+		case changed:
+			metrics.ObserveMonitorCheck(metrics.ResultChanged)
+		default:
+			metrics.ObserveMonitorCheck(metrics.ResultOK)
+		}
+		m.logger.WithFields(logrus.Fields{
+			"url":         src.Location(""),
+			"stage":       "monitor",
+			"duration_ms": time.Since(start).Milliseconds(),
+		}).Debug("Comprobación de cambios finalizada")
 	}
-	defer resp.Body.Close()
+}
+
+// untilNextCheck calcula la espera hasta la siguiente comprobación según la
+// expresión cron o el intervalo, la ventana de comprobación y la zona horaria
+func (m *Monitor) untilNextCheck() time.Duration {
+	cfg := m.config.Load()
+	now := time.Now()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Código de estado inesperado: %d", resp.StatusCode)
+	// La configuración ya está validada; ante un error se usa el intervalo
+	location, err := schedule.Location(cfg.Timezone)
+	if err != nil {
+		location = time.Local
 	}
+	window, err := schedule.ParseWindow(cfg.CheckWindow)
+	if err != nil {
+		return cfg.CheckInterval
+	}
+	sched, err := schedule.New(cfg.Schedule, cfg.CheckInterval, window)
+	if err != nil {
+		return cfg.CheckInterval
+	}
+
+	next := sched.Next(now.In(location))
+	m.logger.WithFields(logrus.Fields{
+		"stage":      "monitor",
+		"next_check": next.Format(time.RFC3339),
+	}).Debug("Siguiente comprobación planificada")
+
+	return next.Sub(now)
+}
 
-	// Leer y calcular el hash del contenido
-	content, err := io.ReadAll(resp.Body)
+// checkForChanges calcula una huella con el nombre, tamaño y fecha de los
+// ficheros MARC publicados en el origen y la compara con la anterior
+func (m *Monitor) checkForChanges(ctx context.Context, src source.Source, changes chan<- FileChange) (bool, error) {
+	location := src.Location("")
+	files, err := src.List(ctx)
 	if err != nil {
-		return fmt.Errorf("Error al leer cuerpo de respuesta: %w", err)
+		return false, fmt.Errorf("Error al listar %s: %w", location, err)
+	}
+
+	var lines []string
+	var lastModified time.Time
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name, constants.MRCFileSuffix) {
+			continue
+		}
+
+		info, err := src.Stat(ctx, file.Name)
+		if err != nil {
+			return false, fmt.Errorf("Error al consultar %s: %w", file.Name, err)
+		}
+		if info.LastModified.After(lastModified) {
+			lastModified = info.LastModified
+		}
+		lines = append(lines, fmt.Sprintf("%s %d %d", info.Name, info.Size, info.LastModified.Unix()))
 	}
+	sort.Strings(lines)
 
-	hash := m.calculateHash(content)
-	lastHash, exists := m.lastCheckHash[m.baseURL]
+	hash := m.calculateHash([]byte(strings.Join(lines, "\n")))
+	lastHash, exists := m.lastCheckHash[location]
 
 	if !exists || hash != lastHash {
-		m.lastCheckHash[m.baseURL] = hash
+		m.lastCheckHash[location] = hash
+		if lastModified.IsZero() {
+			lastModified = time.Now()
+		}
 		changes <- FileChange{
-			URL:          m.baseURL,
+			URL:          location,
 			IsNew:        !exists,
-			LastModified: m.parseLastModified(resp.Header.Get("Last-Modified")),
-			ETag:         resp.Header.Get("ETag"),
+			LastModified: lastModified,
 		}
+		return true, nil
 	}
 
-	return nil
+	return false, nil
 }
 
 func (m *Monitor) calculateHash(content []byte) string {
@@ -111,17 +208,3 @@ func (m *Monitor) calculateHash(content []byte) string {
 	hasher.Write(content)
 	return hex.EncodeToString(hasher.Sum(nil))
 }
-
-func (m *Monitor) parseLastModified(lastModified string) time.Time {
-	if lastModified == "" {
-		return time.Now()
-	}
-
-	t, err := time.Parse(time.RFC1123, lastModified)
-	if err != nil {
-		m.logger.Warnf("Error al parsear cabecera Last-Modified: %v", err)
-		return time.Now()
-	}
-
-	return t
-}
@@ -0,0 +1,293 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsoria-ttec/bne-converter/internal/config"
+	"github.com/sirupsen/logrus" // logging
+)
+
+// Espera mínima y máxima antes de reintentar un resumen que no se ha podido
+// enviar; la espera se duplica con cada fallo consecutivo
+const (
+	emailRetryMinDelay = time.Second
+	emailRetryDelay    = time.Minute
+)
+
+// Email envía por SMTP un resumen de las ejecuciones fallidas. Como mucho se
+// envía un correo cada min_interval; las ejecuciones fallidas entre medias se
+// agrupan en el siguiente. El último envío y los fallos pendientes se guardan
+// en state_path para respetar el límite entre ejecuciones del modo manual; los
+// pendientes de una ejecución anterior se envían con el siguiente resumen
+type Email struct {
+	config          *config.EmailConfig
+	timestampFormat string
+	logger          *logrus.Logger
+
+	mu       sync.Mutex
+	state    emailState
+	timer    *time.Timer
+	flushing bool // envío en curso; los nuevos fallos esperan a que termine
+	failures int  // envíos fallidos consecutivos
+	sending  sync.WaitGroup
+	closed   bool
+}
+
+type emailState struct {
+	LastSent time.Time `json:"last_sent"`
+	Pending  []Event   `json:"pending"`
+}
+
+func NewEmail(cfg *config.Config, logger *logrus.Logger) (*Email, error) {
+	email := &Email{
+		config:          &cfg.Notifications.Email,
+		timestampFormat: cfg.Logging.TimestampFormat,
+		logger:          logger,
+	}
+
+	data, err := os.ReadFile(email.config.StatePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error leyendo estado de correo (%w)", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &email.state); err != nil {
+			return nil, fmt.Errorf("error interpretando estado de correo (%w)", err)
+		}
+	}
+	return email, nil
+}
+
+func (e *Email) Notify(event Event) {
+	if event.Type != EventRunFailed {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return
+	}
+
+	e.state.Pending = append(e.state.Pending, event)
+	e.schedule()
+}
+
+// Close envía el resumen pendiente si el límite lo permite y, si no, lo
+// guarda para la siguiente ejecución
+func (e *Email) Close() error {
+	e.mu.Lock()
+	e.closed = true
+	if e.timer != nil && e.timer.Stop() {
+		e.timer = nil
+		e.sending.Done()
+	}
+	if e.timer == nil && !e.flushing && len(e.state.Pending) > 0 && e.wait() == 0 {
+		e.sending.Add(1)
+		go e.flush()
+	}
+	e.mu.Unlock()
+
+	e.sending.Wait()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.save()
+}
+
+// schedule programa el envío del resumen; requiere e.mu
+func (e *Email) schedule() {
+	if e.timer != nil || e.flushing {
+		return
+	}
+	e.sending.Add(1)
+	e.timer = time.AfterFunc(e.wait(), e.flush)
+}
+
+// retry programa un nuevo intento tras un envío fallido, que no consume el
+// límite entre correos; requiere e.mu
+func (e *Email) retry() {
+	if e.timer != nil || e.closed {
+		return
+	}
+	delay := max(min(e.config.MinInterval, emailRetryDelay), emailRetryMinDelay)
+	for i := 1; i < e.failures && delay < emailRetryDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, emailRetryDelay)
+	e.sending.Add(1)
+	e.timer = time.AfterFunc(delay, e.flush)
+}
+
+// wait devuelve el tiempo que falta para poder enviar otro correo; requiere e.mu
+func (e *Email) wait() time.Duration {
+	wait := time.Until(e.state.LastSent.Add(e.config.MinInterval))
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+func (e *Email) flush() {
+	defer e.sending.Done()
+
+	e.mu.Lock()
+	events := e.state.Pending
+	e.state.Pending = nil
+	e.timer = nil
+	e.flushing = len(events) > 0
+	e.mu.Unlock()
+
+	if len(events) == 0 {
+		return
+	}
+
+	attempt := time.Now()
+	err := e.send(e.subject(events), e.body(events))
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.flushing = false
+	if err != nil {
+		// Se conservan para el reintento o, tras Close, para la siguiente ejecución
+		e.state.Pending = append(events, e.state.Pending...)
+		e.failures++
+		e.logger.WithError(err).Error("Error enviando resumen de fallos por correo")
+		e.retry()
+	} else {
+		e.state.LastSent = attempt
+		e.failures = 0
+		e.logger.WithField("runs", len(events)).Info("Resumen de fallos enviado por correo")
+		if len(e.state.Pending) > 0 && !e.closed {
+			e.schedule()
+		}
+	}
+
+	if err := e.save(); err != nil {
+		e.logger.WithError(err).Warn("Error guardando estado de correo")
+	}
+}
+
+// save persiste el estado; requiere e.mu
+func (e *Email) save() error {
+	data, err := json.MarshalIndent(e.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error serializando estado de correo (%w)", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(e.config.StatePath), 0755); err != nil {
+		return fmt.Errorf("error creando directorio de estado de correo (%w)", err)
+	}
+	return os.WriteFile(e.config.StatePath, data, 0644)
+}
+
+func (e *Email) subject(events []Event) string {
+	categories := make(map[string]bool)
+	for _, event := range events {
+		for _, failure := range event.Failures {
+			categories[failure.Category] = true
+		}
+	}
+
+	return strings.TrimSpace(fmt.Sprintf("%s %d ejecuciones fallidas (%d categorías)",
+		e.config.SubjectPrefix, len(events), len(categories)))
+}
+
+func (e *Email) body(events []Event) string {
+	var body strings.Builder
+
+	body.WriteString("Las siguientes ejecuciones han terminado con errores:\n")
+	for _, event := range events {
+		fmt.Fprintf(&body, "\nEjecución %s (%s)\n", event.RunID, event.Timestamp.Format(e.timestampFormat))
+		for _, failure := range event.Failures {
+			lastSuccess := "nunca"
+			if !failure.LastSuccess.IsZero() {
+				lastSuccess = failure.LastSuccess.Format(e.timestampFormat)
+			}
+			fmt.Fprintf(&body, "  - %s: %s\n", failure.Category, failure.Error)
+			fmt.Fprintf(&body, "    Última actualización correcta: %s\n", lastSuccess)
+		}
+	}
+	return body.String()
+}
+
+func (e *Email) send(subject, body string) error {
+	address := net.JoinHostPort(e.config.Host, strconv.Itoa(e.config.Port))
+
+	conn, err := net.DialTimeout("tcp", address, e.config.Timeout)
+	if err != nil {
+		return fmt.Errorf("error conectando con el servidor SMTP (%w)", err)
+	}
+	conn.SetDeadline(time.Now().Add(e.config.Timeout))
+
+	client, err := smtp.NewClient(conn, e.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("error iniciando sesión SMTP (%w)", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: e.config.Host}); err != nil {
+			return fmt.Errorf("error negociando STARTTLS (%w)", err)
+		}
+	}
+	if e.config.Username != "" {
+		auth := smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("error de autenticación SMTP (%w)", err)
+		}
+	}
+
+	if err := client.Mail(e.config.From); err != nil {
+		return fmt.Errorf("remitente rechazado (%w)", err)
+	}
+	for _, to := range e.config.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("destinatario rechazado %s (%w)", to, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("error iniciando envío del mensaje (%w)", err)
+	}
+	if _, err := writer.Write(e.message(subject, body)); err != nil {
+		return fmt.Errorf("error enviando el mensaje (%w)", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("mensaje rechazado (%w)", err)
+	}
+
+	if err := client.Quit(); err != nil && !errors.Is(err, net.ErrClosed) {
+		return fmt.Errorf("error cerrando sesión SMTP (%w)", err)
+	}
+	return nil
+}
+
+func (e *Email) message(subject, body string) []byte {
+	var message bytes.Buffer
+
+	fmt.Fprintf(&message, "From: %s\r\n", e.config.From)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(e.config.To, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	message.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	message.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	return message.Bytes()
+}
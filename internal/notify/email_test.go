@@ -0,0 +1,362 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsoria-ttec/bne-converter/internal/config"
+	"github.com/sirupsen/logrus" // logging
+)
+
+// smtpSink es un servidor SMTP local mínimo que guarda los mensajes recibidos.
+// Rechaza los primeros fail mensajes con un error temporal
+type smtpSink struct {
+	listener net.Listener
+	messages chan smtpMessage
+
+	mu   sync.Mutex
+	fail int
+}
+
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+	at   time.Time
+}
+
+func newSMTPSink(t *testing.T, fail int) *smtpSink {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("iniciando servidor SMTP: %v", err)
+	}
+	sink := &smtpSink{listener: listener, messages: make(chan smtpMessage, 16), fail: fail}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go sink.serve(conn)
+		}
+	}()
+	return sink
+}
+
+func (s *smtpSink) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	reply("220 localhost ESMTP")
+
+	var message smtpMessage
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.TrimSpace(line)
+		verb := strings.ToUpper(strings.SplitN(command, " ", 2)[0])
+
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			message = smtpMessage{from: command}
+			reply("250 OK")
+		case "RCPT":
+			message.to = append(message.to, command)
+			reply("250 OK")
+		case "DATA":
+			reply("354 fin con <CRLF>.<CRLF>")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			message.data = data.String()
+			message.at = time.Now()
+
+			s.mu.Lock()
+			failed := s.fail > 0
+			s.fail--
+			s.mu.Unlock()
+			if failed {
+				reply("451 error temporal")
+				continue
+			}
+			s.messages <- message
+			reply("250 OK")
+		case "RSET", "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 adiós")
+			return
+		default:
+			reply("502 no implementado")
+		}
+	}
+}
+
+// next espera el siguiente mensaje aceptado
+func (s *smtpSink) next(t *testing.T, timeout time.Duration) smtpMessage {
+	t.Helper()
+
+	select {
+	case message := <-s.messages:
+		return message
+	case <-time.After(timeout):
+		t.Fatalf("ningún correo recibido en %s", timeout)
+		return smtpMessage{}
+	}
+}
+
+// none comprueba que no llega ningún mensaje durante el intervalo
+func (s *smtpSink) none(t *testing.T, wait time.Duration) {
+	t.Helper()
+
+	select {
+	case message := <-s.messages:
+		t.Fatalf("correo inesperado:\n%s", message.data)
+	case <-time.After(wait):
+	}
+}
+
+func newTestEmail(t *testing.T, port int, minInterval time.Duration, statePath string) *Email {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Logging.TimestampFormat = time.RFC3339
+	cfg.Notifications.Email = config.EmailConfig{
+		Enabled:       true,
+		Host:          "127.0.0.1",
+		Port:          port,
+		From:          "bne-converter@example.org",
+		To:            []string{"catalogo@example.org"},
+		SubjectPrefix: "[BNE]",
+		MinInterval:   minInterval,
+		Timeout:       5 * time.Second,
+		StatePath:     statePath,
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	email, err := NewEmail(cfg, logger)
+	if err != nil {
+		t.Fatalf("NewEmail: %v", err)
+	}
+	return email
+}
+
+func failedRun(runID, category, cause string) Event {
+	event := NewEvent(EventRunFailed)
+	event.RunID = runID
+	event.Failures = []Failure{{Category: category, Error: cause}}
+	return event
+}
+
+func readEmailState(t *testing.T, path string) emailState {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("leyendo estado de correo: %v", err)
+	}
+	var state emailState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("estado de correo no válido: %v", err)
+	}
+	return state
+}
+
+func TestEmailDigestRateLimit(t *testing.T) {
+	sink := newSMTPSink(t, 0)
+	interval := 300 * time.Millisecond
+	email := newTestEmail(t, sink.port(), interval, filepath.Join(t.TempDir(), "email.json"))
+	defer email.Close()
+
+	// Sin envíos previos el primer fallo se envía en el acto
+	email.Notify(failedRun("run-1", "monomodernas", "checksum no coincide"))
+	first := sink.next(t, 2*time.Second)
+	if !strings.Contains(first.data, "run-1") || !strings.Contains(first.data, "checksum no coincide") {
+		t.Errorf("primer resumen sin la ejecución fallida:\n%s", first.data)
+	}
+	if !strings.Contains(first.from, "bne-converter@example.org") || len(first.to) != 1 ||
+		!strings.Contains(first.to[0], "catalogo@example.org") {
+		t.Errorf("sobre del primer resumen: %s %v", first.from, first.to)
+	}
+
+	// Los siguientes esperan a que pase el intervalo y se agrupan
+	email.Notify(failedRun("run-2", "monomodernas", "descarga interrumpida"))
+	email.Notify(failedRun("run-3", "autoridades", "fichero vacío"))
+	email.Notify(NewEvent(EventLoadCompleted))
+	sink.none(t, interval/2)
+
+	digest := sink.next(t, 2*time.Second)
+	// El intervalo cuenta desde el inicio del envío anterior
+	if gap := digest.at.Sub(first.at); gap < interval-50*time.Millisecond {
+		t.Errorf("segundo correo a los %s, antes del intervalo mínimo %s", gap, interval)
+	}
+	for _, want := range []string{"run-2", "run-3", "descarga interrumpida", "fichero vacío",
+		"Última actualización correcta: nunca", "2_ejecuciones_fallidas"} {
+		if !strings.Contains(digest.data, want) {
+			t.Errorf("resumen sin %q:\n%s", want, digest.data)
+		}
+	}
+	if strings.Contains(digest.data, "run-1") {
+		t.Errorf("resumen con una ejecución ya enviada:\n%s", digest.data)
+	}
+	sink.none(t, interval/2)
+}
+
+func TestEmailStateAcrossRestarts(t *testing.T) {
+	sink := newSMTPSink(t, 0)
+	statePath := filepath.Join(t.TempDir(), "notify", "email.json")
+
+	// Primera ejecución: se envía un resumen y el siguiente fallo queda pendiente
+	email := newTestEmail(t, sink.port(), time.Hour, statePath)
+	email.Notify(failedRun("run-1", "monomodernas", "error 1"))
+	sink.next(t, 2*time.Second)
+	email.Notify(failedRun("run-2", "monomodernas", "error 2"))
+	if err := email.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	sink.none(t, 100*time.Millisecond)
+
+	state := readEmailState(t, statePath)
+	if state.LastSent.IsZero() || len(state.Pending) != 1 || state.Pending[0].RunID != "run-2" {
+		t.Fatalf("estado tras la primera ejecución: %+v", state)
+	}
+
+	// Segunda ejecución dentro del intervalo: el límite se respeta
+	email = newTestEmail(t, sink.port(), time.Hour, statePath)
+	email.Notify(failedRun("run-3", "autoridades", "error 3"))
+	if err := email.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	sink.none(t, 100*time.Millisecond)
+
+	if state := readEmailState(t, statePath); len(state.Pending) != 2 {
+		t.Fatalf("pendientes tras la segunda ejecución: %+v", state.Pending)
+	}
+
+	// Tercera ejecución con el intervalo cumplido: se envían los pendientes
+	email = newTestEmail(t, sink.port(), time.Millisecond, statePath)
+	if err := email.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	digest := sink.next(t, 2*time.Second)
+	for _, want := range []string{"run-2", "run-3"} {
+		if !strings.Contains(digest.data, want) {
+			t.Errorf("resumen sin %q:\n%s", want, digest.data)
+		}
+	}
+	if state := readEmailState(t, statePath); len(state.Pending) != 0 {
+		t.Errorf("pendientes tras el envío: %+v", state.Pending)
+	}
+}
+
+func TestEmailRetryAfterFailure(t *testing.T) {
+	sink := newSMTPSink(t, 1)
+	statePath := filepath.Join(t.TempDir(), "email.json")
+	email := newTestEmail(t, sink.port(), 100*time.Millisecond, statePath)
+	defer email.Close()
+
+	// El primer envío se rechaza y se reintenta sin esperar a otro fallo
+	email.Notify(failedRun("run-1", "monomodernas", "error 1"))
+	message := sink.next(t, 2*time.Second)
+	if !strings.Contains(message.data, "run-1") {
+		t.Errorf("reintento sin la ejecución fallida:\n%s", message.data)
+	}
+}
+
+func TestEmailFailedSendKeepsRateLimit(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "email.json")
+
+	// Servidor que rechaza todos los envíos
+	rejecting := newSMTPSink(t, 1<<30)
+	email := newTestEmail(t, rejecting.port(), time.Hour, statePath)
+	email.Notify(failedRun("run-1", "monomodernas", "error 1"))
+	waitFor(t, func() bool {
+		rejecting.mu.Lock()
+		defer rejecting.mu.Unlock()
+		return rejecting.fail < 1<<30
+	})
+	if err := email.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	state := readEmailState(t, statePath)
+	if !state.LastSent.IsZero() || len(state.Pending) != 1 {
+		t.Fatalf("un envío fallido no debe contar como enviado: %+v", state)
+	}
+
+	// La siguiente ejecución puede enviar en el acto
+	sink := newSMTPSink(t, 0)
+	email = newTestEmail(t, sink.port(), time.Hour, statePath)
+	if err := email.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if message := sink.next(t, 2*time.Second); !strings.Contains(message.data, "run-1") {
+		t.Errorf("resumen sin la ejecución pendiente:\n%s", message.data)
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal(errors.New("tiempo de espera agotado"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEmailRetryBackoffWithoutMinInterval(t *testing.T) {
+	const rejected = 1 << 30
+	sink := newSMTPSink(t, rejected)
+	email := newTestEmail(t, sink.port(), 0, filepath.Join(t.TempDir(), "email.json"))
+
+	// Sin intervalo mínimo los reintentos esperan al menos emailRetryMinDelay
+	// y la espera se duplica: intentos a los 0s, 1s y 3s
+	email.Notify(failedRun("run-1", "monomodernas", "error 1"))
+	time.Sleep(2 * emailRetryMinDelay)
+
+	sink.mu.Lock()
+	attempts := rejected - sink.fail
+	sink.mu.Unlock()
+	if attempts != 2 {
+		t.Errorf("%d intentos en %s, se esperaban 2", attempts, 2*emailRetryMinDelay)
+	}
+
+	if err := email.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+)
+
+// Tipos de evento
+const (
+	EventChangeDetected    = "change_detected"
+	EventDownloadCompleted = "download_completed"
+	EventValidationFailed  = "validation_failed"
+	EventLoadCompleted     = "load_completed"
+	EventPublishCompleted  = "publish_completed"
+	EventRunFailed         = "run_failed"
+)
+
+type Event struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	Timestamp   time.Time `json:"timestamp"`
+	RunID       string    `json:"run_id,omitempty"`
+	Category    string    `json:"category,omitempty"`
+	URL         string    `json:"url,omitempty"`
+	Size        int64     `json:"size,omitempty"`
+	Checksum    string    `json:"checksum,omitempty"`
+	RecordCount int       `json:"record_count,omitempty"`
+	ParseErrors int       `json:"parse_errors,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Failures    []Failure `json:"failures,omitempty"` // solo en run_failed
+}
+
+// Failure es una categoría fallida dentro de una ejecución
+type Failure struct {
+	Category    string    `json:"category"`
+	Error       string    `json:"error"`
+	LastSuccess time.Time `json:"last_success"` // cero si nunca se ha procesado con éxito
+}
+
+// Notifier recibe eventos; las implementaciones no deben bloquear al emisor
+type Notifier interface {
+	Notify(event Event)
+	Close() error
+}
+
+// Dispatcher reparte cada evento entre varios notificadores
+type Dispatcher struct {
+	notifiers []Notifier
+}
+
+func NewEvent(eventType string) Event {
+	now := time.Now()
+	return Event{
+		ID:        fmt.Sprintf("%s-%d", eventType, now.UnixNano()),
+		Type:      eventType,
+		Timestamp: now,
+	}
+}
+
+func NewDispatcher(notifiers ...Notifier) *Dispatcher {
+	return &Dispatcher{notifiers: notifiers}
+}
+
+func (d *Dispatcher) Notify(event Event) {
+	for _, notifier := range d.notifiers {
+		notifier.Notify(event)
+	}
+}
+
+// Close espera a que cada notificador entregue los eventos pendientes
+func (d *Dispatcher) Close() error {
+	var firstErr error
+	for _, notifier := range d.notifiers {
+		if err := notifier.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,216 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsoria-ttec/bne-converter/internal/config"
+	"github.com/sirupsen/logrus" // logging
+)
+
+// Tamaño de la cola de eventos pendientes de entrega
+const webhookQueueSize = 256
+
+var knownEvents = map[string]bool{
+	EventChangeDetected:    true,
+	EventDownloadCompleted: true,
+	EventValidationFailed:  true,
+	EventLoadCompleted:     true,
+	EventPublishCompleted:  true,
+	EventRunFailed:         true,
+}
+
+// Webhook entrega los eventos por POST con firma HMAC-SHA256 en la cabecera
+// X-BNE-Signature. Los eventos que agotan los reintentos se guardan en el
+// fichero de dead-letter, uno por línea
+type Webhook struct {
+	config  *config.WebhookConfig
+	client  *http.Client
+	events  map[string]bool
+	logger  *logrus.Logger
+	queue   chan Event
+	done    chan struct{}
+	mu      sync.RWMutex
+	closed  bool
+	letters sync.Mutex
+}
+
+type deadLetter struct {
+	URL      string    `json:"url"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+	Event    Event     `json:"event"`
+}
+
+func NewWebhook(cfg *config.Config, logger *logrus.Logger) (*Webhook, error) {
+	events := make(map[string]bool)
+	for _, event := range cfg.Notifications.Webhook.Events {
+		if !knownEvents[event] {
+			return nil, fmt.Errorf("evento de webhook desconocido (%s)", event)
+		}
+		events[event] = true
+	}
+
+	webhook := &Webhook{
+		config: &cfg.Notifications.Webhook,
+		client: &http.Client{
+			Timeout: cfg.Notifications.Webhook.Timeout,
+		},
+		events: events,
+		logger: logger,
+		queue:  make(chan Event, webhookQueueSize),
+		done:   make(chan struct{}),
+	}
+
+	go webhook.run()
+	return webhook, nil
+}
+
+func (w *Webhook) Notify(event Event) {
+	// Sin lista de eventos se envían todos
+	if len(w.events) > 0 && !w.events[event.Type] {
+		return
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return
+	}
+
+	select {
+	case w.queue <- event:
+	default:
+		for _, url := range w.config.URLs {
+			w.writeDeadLetter(url, event, fmt.Errorf("cola de webhooks llena"))
+		}
+	}
+}
+
+// Close deja de aceptar eventos y espera a que se entreguen los pendientes
+func (w *Webhook) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	<-w.done
+	return nil
+}
+
+func (w *Webhook) run() {
+	defer close(w.done)
+
+	for event := range w.queue {
+		body, err := json.Marshal(event)
+		if err != nil {
+			w.logger.WithError(err).Error("Error serializando evento de webhook")
+			continue
+		}
+
+		for _, url := range w.config.URLs {
+			if err := w.deliver(url, event, body); err != nil {
+				w.logger.WithFields(logrus.Fields{
+					"url":      url,
+					"event":    event.Type,
+					"category": event.Category,
+				}).WithError(err).Error("Webhook no entregado, guardado en dead-letter")
+				w.writeDeadLetter(url, event, err)
+			}
+		}
+	}
+}
+
+func (w *Webhook) deliver(url string, event Event, body []byte) error {
+	var err error
+	delay := w.config.RetryDelay
+
+	for attempt := 1; attempt <= w.config.RetryAttempts; attempt++ {
+		if err = w.post(url, event, body); err == nil {
+			return nil
+		}
+
+		if attempt < w.config.RetryAttempts {
+			w.logger.WithFields(logrus.Fields{
+				"url":     url,
+				"event":   event.Type,
+				"attempt": attempt,
+			}).WithError(err).Warn("Intento de entrega de webhook fallido, reintentando...")
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+
+	return fmt.Errorf("todos los intentos de entrega han fallado: %w", err)
+}
+
+func (w *Webhook) post(url string, event Event, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error al crear petición (%w)", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-BNE-Event", event.Type)
+	req.Header.Set("X-BNE-Delivery", event.ID)
+	req.Header.Set("X-BNE-Signature", "sha256="+Sign(w.config.Secret, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error al realizar petición (%w)", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("código de respuesta inesperado (%d)", resp.StatusCode)
+	}
+	return nil
+}
+
+func (w *Webhook) writeDeadLetter(url string, event Event, cause error) {
+	w.letters.Lock()
+	defer w.letters.Unlock()
+
+	line, err := json.Marshal(deadLetter{
+		URL:      url,
+		Error:    cause.Error(),
+		FailedAt: time.Now(),
+		Event:    event,
+	})
+	if err != nil {
+		w.logger.WithError(err).Error("Error serializando dead-letter")
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(w.config.DeadLetterPath), 0755); err != nil {
+		w.logger.WithError(err).Error("Error creando directorio de dead-letter")
+		return
+	}
+
+	file, err := os.OpenFile(w.config.DeadLetterPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		w.logger.WithError(err).Error("Error abriendo fichero de dead-letter")
+		return
+	}
+	defer file.Close()
+
+	file.Write(append(line, '\n'))
+}
+
+// Sign calcula la firma HMAC-SHA256 en hexadecimal del cuerpo de la petición
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
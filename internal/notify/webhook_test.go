@@ -0,0 +1,275 @@
+package notify
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsoria-ttec/bne-converter/internal/config"
+	"github.com/sirupsen/logrus" // logging
+)
+
+// receiver es un destino de webhooks local que guarda las peticiones recibidas
+type receiver struct {
+	mu       sync.Mutex
+	requests []receivedRequest
+}
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+	at     time.Time
+}
+
+func (r *receiver) record(req *http.Request) int {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, receivedRequest{header: req.Header.Clone(), body: body, at: time.Now()})
+	return len(r.requests)
+}
+
+func (r *receiver) received() []receivedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedRequest(nil), r.requests...)
+}
+
+func newTestWebhook(t *testing.T, url string, attempts int, events ...string) (*Webhook, string) {
+	t.Helper()
+
+	deadLetters := filepath.Join(t.TempDir(), "webhooks", "dead-letter.jsonl")
+	cfg := &config.Config{}
+	cfg.Notifications.Webhook = config.WebhookConfig{
+		Enabled:        true,
+		URLs:           []string{url},
+		Secret:         "s3cret",
+		Events:         events,
+		Timeout:        5 * time.Second,
+		RetryAttempts:  attempts,
+		RetryDelay:     20 * time.Millisecond,
+		DeadLetterPath: deadLetters,
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	webhook, err := NewWebhook(cfg, logger)
+	if err != nil {
+		t.Fatalf("NewWebhook: %v", err)
+	}
+	return webhook, deadLetters
+}
+
+func readDeadLetters(t *testing.T, path string) []deadLetter {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("abriendo dead-letter: %v", err)
+	}
+	defer file.Close()
+
+	var letters []deadLetter
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var letter deadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &letter); err != nil {
+			t.Fatalf("línea de dead-letter no válida %q: %v", scanner.Text(), err)
+		}
+		letters = append(letters, letter)
+	}
+	return letters
+}
+
+func TestWebhookSignature(t *testing.T) {
+	recv := &receiver{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recv.record(r)
+	}))
+	defer server.Close()
+
+	webhook, _ := newTestWebhook(t, server.URL, 1)
+	event := NewEvent(EventLoadCompleted)
+	event.Category = "monomodernas"
+	webhook.Notify(event)
+	webhook.Close()
+
+	requests := recv.received()
+	if len(requests) != 1 {
+		t.Fatalf("recibidas %d peticiones, se esperaba 1", len(requests))
+	}
+	request := requests[0]
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(request.body)
+	if want, got := "sha256="+hex.EncodeToString(mac.Sum(nil)), request.header.Get("X-BNE-Signature"); got != want {
+		t.Errorf("X-BNE-Signature = %q, se esperaba %q", got, want)
+	}
+	if got := request.header.Get("X-BNE-Event"); got != EventLoadCompleted {
+		t.Errorf("X-BNE-Event = %q", got)
+	}
+	if got := request.header.Get("X-BNE-Delivery"); got != event.ID {
+		t.Errorf("X-BNE-Delivery = %q, se esperaba %q", got, event.ID)
+	}
+
+	var delivered Event
+	if err := json.Unmarshal(request.body, &delivered); err != nil {
+		t.Fatalf("cuerpo no válido: %v", err)
+	}
+	if delivered.ID != event.ID || delivered.Category != "monomodernas" {
+		t.Errorf("evento entregado %+v", delivered)
+	}
+}
+
+func TestWebhookEventFilter(t *testing.T) {
+	recv := &receiver{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recv.record(r)
+	}))
+	defer server.Close()
+
+	// Solo se entregan los eventos de la lista
+	webhook, _ := newTestWebhook(t, server.URL, 1, EventPublishCompleted)
+	webhook.Notify(NewEvent(EventLoadCompleted))
+	published := NewEvent(EventPublishCompleted)
+	published.Category = "MONOMODERN"
+	published.RecordCount = 42
+	webhook.Notify(published)
+	webhook.Close()
+
+	requests := recv.received()
+	if len(requests) != 1 {
+		t.Fatalf("recibidas %d peticiones, se esperaba 1", len(requests))
+	}
+	if got := requests[0].header.Get("X-BNE-Event"); got != EventPublishCompleted {
+		t.Errorf("X-BNE-Event = %q, se esperaba %q", got, EventPublishCompleted)
+	}
+	var delivered Event
+	if err := json.Unmarshal(requests[0].body, &delivered); err != nil {
+		t.Fatalf("cuerpo no válido: %v", err)
+	}
+	if delivered.ID != published.ID || delivered.RecordCount != 42 {
+		t.Errorf("evento entregado %+v", delivered)
+	}
+}
+
+func TestWebhookUnknownEvent(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Notifications.Webhook.Events = []string{EventPublishCompleted, "published"}
+	if _, err := NewWebhook(cfg, logrus.New()); err == nil || !strings.Contains(err.Error(), "published") {
+		t.Errorf("error = %v, se esperaba el evento desconocido", err)
+	}
+}
+
+func TestWebhookRetriesWithBackoff(t *testing.T) {
+	recv := &receiver{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if recv.record(r) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	webhook, deadLetters := newTestWebhook(t, server.URL, 3)
+	webhook.Notify(NewEvent(EventRunFailed))
+	webhook.Close()
+
+	requests := recv.received()
+	if len(requests) != 3 {
+		t.Fatalf("recibidos %d intentos, se esperaban 3", len(requests))
+	}
+	// Espera de 20ms tras el primer intento y del doble tras el segundo
+	if gap := requests[1].at.Sub(requests[0].at); gap < 20*time.Millisecond {
+		t.Errorf("primer reintento a los %s", gap)
+	}
+	if gap := requests[2].at.Sub(requests[1].at); gap < 40*time.Millisecond {
+		t.Errorf("segundo reintento a los %s, sin espera exponencial", gap)
+	}
+	if letters := readDeadLetters(t, deadLetters); len(letters) != 0 {
+		t.Errorf("evento entregado guardado en dead-letter: %+v", letters)
+	}
+}
+
+func TestWebhookDeadLetter(t *testing.T) {
+	recv := &receiver{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recv.record(r)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	webhook, deadLetters := newTestWebhook(t, server.URL, 2)
+	event := NewEvent(EventValidationFailed)
+	webhook.Notify(event)
+	webhook.Close()
+
+	if attempts := len(recv.received()); attempts != 2 {
+		t.Errorf("recibidos %d intentos, se esperaban 2", attempts)
+	}
+
+	letters := readDeadLetters(t, deadLetters)
+	if len(letters) != 1 {
+		t.Fatalf("%d eventos en dead-letter, se esperaba 1", len(letters))
+	}
+	letter := letters[0]
+	if letter.URL != server.URL || letter.Event.ID != event.ID {
+		t.Errorf("dead-letter %+v", letter)
+	}
+	if !strings.Contains(letter.Error, "503") {
+		t.Errorf("error de dead-letter sin el código de respuesta: %q", letter.Error)
+	}
+}
+
+func TestWebhookQueueFull(t *testing.T) {
+	recv := &receiver{}
+	busy := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recv.record(r)
+		once.Do(func() { close(busy) })
+		<-release
+	}))
+	defer server.Close()
+
+	webhook, deadLetters := newTestWebhook(t, server.URL, 1)
+
+	// El primer evento ocupa al repartidor; los siguientes llenan la cola
+	webhook.Notify(NewEvent(EventChangeDetected))
+	<-busy
+	for i := 0; i < webhookQueueSize; i++ {
+		webhook.Notify(NewEvent(EventChangeDetected))
+	}
+	overflow := NewEvent(EventRunFailed)
+	webhook.Notify(overflow)
+
+	letters := readDeadLetters(t, deadLetters)
+	if len(letters) != 1 || letters[0].Event.ID != overflow.ID {
+		t.Fatalf("dead-letter con la cola llena: %+v", letters)
+	}
+	if !strings.Contains(letters[0].Error, "cola") {
+		t.Errorf("error de dead-letter %q", letters[0].Error)
+	}
+
+	close(release)
+	webhook.Close()
+	if delivered := len(recv.received()); delivered != webhookQueueSize+1 {
+		t.Errorf("entregados %d eventos, se esperaban %d", delivered, webhookQueueSize+1)
+	}
+}
@@ -0,0 +1,198 @@
+package oai
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/fsoria-ttec/bne-converter/internal/config"
+	"github.com/fsoria-ttec/bne-converter/internal/storage"
+	"github.com/sirupsen/logrus" // logging
+)
+
+// Repository consulta los registros almacenados que se recolectan
+type Repository interface {
+	EarliestDatestamp(ctx context.Context) (time.Time, bool, error)
+	Record(ctx context.Context, controlNumber string) (*storage.RecordVersion, error)
+	Harvest(ctx context.Context, query storage.HarvestQuery) ([]*storage.RecordVersion, error)
+}
+
+// Server es un proveedor de datos OAI-PMH 2.0 sobre los registros almacenados
+type Server struct {
+	config *config.OAIConfig
+	store  Repository
+	logger *logrus.Logger
+}
+
+// verb describe los argumentos de un verbo. Con exclusive, resumptionToken
+// es válido y debe ser el único argumento
+type verb struct {
+	handler   func(*Server, *http.Request, *response) error
+	required  []string
+	optional  []string
+	exclusive bool
+}
+
+var verbs = map[string]verb{
+	"Identify":            {handler: (*Server).identify},
+	"ListMetadataFormats": {handler: (*Server).listMetadataFormats, optional: []string{"identifier"}},
+	"ListSets":            {handler: (*Server).listSets, exclusive: true},
+	"GetRecord":           {handler: (*Server).getRecord, required: []string{"identifier", "metadataPrefix"}},
+	"ListIdentifiers": {handler: (*Server).listIdentifiers, required: []string{"metadataPrefix"},
+		optional: []string{"from", "until", "set"}, exclusive: true},
+	"ListRecords": {handler: (*Server).listRecords, required: []string{"metadataPrefix"},
+		optional: []string{"from", "until", "set"}, exclusive: true},
+}
+
+// protocolError es un error de OAI-PMH que se devuelve en el documento de respuesta
+type protocolError struct {
+	code    string
+	message string
+}
+
+func (e *protocolError) Error() string {
+	return e.code + ": " + e.message
+}
+
+func New(cfg *config.Config, store Repository, logger *logrus.Logger) *Server {
+	return &Server{
+		config: &cfg.OAI,
+		store:  store,
+		logger: logger,
+	}
+}
+
+// Serve atiende peticiones en la dirección configurada hasta que se cancele el contexto
+func (s *Server) Serve(ctx context.Context) {
+	server := &http.Server{
+		Addr:              s.config.Address,
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	go func() {
+		s.logger.WithField("address", s.config.Address).Info("Proveedor OAI-PMH disponible")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.WithError(err).Error("Error en el proveedor OAI-PMH")
+		}
+	}()
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /oai", s.handle)
+	mux.HandleFunc("POST /oai", s.handle)
+
+	return mux
+}
+
+// handle atiende un verbo. Los errores del protocolo se devuelven con código
+// 200 en el propio documento OAI-PMH
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	resp := &response{
+		XSI:            xsiNamespace,
+		SchemaLocation: oaiSchemaLocation,
+		ResponseDate:   formatDatestamp(time.Now()),
+		Request:        request{BaseURL: s.config.BaseURL},
+	}
+
+	if err := r.ParseForm(); err != nil {
+		resp.Errors = append(resp.Errors, oaiError{Code: errBadArgument, Message: err.Error()})
+		writeXML(w, http.StatusOK, resp)
+		return
+	}
+
+	name := r.Form.Get("verb")
+	spec, exists := verbs[name]
+	switch {
+	case len(r.Form["verb"]) > 1:
+		resp.Errors = append(resp.Errors, oaiError{Code: errBadVerb, Message: "verbo repetido"})
+	case !exists:
+		resp.Errors = append(resp.Errors, oaiError{Code: errBadVerb, Message: "verbo no válido o ausente"})
+	default:
+		if err := checkArguments(r, spec); err != nil {
+			resp.Errors = append(resp.Errors, oaiError{Code: errBadArgument, Message: err.Error()})
+			break
+		}
+
+		resp.Request = request{
+			Verb:            name,
+			Identifier:      r.Form.Get("identifier"),
+			MetadataPrefix:  r.Form.Get("metadataPrefix"),
+			From:            r.Form.Get("from"),
+			Until:           r.Form.Get("until"),
+			Set:             r.Form.Get("set"),
+			ResumptionToken: r.Form.Get("resumptionToken"),
+			BaseURL:         s.config.BaseURL,
+		}
+
+		if err := spec.handler(s, r, resp); err != nil {
+			var protocolErr *protocolError
+			if !errors.As(err, &protocolErr) {
+				s.logger.WithError(err).WithField("verb", name).Error("Error en el proveedor OAI-PMH")
+				http.Error(w, "error interno", http.StatusInternalServerError)
+				return
+			}
+			resp.Errors = append(resp.Errors, oaiError{Code: protocolErr.code, Message: protocolErr.message})
+			if protocolErr.code == errBadArgument {
+				resp.Request = request{BaseURL: s.config.BaseURL}
+			}
+		}
+	}
+
+	writeXML(w, http.StatusOK, resp)
+}
+
+// checkArguments comprueba que la petición trae los argumentos del verbo,
+// sin repetir ninguno ni añadir otros
+func checkArguments(r *http.Request, spec verb) error {
+	allowed := map[string]bool{"verb": true}
+	for _, name := range append(spec.required, spec.optional...) {
+		allowed[name] = true
+	}
+	if spec.exclusive {
+		allowed["resumptionToken"] = true
+	}
+
+	for name, values := range r.Form {
+		if !allowed[name] {
+			return errors.New("argumento no válido para el verbo: " + name)
+		}
+		if len(values) > 1 {
+			return errors.New("argumento repetido: " + name)
+		}
+	}
+
+	if r.Form.Has("resumptionToken") {
+		if len(r.Form) > 2 {
+			return errors.New("resumptionToken debe ser el único argumento")
+		}
+		return nil
+	}
+	for _, name := range spec.required {
+		if r.Form.Get(name) == "" {
+			return errors.New("falta el argumento obligatorio: " + name)
+		}
+	}
+	return nil
+}
+
+func writeXML(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.WriteHeader(code)
+	w.Write([]byte(xml.Header))
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	encoder.Encode(body)
+}
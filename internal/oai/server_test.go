@@ -0,0 +1,314 @@
+package oai
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fsoria-ttec/bne-converter/internal/config"
+	"github.com/fsoria-ttec/bne-converter/internal/constants"
+	"github.com/fsoria-ttec/bne-converter/internal/storage"
+	"github.com/fsoria-ttec/bne-converter/pkg/models"
+	"github.com/sirupsen/logrus" // logging
+)
+
+// repository es un repositorio en memoria con las versiones ordenadas por
+// datestamp y número de control, como las devuelve la base de datos
+type repository struct {
+	versions []*storage.RecordVersion
+}
+
+func (r *repository) EarliestDatestamp(ctx context.Context) (time.Time, bool, error) {
+	if len(r.versions) == 0 {
+		return time.Time{}, false, nil
+	}
+	return r.versions[0].Datestamp, true, nil
+}
+
+func (r *repository) Record(ctx context.Context, controlNumber string) (*storage.RecordVersion, error) {
+	for _, version := range r.versions {
+		if version.ControlNumber == controlNumber {
+			return version, nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (r *repository) Harvest(ctx context.Context, query storage.HarvestQuery) ([]*storage.RecordVersion, error) {
+	var versions []*storage.RecordVersion
+	for _, version := range r.versions {
+		switch {
+		case query.Category != "" && version.Category != query.Category,
+			!query.From.IsZero() && version.Datestamp.Before(query.From),
+			!query.Until.IsZero() && !version.Datestamp.Before(query.Until):
+			continue
+		case !query.AfterDatestamp.IsZero() && (version.Datestamp.Before(query.AfterDatestamp) ||
+			version.Datestamp.Equal(query.AfterDatestamp) && version.ControlNumber <= query.AfterControlNumber):
+			continue
+		}
+		if len(versions) == query.Limit {
+			break
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+func version(controlNumber, category string, datestamp time.Time, deleted bool) *storage.RecordVersion {
+	return &storage.RecordVersion{
+		Family:        constants.FamilyBibliographic,
+		ControlNumber: controlNumber,
+		Category:      category,
+		Deleted:       deleted,
+		Datestamp:     datestamp,
+		Record: &models.Record{
+			Leader:        "00000nam a2200000 i 4500",
+			ControlFields: []models.ControlField{{Tag: "001", Value: controlNumber}},
+			DataFields: []models.DataField{{Tag: "245", Ind1: "1", Ind2: "0", Subfields: []models.Subfield{
+				{Code: "a", Value: "Poesías completas"},
+			}}},
+		},
+	}
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	store := &repository{versions: []*storage.RecordVersion{
+		version("bimo0000000001", "MONOMODERN", day.Add(9*time.Hour), false),
+		version("bimo0000000002", "MONOMODERN", day.Add(9*time.Hour), true),
+		version("bimo0000000003", "MONOMODERN", day.Add(33*time.Hour), false),
+		version("bise0000000001", "SERIADA", day.Add(57*time.Hour), false),
+	}}
+
+	cfg := &config.Config{OAI: config.OAIConfig{
+		BaseURL:        "https://datos.example.es/oai",
+		RepositoryName: "Catálogo de la BNE",
+		AdminEmail:     "datos@example.es",
+		Namespace:      "datos.example.es",
+		PageSize:       2,
+	}}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	server := httptest.NewServer(New(cfg, store, logger).routes())
+	t.Cleanup(server.Close)
+	return server
+}
+
+// get envía la consulta tal cual, para poder repetir argumentos
+func get(t *testing.T, server *httptest.Server, query string) *response {
+	t.Helper()
+
+	resp, err := http.Get(server.URL + "/oai?" + query)
+	if err != nil {
+		t.Fatalf("GET %s: %v", query, err)
+	}
+	defer resp.Body.Close()
+	return decode(t, query, resp)
+}
+
+func decode(t *testing.T, query string, resp *http.Response) *response {
+	t.Helper()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("%s: código %d", query, resp.StatusCode)
+	}
+	var doc response
+	if err := xml.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatalf("%s: respuesta no válida (%v)", query, err)
+	}
+	return &doc
+}
+
+func errorCode(doc *response) string {
+	if len(doc.Errors) == 0 {
+		return ""
+	}
+	return doc.Errors[0].Code
+}
+
+func TestProtocolErrors(t *testing.T) {
+	server := newTestServer(t)
+
+	tests := []struct {
+		name  string
+		query string
+		code  string
+	}{
+		{name: "sin verbo", query: "", code: errBadVerb},
+		{name: "verbo desconocido", query: "verb=ListEverything", code: errBadVerb},
+		{name: "verbo repetido", query: "verb=Identify&verb=Identify", code: errBadVerb},
+		{name: "argumento no admitido", query: "verb=Identify&set=MONOMODERN", code: errBadArgument},
+		{name: "argumento repetido", query: "verb=ListRecords&metadataPrefix=oai_dc&metadataPrefix=marc21", code: errBadArgument},
+		{name: "falta un obligatorio", query: "verb=GetRecord&metadataPrefix=oai_dc", code: errBadArgument},
+		{name: "resumptionToken con otros argumentos", query: "verb=ListRecords&metadataPrefix=oai_dc&resumptionToken=x", code: errBadArgument},
+		{name: "resumptionToken en un verbo sin paginar", query: "verb=GetRecord&resumptionToken=x", code: errBadArgument},
+		{name: "fecha no válida", query: "verb=ListIdentifiers&metadataPrefix=oai_dc&from=2026-3-1", code: errBadArgument},
+		{name: "granularidades distintas", query: "verb=ListIdentifiers&metadataPrefix=oai_dc&from=2026-03-01&until=2026-03-02T00:00:00Z", code: errBadArgument},
+		{name: "from posterior a until", query: "verb=ListIdentifiers&metadataPrefix=oai_dc&from=2026-03-02&until=2026-03-01", code: errBadArgument},
+		{name: "formato no disponible en GetRecord", query: "verb=GetRecord&metadataPrefix=mods&identifier=oai:datos.example.es:bimo0000000001", code: errCannotDisseminateFormat},
+		{name: "formato no disponible en ListRecords", query: "verb=ListRecords&metadataPrefix=mods", code: errCannotDisseminateFormat},
+		{name: "identificador de otro repositorio", query: "verb=GetRecord&metadataPrefix=oai_dc&identifier=oai:otro:bimo0000000001", code: errIDDoesNotExist},
+		{name: "identificador desconocido", query: "verb=ListMetadataFormats&identifier=oai:datos.example.es:bimo9999999999", code: errIDDoesNotExist},
+		{name: "resumptionToken vacío", query: "verb=ListRecords&resumptionToken=", code: errBadResumptionToken},
+		{name: "resumptionToken no válido", query: "verb=ListIdentifiers&resumptionToken=e30", code: errBadResumptionToken},
+		{name: "resumptionToken en ListSets", query: "verb=ListSets&resumptionToken=x", code: errBadResumptionToken},
+		{name: "conjunto desconocido", query: "verb=ListRecords&metadataPrefix=oai_dc&set=DESCONOCIDO", code: errNoRecordsMatch},
+		{name: "intervalo sin registros", query: "verb=ListRecords&metadataPrefix=oai_dc&from=2025-01-01&until=2025-12-31", code: errNoRecordsMatch},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc := get(t, server, test.query)
+			if code := errorCode(doc); code != test.code {
+				t.Errorf("error %q, se esperaba %q", code, test.code)
+			}
+			// Con el verbo o los argumentos erróneos no se repiten en request
+			if (test.code == errBadVerb || test.code == errBadArgument) && doc.Request.Verb != "" {
+				t.Errorf("request con verbo %q", doc.Request.Verb)
+			}
+			if doc.Request.BaseURL != "https://datos.example.es/oai" {
+				t.Errorf("baseURL %q", doc.Request.BaseURL)
+			}
+		})
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	server := newTestServer(t)
+
+	// Los argumentos también se admiten en el cuerpo de un POST
+	resp, err := http.Post(server.URL+"/oai", "application/x-www-form-urlencoded", strings.NewReader("verb=Identify"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	doc := decode(t, "POST Identify", resp)
+
+	if doc.Identify == nil {
+		t.Fatalf("sin Identify: %+v", doc.Errors)
+	}
+	if doc.Identify.EarliestDatestamp != "2026-03-01T09:00:00Z" || doc.Identify.DeletedRecord != "persistent" ||
+		doc.Identify.Granularity != "YYYY-MM-DDThh:mm:ssZ" {
+		t.Errorf("Identify %+v", *doc.Identify)
+	}
+}
+
+func TestListMetadataFormatsAndSets(t *testing.T) {
+	server := newTestServer(t)
+
+	doc := get(t, server, "verb=ListMetadataFormats&identifier=oai:datos.example.es:bimo0000000001")
+	if doc.ListMetadataFormats == nil || len(doc.ListMetadataFormats.Formats) != len(metadataFormats) {
+		t.Errorf("ListMetadataFormats %+v, errores %+v", doc.ListMetadataFormats, doc.Errors)
+	}
+
+	doc = get(t, server, "verb=ListSets")
+	if doc.ListSets == nil || len(doc.ListSets.Sets) != len(constants.BNECategories) {
+		t.Errorf("ListSets %+v, errores %+v", doc.ListSets, doc.Errors)
+	}
+}
+
+func TestGetRecord(t *testing.T) {
+	server := newTestServer(t)
+
+	doc := get(t, server, "verb=GetRecord&metadataPrefix=marc21&identifier=oai:datos.example.es:bimo0000000001")
+	if doc.GetRecord == nil {
+		t.Fatalf("sin GetRecord: %+v", doc.Errors)
+	}
+	if header := doc.GetRecord.Record.Header; header.Datestamp != "2026-03-01T09:00:00Z" || header.Status != "" ||
+		len(header.SetSpec) != 1 || header.SetSpec[0] != "MONOMODERN" {
+		t.Errorf("cabecera %+v", header)
+	}
+	if doc.Request.Verb != "GetRecord" || doc.Request.MetadataPrefix != "marc21" {
+		t.Errorf("request %+v", doc.Request)
+	}
+
+	// Las marcas de eliminación se sirven sin metadatos
+	doc = get(t, server, "verb=GetRecord&metadataPrefix=oai_dc&identifier=oai:datos.example.es:bimo0000000002")
+	if doc.GetRecord == nil || doc.GetRecord.Record.Header.Status != "deleted" {
+		t.Errorf("registro eliminado %+v, errores %+v", doc.GetRecord, doc.Errors)
+	}
+}
+
+func TestListIdentifiersPaging(t *testing.T) {
+	server := newTestServer(t)
+
+	var identifiers []string
+	var cursors []int
+	query := "verb=ListIdentifiers&metadataPrefix=oai_dc"
+	for page := 0; page < 5; page++ {
+		doc := get(t, server, query)
+		if doc.ListIdentifiers == nil {
+			t.Fatalf("página %d: %+v", page, doc.Errors)
+		}
+		for _, header := range doc.ListIdentifiers.Headers {
+			identifiers = append(identifiers, header.Identifier)
+		}
+
+		token := doc.ListIdentifiers.ResumptionToken
+		if token == nil {
+			break
+		}
+		cursors = append(cursors, token.Cursor)
+		if token.Value == "" {
+			break
+		}
+		query = "verb=ListIdentifiers&resumptionToken=" + url.QueryEscape(token.Value)
+	}
+
+	want := []string{
+		"oai:datos.example.es:bimo0000000001",
+		"oai:datos.example.es:bimo0000000002",
+		"oai:datos.example.es:bimo0000000003",
+		"oai:datos.example.es:bise0000000001",
+	}
+	if strings.Join(identifiers, " ") != strings.Join(want, " ") {
+		t.Errorf("identificadores %v, se esperaba %v", identifiers, want)
+	}
+	// La última página de una lista paginada lleva un token vacío
+	if len(cursors) != 2 || cursors[0] != 0 || cursors[1] != 2 {
+		t.Errorf("cursores %v, se esperaba [0 2]", cursors)
+	}
+}
+
+func TestListRecordsSelective(t *testing.T) {
+	server := newTestServer(t)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "set=SERIADA", want: []string{"bise0000000001"}},
+		// until con granularidad de día incluye todo el día
+		{query: "from=2026-03-02&until=2026-03-02", want: []string{"bimo0000000003"}},
+		{query: "from=2026-03-01T09:00:01Z&until=2026-03-02T09:00:00Z", want: []string{"bimo0000000003"}},
+		{query: "set=MONOMODERN&until=2026-03-01", want: []string{"bimo0000000001", "bimo0000000002"}},
+	}
+
+	for _, test := range tests {
+		doc := get(t, server, "verb=ListRecords&metadataPrefix=oai_dc&"+test.query)
+		if doc.ListRecords == nil {
+			t.Errorf("%s: %+v", test.query, doc.Errors)
+			continue
+		}
+		var got []string
+		for _, rec := range doc.ListRecords.Records {
+			got = append(got, strings.TrimPrefix(rec.Header.Identifier, "oai:datos.example.es:"))
+		}
+		if strings.Join(got, " ") != strings.Join(test.want, " ") {
+			t.Errorf("%s: %v, se esperaba %v", test.query, got, test.want)
+		}
+		// Una lista completa en una página no lleva token
+		if doc.ListRecords.ResumptionToken != nil {
+			t.Errorf("%s: token %+v", test.query, *doc.ListRecords.ResumptionToken)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package oai
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Granularidades de fecha admitidas en from y until
+const (
+	dayLayout     = "2006-01-02"
+	secondsLayout = "2006-01-02T15:04:05Z"
+)
+
+var errInvalidToken = errors.New("resumption token no válido")
+
+// harvestState es el estado de una recolección por páginas. Se entrega al
+// cliente como resumption token, así que no hay que guardarlo en el servidor
+type harvestState struct {
+	MetadataPrefix string    `json:"m"`
+	Set            string    `json:"s,omitempty"`
+	From           time.Time `json:"f,omitempty"`
+	Until          time.Time `json:"u,omitempty"` // exclusiva
+	// Último registro entregado y número de registros entregados hasta él
+	AfterDatestamp     time.Time `json:"d"`
+	AfterControlNumber string    `json:"c"`
+	Cursor             int       `json:"n"`
+}
+
+func (s harvestState) encode() string {
+	data, _ := json.Marshal(s)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeState(token string) (harvestState, error) {
+	var state harvestState
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || json.Unmarshal(data, &state) != nil || !supportedFormat(state.MetadataPrefix) ||
+		state.AfterControlNumber == "" || state.Cursor <= 0 {
+		return harvestState{}, errInvalidToken
+	}
+	return state, nil
+}
+
+// parseDate interpreta una fecha de from o until y devuelve también su
+// granularidad, que debe coincidir en ambos argumentos
+func parseDate(value string) (time.Time, string, error) {
+	for _, layout := range []string{dayLayout, secondsLayout} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, layout, nil
+		}
+	}
+	return time.Time{}, "", errors.New("fecha no válida")
+}
+
+// untilExclusive convierte until, que incluye todo su día o segundo, en un
+// límite exclusivo
+func untilExclusive(date time.Time, layout string) time.Time {
+	if layout == dayLayout {
+		return date.AddDate(0, 0, 1)
+	}
+	return date.Add(time.Second)
+}
+
+func formatDatestamp(date time.Time) string {
+	return date.UTC().Format(secondsLayout)
+}
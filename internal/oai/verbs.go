@@ -0,0 +1,255 @@
+package oai
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fsoria-ttec/bne-converter/internal/constants"
+	"github.com/fsoria-ttec/bne-converter/internal/storage"
+	"github.com/fsoria-ttec/bne-converter/pkg/models"
+)
+
+// Formatos de metadatos, por orden de presentación
+var metadataFormats = []metadataFormat{
+	{
+		MetadataPrefix:    "oai_dc",
+		Schema:            "http://www.openarchives.org/OAI/2.0/oai_dc.xsd",
+		MetadataNamespace: models.OAIDCNamespace,
+	},
+	{
+		MetadataPrefix:    "marc21",
+		Schema:            "http://www.loc.gov/standards/marcxml/schema/MARC21slim.xsd",
+		MetadataNamespace: models.MARCXMLNamespace,
+	},
+}
+
+func supportedFormat(prefix string) bool {
+	for _, format := range metadataFormats {
+		if format.MetadataPrefix == prefix {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) identify(r *http.Request, resp *response) error {
+	earliest, exists, err := s.store.EarliestDatestamp(r.Context())
+	if err != nil {
+		return err
+	}
+	if !exists {
+		earliest = time.Now()
+	}
+
+	resp.Identify = &identify{
+		RepositoryName:    s.config.RepositoryName,
+		BaseURL:           s.config.BaseURL,
+		ProtocolVersion:   "2.0",
+		AdminEmail:        s.config.AdminEmail,
+		EarliestDatestamp: formatDatestamp(earliest),
+		DeletedRecord:     "persistent", // las marcas de eliminación no se purgan
+		Granularity:       "YYYY-MM-DDThh:mm:ssZ",
+	}
+	return nil
+}
+
+func (s *Server) listMetadataFormats(r *http.Request, resp *response) error {
+	if identifier := r.Form.Get("identifier"); identifier != "" {
+		if _, err := s.lookup(r, identifier); err != nil {
+			return err
+		}
+	}
+	resp.ListMetadataFormats = &listMetadataFormats{Formats: metadataFormats}
+	return nil
+}
+
+// listSets devuelve un conjunto por categoría de la BNE. La lista es corta y
+// nunca se pagina, así que cualquier resumption token es erróneo
+func (s *Server) listSets(r *http.Request, resp *response) error {
+	if r.Form.Has("resumptionToken") {
+		return &protocolError{errBadResumptionToken, errInvalidToken.Error()}
+	}
+
+	sets := &listSets{}
+	for _, category := range constants.BNECategories {
+		sets.Sets = append(sets.Sets, set{SetSpec: category.Id, SetName: category.Description})
+	}
+	resp.ListSets = sets
+	return nil
+}
+
+func (s *Server) getRecord(r *http.Request, resp *response) error {
+	prefix := r.Form.Get("metadataPrefix")
+	if !supportedFormat(prefix) {
+		return &protocolError{errCannotDisseminateFormat, "formato no disponible: " + prefix}
+	}
+
+	version, err := s.lookup(r, r.Form.Get("identifier"))
+	if err != nil {
+		return err
+	}
+	resp.GetRecord = &getRecord{Record: s.record(version, prefix)}
+	return nil
+}
+
+func (s *Server) listIdentifiers(r *http.Request, resp *response) error {
+	versions, _, token, err := s.harvest(r)
+	if err != nil {
+		return err
+	}
+
+	list := &listIdentifiers{ResumptionToken: token}
+	for _, version := range versions {
+		list.Headers = append(list.Headers, s.header(version))
+	}
+	resp.ListIdentifiers = list
+	return nil
+}
+
+func (s *Server) listRecords(r *http.Request, resp *response) error {
+	versions, state, token, err := s.harvest(r)
+	if err != nil {
+		return err
+	}
+
+	list := &listRecords{ResumptionToken: token}
+	for _, version := range versions {
+		list.Records = append(list.Records, s.record(version, state.MetadataPrefix))
+	}
+	resp.ListRecords = list
+	return nil
+}
+
+// harvest devuelve una página de la recolección selectiva que describen los
+// argumentos o el resumption token, y el token de la página siguiente
+func (s *Server) harvest(r *http.Request) ([]*storage.RecordVersion, harvestState, *resumptionToken, error) {
+	var state harvestState
+	var err error
+	token := r.Form.Get("resumptionToken")
+	if r.Form.Has("resumptionToken") {
+		if state, err = decodeState(token); err != nil {
+			return nil, state, nil, &protocolError{errBadResumptionToken, err.Error()}
+		}
+	} else if state, err = newHarvestState(r); err != nil {
+		return nil, state, nil, err
+	}
+
+	if state.Set != "" && !constants.IsBNECategory(state.Set) {
+		return nil, state, nil, &protocolError{errNoRecordsMatch, "conjunto desconocido: " + state.Set}
+	}
+
+	pageSize := s.config.PageSize
+	versions, err := s.store.Harvest(r.Context(), storage.HarvestQuery{
+		Category:           state.Set,
+		From:               state.From,
+		Until:              state.Until,
+		AfterDatestamp:     state.AfterDatestamp,
+		AfterControlNumber: state.AfterControlNumber,
+		Limit:              pageSize + 1,
+	})
+	if err != nil {
+		return nil, state, nil, err
+	}
+	if len(versions) == 0 {
+		return nil, state, nil, &protocolError{errNoRecordsMatch, "ningún registro cumple los criterios"}
+	}
+
+	// Página intermedia: token con la posición del último registro. Última
+	// página de una lista paginada: token vacío
+	var next *resumptionToken
+	switch {
+	case len(versions) > pageSize:
+		versions = versions[:pageSize]
+		last := versions[pageSize-1]
+		following := state
+		following.AfterDatestamp = last.Datestamp
+		following.AfterControlNumber = last.ControlNumber
+		following.Cursor = state.Cursor + pageSize
+		next = &resumptionToken{Cursor: state.Cursor, Value: following.encode()}
+	case r.Form.Has("resumptionToken"):
+		next = &resumptionToken{Cursor: state.Cursor}
+	}
+	return versions, state, next, nil
+}
+
+// newHarvestState valida los argumentos de la primera página de una recolección
+func newHarvestState(r *http.Request) (harvestState, error) {
+	state := harvestState{
+		MetadataPrefix: r.Form.Get("metadataPrefix"),
+		Set:            r.Form.Get("set"),
+	}
+	if !supportedFormat(state.MetadataPrefix) {
+		return state, &protocolError{errCannotDisseminateFormat, "formato no disponible: " + state.MetadataPrefix}
+	}
+
+	var fromLayout, untilLayout string
+	var until time.Time
+	var err error
+	if from := r.Form.Get("from"); from != "" {
+		if state.From, fromLayout, err = parseDate(from); err != nil {
+			return state, &protocolError{errBadArgument, "from: " + err.Error()}
+		}
+	}
+	if value := r.Form.Get("until"); value != "" {
+		if until, untilLayout, err = parseDate(value); err != nil {
+			return state, &protocolError{errBadArgument, "until: " + err.Error()}
+		}
+		state.Until = untilExclusive(until, untilLayout)
+	}
+
+	if fromLayout != "" && untilLayout != "" {
+		if fromLayout != untilLayout {
+			return state, &protocolError{errBadArgument, "from y until con distinta granularidad"}
+		}
+		if state.From.After(until) {
+			return state, &protocolError{errBadArgument, "from posterior a until"}
+		}
+	}
+	return state, nil
+}
+
+// lookup devuelve la versión vigente del registro de un identificador OAI
+func (s *Server) lookup(r *http.Request, identifier string) (*storage.RecordVersion, error) {
+	controlNumber, found := strings.CutPrefix(identifier, "oai:"+s.config.Namespace+":")
+	if !found || controlNumber == "" {
+		return nil, &protocolError{errIDDoesNotExist, "identificador desconocido: " + identifier}
+	}
+
+	version, err := s.store.Record(r.Context(), controlNumber)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, &protocolError{errIDDoesNotExist, "identificador desconocido: " + identifier}
+	}
+	return version, err
+}
+
+// header describe una versión: su datestamp es la confirmación de la carga que
+// la escribió y los registros eliminados se marcan con status="deleted"
+func (s *Server) header(version *storage.RecordVersion) header {
+	h := header{
+		Identifier: "oai:" + s.config.Namespace + ":" + version.ControlNumber,
+		Datestamp:  formatDatestamp(version.Datestamp),
+		SetSpec:    []string{version.Category},
+	}
+	if version.Deleted {
+		h.Status = "deleted"
+	}
+	return h
+}
+
+// record compone el registro OAI; los eliminados no llevan metadatos
+func (s *Server) record(version *storage.RecordVersion, prefix string) record {
+	rec := record{Header: s.header(version)}
+	if version.Deleted {
+		return rec
+	}
+
+	switch prefix {
+	case "marc21":
+		rec.Metadata = &metadata{Content: version.Record.MARCXML()}
+	default:
+		rec.Metadata = &metadata{Content: version.Record.DublinCore()}
+	}
+	return rec
+}
@@ -0,0 +1,220 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/fsoria-ttec/bne-converter/pkg/models"
+)
+
+// Delimitadores ISO 2709
+const (
+	recordTerminator     = 0x1D
+	subfieldDelimiter    = 0x1F
+	leaderLength         = 24
+	directoryEntryLength = 12
+)
+
+type Reader struct {
+	reader *bufio.Reader
+	offset int64
+	last   int64 // posición del último registro devuelto
+}
+
+// RecordError indica un registro mal formado; la lectura puede continuar
+type RecordError struct {
+	Offset int64
+	Err    error
+}
+
+type Stats struct {
+	Records     int
+	Authorities int // registros de autoridad incluidos en Records
+	Errors      int
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("registro mal formado en byte %d (%v)", e.Offset, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		reader: bufio.NewReaderSize(r, 64*1024),
+	}
+}
+
+// Next devuelve el siguiente registro o io.EOF al final del fichero
+func (r *Reader) Next() (*models.Record, error) {
+	for {
+		data, err := r.reader.ReadBytes(recordTerminator)
+		offset := r.offset
+		r.offset += int64(len(data))
+
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("error leyendo registro (%w)", err)
+		}
+
+		// Algunos volcados separan registros con saltos de línea
+		trimmed := bytes.TrimLeft(data, "\r\n ")
+		offset += int64(len(data) - len(trimmed))
+
+		if err == io.EOF {
+			if len(bytes.TrimSpace(trimmed)) == 0 {
+				return nil, io.EOF
+			}
+			return nil, &RecordError{Offset: offset, Err: errors.New("registro truncado")}
+		}
+
+		if len(trimmed) <= 1 {
+			continue
+		}
+
+		record, err := parseRecord(trimmed)
+		if err != nil {
+			return nil, &RecordError{Offset: offset, Err: err}
+		}
+		r.last = offset
+		return record, nil
+	}
+}
+
+// Offset devuelve la posición en bytes del último registro devuelto por Next
+func (r *Reader) Offset() int64 {
+	return r.last
+}
+
+// ParseFile recorre los registros del fichero invocando fn para cada uno.
+// Los registros mal formados se contabilizan y se omiten
+func ParseFile(ctx context.Context, path string, fn func(*models.Record) error) (Stats, error) {
+	var stats Stats
+
+	file, err := os.Open(path)
+	if err != nil {
+		return stats, fmt.Errorf("error abriendo fichero MARC (%w)", err)
+	}
+	defer file.Close()
+
+	reader := NewReader(file)
+	for {
+		if stats.Records%1000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return stats, err
+			}
+		}
+
+		record, err := reader.Next()
+		if err == io.EOF {
+			return stats, nil
+		}
+
+		var recordErr *RecordError
+		if errors.As(err, &recordErr) {
+			stats.Errors++
+			continue
+		}
+		if err != nil {
+			return stats, err
+		}
+
+		stats.Records++
+		if record.IsAuthority() {
+			stats.Authorities++
+		}
+		if fn != nil {
+			if err := fn(record); err != nil {
+				return stats, err
+			}
+		}
+	}
+}
+
+func parseRecord(data []byte) (*models.Record, error) {
+	if len(data) < leaderLength+1 {
+		return nil, fmt.Errorf("longitud insuficiente (%d bytes)", len(data))
+	}
+
+	leader := data[:leaderLength]
+	baseAddress, err := strconv.Atoi(string(leader[12:17]))
+	if err != nil || baseAddress <= leaderLength || baseAddress > len(data) {
+		return nil, fmt.Errorf("dirección base inválida (%q)", leader[12:17])
+	}
+
+	directory := bytes.TrimRight(data[leaderLength:baseAddress], "\x1e")
+	if len(directory)%directoryEntryLength != 0 {
+		return nil, fmt.Errorf("directorio con longitud inválida (%d)", len(directory))
+	}
+
+	record := &models.Record{Leader: string(leader)}
+
+	for i := 0; i < len(directory); i += directoryEntryLength {
+		entry := directory[i : i+directoryEntryLength]
+		tag := string(entry[0:3])
+
+		length, err := strconv.Atoi(string(entry[3:7]))
+		if err != nil {
+			return nil, fmt.Errorf("longitud inválida en campo %s", tag)
+		}
+		start, err := strconv.Atoi(string(entry[7:12]))
+		if err != nil {
+			return nil, fmt.Errorf("posición inválida en campo %s", tag)
+		}
+
+		fieldStart := baseAddress + start
+		fieldEnd := fieldStart + length
+		if fieldStart > len(data) || fieldEnd > len(data) {
+			return nil, fmt.Errorf("campo %s fuera de los límites del registro", tag)
+		}
+
+		field := bytes.TrimRight(data[fieldStart:fieldEnd], "\x1e\x1d")
+
+		if isControlTag(tag) {
+			record.ControlFields = append(record.ControlFields, models.ControlField{
+				Tag:   tag,
+				Value: string(field),
+			})
+			continue
+		}
+
+		record.DataFields = append(record.DataFields, parseDataField(tag, field))
+	}
+
+	return record, nil
+}
+
+func parseDataField(tag string, field []byte) models.DataField {
+	dataField := models.DataField{Tag: tag, Ind1: " ", Ind2: " "}
+
+	if len(field) > 0 && field[0] != subfieldDelimiter {
+		dataField.Ind1 = string(field[0])
+	}
+	if len(field) > 1 && field[1] != subfieldDelimiter {
+		dataField.Ind2 = string(field[1])
+	}
+
+	parts := bytes.Split(field, []byte{subfieldDelimiter})
+	for _, part := range parts[1:] {
+		if len(part) == 0 {
+			continue
+		}
+		dataField.Subfields = append(dataField.Subfields, models.Subfield{
+			Code:  string(part[0]),
+			Value: string(part[1:]),
+		})
+	}
+
+	return dataField
+}
+
+func isControlTag(tag string) bool {
+	return len(tag) == 3 && tag[0] == '0' && tag[1] == '0'
+}
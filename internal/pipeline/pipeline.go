@@ -0,0 +1,505 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsoria-ttec/bne-converter/internal/ckan"
+	"github.com/fsoria-ttec/bne-converter/internal/constants"
+	"github.com/fsoria-ttec/bne-converter/internal/crawler"
+	"github.com/fsoria-ttec/bne-converter/internal/diff"
+	"github.com/fsoria-ttec/bne-converter/internal/metrics"
+	"github.com/fsoria-ttec/bne-converter/internal/notify"
+	"github.com/fsoria-ttec/bne-converter/internal/parser"
+	"github.com/fsoria-ttec/bne-converter/internal/report"
+	"github.com/fsoria-ttec/bne-converter/internal/storage"
+	"github.com/fsoria-ttec/bne-converter/pkg/models"
+	"github.com/sirupsen/logrus" // logging
+)
+
+// Número de ejecuciones que se conservan en memoria para su consulta
+const maxTrackedRuns = 50
+
+type Options struct {
+	Mode       string
+	Categories []string // vacío: categorías seleccionadas en la configuración
+	Force      bool
+}
+
+// Pipeline descarga, procesa y publica categorías, registrando cada ejecución
+// en el informe. Las ejecuciones se serializan para no descargar dos veces el
+// mismo fichero a la vez
+type Pipeline struct {
+	crawler   *crawler.Crawler
+	reports   *report.Store
+	store     *storage.Store  // nil: solo se validan los ficheros
+	publisher *ckan.Publisher // nil: no se publica en CKAN
+	notifier  notify.Notifier
+	logger    *logrus.Logger
+	running   sync.Mutex
+
+	mu    sync.RWMutex
+	runs  map[string]*report.Run
+	order []string
+}
+
+func New(crw *crawler.Crawler, reports *report.Store, store *storage.Store, publisher *ckan.Publisher,
+	notifier notify.Notifier, logger *logrus.Logger) *Pipeline {
+	return &Pipeline{
+		crawler:   crw,
+		reports:   reports,
+		store:     store,
+		publisher: publisher,
+		notifier:  notifier,
+		logger:    logger,
+		runs:      make(map[string]*report.Run),
+	}
+}
+
+// Run ejecuta el pipeline y espera a que termine
+func (p *Pipeline) Run(ctx context.Context, opts Options) *report.Run {
+	run := p.track(opts)
+	p.execute(ctx, run)
+	return run
+}
+
+// Start encola la ejecución y la devuelve sin esperar a que termine
+func (p *Pipeline) Start(ctx context.Context, opts Options) *report.Run {
+	run := p.track(opts)
+	go p.execute(ctx, run)
+	return run
+}
+
+func (p *Pipeline) Get(id string) (*report.Run, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	run, exists := p.runs[id]
+	return run, exists
+}
+
+func (p *Pipeline) track(opts Options) *report.Run {
+	categories := opts.Categories
+	if len(categories) == 0 {
+		categories = p.crawler.SelectedCategories()
+	}
+
+	run := report.NewRun(opts.Mode, categories, opts.Force)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.runs[run.ID] = run
+	p.order = append(p.order, run.ID)
+	if len(p.order) > maxTrackedRuns {
+		delete(p.runs, p.order[0])
+		p.order = p.order[1:]
+	}
+
+	return run
+}
+
+func (p *Pipeline) execute(ctx context.Context, run *report.Run) {
+	p.running.Lock()
+	defer p.running.Unlock()
+
+	run.SetStatus(report.RunRunning)
+	runLog := p.logger.WithFields(logrus.Fields{"run_id": run.ID})
+
+	results := p.crawler.DownloadCategories(ctx, run.Requested, run.Force)
+	for _, result := range results {
+		categoryLog := runLog.WithField("category", result.Category)
+
+		categoryReport := report.CategoryReport{
+			Category:    result.Category,
+			Result:      report.ResultOK,
+			FilePath:    result.FilePath,
+			ProcessedAt: time.Now(),
+		}
+
+		// La versión anterior del fichero es la cargada si su carga se confirmó
+		loadedPrevious := false
+		if previous, exists := p.reports.Category(result.Category); exists {
+			categoryReport.PublishedAt = previous.PublishedAt
+			categoryReport.LastSuccess = previous.LastSuccess
+			loadedPrevious = previous.Load != nil && previous.Load.Status == report.LoadSwapped
+		}
+
+		if result.Error != nil {
+			categoryLog.WithField("stage", "download").WithError(result.Error).Error("Error al descargar")
+			categoryReport.Result = report.ResultError
+			categoryReport.Error = result.Error.Error()
+			run.Add(categoryReport)
+			continue
+		}
+		categoryLog.WithFields(logrus.Fields{
+			"stage": "download",
+			"file":  result.FilePath,
+		}).Info("Fichero disponible para procesar")
+
+		if !result.Skipped {
+			p.notifier.Notify(newEvent(notify.EventDownloadCompleted, run, result))
+		}
+
+		var changes *diff.Changeset
+		if result.PreviousPath != "" {
+			changes, categoryReport.Changes = p.compareVersions(ctx, run, result, categoryLog)
+		}
+		if !loadedPrevious {
+			changes = nil
+		}
+
+		summary := newFileSummary()
+		stats, err := p.processFile(ctx, run.ID, result.Category, result.FilePath, changes, summary, categoryLog)
+		metrics.ObserveParse(result.Category, stats.Records, stats.Errors)
+		categoryReport.RecordCount = stats.Records
+		categoryReport.ParseErrors = stats.Errors
+		summary.apply(&categoryReport)
+
+		// Sin base de datos no hay carga que notificar, solo los fallos
+		eventType, eventError := notify.EventLoadCompleted, ""
+		if p.store == nil {
+			eventType = ""
+		}
+		if err != nil {
+			categoryLog.WithField("file", result.FilePath).WithError(err).Error("Error al procesar")
+			categoryReport.Result = report.ResultError
+			categoryReport.Error = err.Error()
+			eventType, eventError = notify.EventValidationFailed, err.Error()
+		} else {
+			metrics.ObserveCategorySuccess(result.Category)
+			categoryReport.LastSuccess = categoryReport.ProcessedAt
+			if stats.Errors > 0 {
+				eventType = notify.EventValidationFailed
+				eventError = fmt.Sprintf("%d registros mal formados omitidos", stats.Errors)
+			}
+		}
+
+		if eventType != "" {
+			event := newEvent(eventType, run, result)
+			event.RecordCount = stats.Records
+			event.ParseErrors = stats.Errors
+			event.Error = eventError
+			p.notifier.Notify(event)
+		}
+
+		// Un fichero ya publicado que no ha cambiado no se vuelve a publicar
+		if err == nil && p.publisher != nil && (!result.Skipped || categoryReport.PublishedAt.IsZero()) {
+			p.publish(ctx, run, result, &categoryReport, categoryLog)
+		}
+		run.Add(categoryReport)
+	}
+
+	if p.store != nil {
+		p.linkHeadings(ctx, run, runLog)
+	}
+
+	if run.HasErrors() {
+		run.SetStatus(report.RunFailed)
+		p.notifier.Notify(runFailedEvent(run))
+	} else {
+		run.SetStatus(report.RunCompleted)
+	}
+
+	if err := p.reports.Save(run); err != nil {
+		runLog.WithError(err).Warn("Error al guardar informe de ejecución")
+	}
+}
+
+// linkHeadings enlaza con autoridades los encabezamientos de las categorías
+// bibliográficas cargadas. Si se han cargado autoridades, se enlazan de nuevo
+// todas las categorías bibliográficas
+func (p *Pipeline) linkHeadings(ctx context.Context, run *report.Run, logger *logrus.Entry) {
+	log := logger.WithField("stage", "link")
+
+	var loaded []string
+	authorities := false
+	for _, category := range run.Requested {
+		result, exists := run.Result(category)
+		if !exists || result.Result != report.ResultOK {
+			continue
+		}
+		if family, _ := constants.CategoryFamily(category); family == constants.FamilyAuthority {
+			authorities = true
+		} else {
+			loaded = append(loaded, category)
+		}
+	}
+
+	scope := loaded
+	if authorities {
+		scope = nil
+		for _, category := range p.crawler.Categories() {
+			if category.Family == constants.FamilyBibliographic {
+				scope = append(scope, category.Id)
+			}
+		}
+	}
+	if len(scope) == 0 {
+		return
+	}
+
+	start := time.Now()
+	stats, err := p.store.LinkHeadings(ctx, scope)
+	if err != nil {
+		log.WithError(err).Error("Error al enlazar encabezamientos")
+		for _, category := range loaded {
+			if result, exists := run.Result(category); exists {
+				result.Result = report.ResultError
+				result.Error = err.Error()
+				run.Add(result)
+			}
+		}
+		return
+	}
+
+	for _, category := range scope {
+		linkStats, exists := stats[category]
+		if !exists {
+			continue
+		}
+
+		log.WithFields(logrus.Fields{
+			"category":  category,
+			"linked":    linkStats.Linked,
+			"unmatched": linkStats.Unmatched,
+		}).Info("Encabezamientos enlazados con autoridades")
+
+		result, exists := run.Result(category)
+		if !exists {
+			continue
+		}
+		result.LinkedHeadings = linkStats.Linked
+		result.UnmatchedHeadings = linkStats.Unmatched
+		result.TopUnmatched = nil
+		for _, heading := range linkStats.TopUnmatched {
+			result.TopUnmatched = append(result.TopUnmatched, report.UnmatchedHeading(heading))
+		}
+		run.Add(result)
+	}
+	log.WithField("duration_ms", time.Since(start).Milliseconds()).Debug("Enlace de encabezamientos finalizado")
+}
+
+// publish actualiza el recurso de la categoría en CKAN. Un error no hace
+// fallar la categoría, que ya está procesada; se reintenta en la siguiente
+// ejecución porque la fecha de publicación no avanza
+func (p *Pipeline) publish(ctx context.Context, run *report.Run, result crawler.DownloadResult,
+	categoryReport *report.CategoryReport, logger *logrus.Entry) {
+	log := logger.WithField("stage", "publish")
+
+	resource := ckan.Resource{
+		Category:     result.Category,
+		URL:          result.URL,
+		Size:         result.Size,
+		Checksum:     result.Checksum,
+		RecordCount:  categoryReport.RecordCount,
+		LastModified: result.LastModified,
+	}
+	for _, category := range constants.BNECategories {
+		if category.Id == result.Category {
+			resource.Description = category.Description
+		}
+	}
+
+	if err := p.publisher.Publish(ctx, resource); err != nil {
+		metrics.ObservePublish(result.Category, metrics.ResultError)
+		log.WithError(err).Error("Error al publicar en CKAN")
+		return
+	}
+	metrics.ObservePublish(result.Category, metrics.ResultOK)
+	categoryReport.PublishedAt = time.Now()
+	log.Info("Categoría publicada en CKAN")
+
+	event := newEvent(notify.EventPublishCompleted, run, result)
+	event.RecordCount = categoryReport.RecordCount
+	p.notifier.Notify(event)
+}
+
+func newEvent(eventType string, run *report.Run, result crawler.DownloadResult) notify.Event {
+	event := notify.NewEvent(eventType)
+	event.RunID = run.ID
+	event.Category = result.Category
+	event.URL = result.URL
+	event.Size = result.Size
+	event.Checksum = result.Checksum
+	return event
+}
+
+// runFailedEvent resume las categorías fallidas de la ejecución
+func runFailedEvent(run *report.Run) notify.Event {
+	event := notify.NewEvent(notify.EventRunFailed)
+	event.RunID = run.ID
+
+	for _, category := range run.Requested {
+		result, exists := run.Result(category)
+		if !exists || result.Result != report.ResultError {
+			continue
+		}
+		event.Failures = append(event.Failures, notify.Failure{
+			Category:    category,
+			Error:       result.Error,
+			LastSuccess: result.LastSuccess,
+		})
+	}
+	return event
+}
+
+// compareVersions calcula los cambios respecto a la versión anterior del
+// fichero y los guarda junto a él. Un error no impide procesar el fichero
+func (p *Pipeline) compareVersions(ctx context.Context, run *report.Run, result crawler.DownloadResult, logger *logrus.Entry) (*diff.Changeset, *report.Changes) {
+	start := time.Now()
+	log := logger.WithFields(logrus.Fields{
+		"stage":    "diff",
+		"previous": result.PreviousPath,
+	})
+
+	changes, err := diff.Compare(ctx, result.Category, result.PreviousPath, result.FilePath)
+	if err != nil {
+		log.WithError(err).Warn("Error al comparar con la versión anterior")
+		return nil, nil
+	}
+
+	changelog := filepath.Join(filepath.Dir(result.FilePath), "changelog", run.ID)
+	if err := changes.Write(filepath.Dir(changelog), filepath.Base(changelog)); err != nil {
+		log.WithError(err).Warn("Error al guardar el registro de cambios")
+		return changes, nil
+	}
+
+	log.WithFields(logrus.Fields{
+		"added":       len(changes.Added),
+		"deleted":     len(changes.Deleted),
+		"modified":    len(changes.Modified),
+		"unchanged":   changes.Unchanged,
+		"duplicates":  len(changes.Duplicates),
+		"duration_ms": time.Since(start).Milliseconds(),
+	}).Info("Cambios respecto a la versión anterior")
+
+	return changes, &report.Changes{
+		Added:      len(changes.Added),
+		Deleted:    len(changes.Deleted),
+		Modified:   len(changes.Modified),
+		Duplicates: len(changes.Duplicates),
+		Changelog:  changelog,
+	}
+}
+
+// processFile lee el fichero, resume sus registros y, si hay base de datos,
+// los guarda en una única transacción. Con los cambios respecto a la versión
+// cargada solo se comparan los registros añadidos o modificados. Los
+// registros que no se pueden guardar se cuentan como errores de lectura
+func (p *Pipeline) processFile(ctx context.Context, runID, category, filePath string, changes *diff.Changeset,
+	summary *fileSummary, logger *logrus.Entry) (parser.Stats, error) {
+	start := time.Now()
+	log := logger.WithFields(logrus.Fields{
+		"stage": "parse",
+		"file":  filePath,
+	})
+
+	if p.store == nil {
+		stats, err := parser.ParseFile(ctx, filePath, func(record *models.Record) error {
+			summary.observe(category, record)
+			return nil
+		})
+		if err == nil {
+			logStats(log, stats, 0, start)
+			summary.log(log)
+		}
+		return stats, err
+	}
+
+	batch, err := p.store.Begin(ctx, runID, category)
+	if err != nil {
+		return parser.Stats{}, err
+	}
+	if changes != nil {
+		modified := make([]string, 0, len(changes.Modified))
+		for _, record := range changes.Modified {
+			modified = append(modified, record.ControlNumber)
+		}
+		batch.Expect(changes.Added, modified, changes.Deleted)
+	}
+
+	invalid := 0
+	stats, err := parser.ParseFile(ctx, filePath, func(record *models.Record) error {
+		summary.observe(category, record)
+		err := batch.Save(record)
+		if errors.Is(err, storage.ErrInvalidRecord) {
+			invalid++
+			log.WithField("stage", "store").WithError(err).Debug("Registro omitido")
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		batch.Rollback()
+		summary.loaded = &report.Load{Status: report.LoadRolledBack, Error: err.Error()}
+		metrics.ObserveLoad(category, report.LoadRolledBack, 0, 0, 0, 0)
+		return stats, err
+	}
+
+	// Un registro mal formado no puede distinguirse de uno eliminado
+	tombstone := stats.Errors == 0
+	if !tombstone {
+		log.WithField("stage", "store").Warn("Fichero con registros mal formados: no se marcan registros eliminados")
+	}
+	loaded, err := batch.Commit(tombstone, stats.Records-invalid)
+	if errors.Is(err, storage.ErrDatestamp) {
+		log.WithField("stage", "store").WithError(err).Warn("Error al sellar los registros cargados")
+		err = nil
+	}
+	summary.loaded = &report.Load{
+		Status:     report.LoadSwapped,
+		Added:      loaded.Added,
+		Updated:    loaded.Updated,
+		Unchanged:  loaded.Unchanged,
+		Deleted:    loaded.Deleted,
+		Duplicates: loaded.Duplicates,
+		Diverged:   loaded.Diverged,
+	}
+	if err != nil {
+		summary.loaded.Status = report.LoadRolledBack
+		summary.loaded.Error = err.Error()
+		metrics.ObserveLoad(category, report.LoadRolledBack, 0, 0, 0, 0)
+		log.WithField("stage", "store").WithError(err).Error("Carga deshecha; se conservan los datos anteriores")
+		return stats, err
+	}
+	metrics.ObserveLoad(category, report.LoadSwapped, loaded.Added, loaded.Updated, loaded.Unchanged, loaded.Deleted)
+	if loaded.Diverged > 0 {
+		log.WithFields(logrus.Fields{
+			"stage":    "store",
+			"diverged": loaded.Diverged,
+		}).Warn("La base de datos no coincide con los cambios respecto a la versión anterior")
+	}
+	log.WithFields(logrus.Fields{
+		"stage":      "store",
+		"added":      loaded.Added,
+		"updated":    loaded.Updated,
+		"unchanged":  loaded.Unchanged,
+		"deleted":    loaded.Deleted,
+		"duplicates": loaded.Duplicates,
+	}).Info("Carga validada y confirmada")
+
+	stats.Records -= invalid
+	stats.Errors += invalid
+	logStats(log, stats, invalid, start)
+	summary.log(log)
+	return stats, nil
+}
+
+func logStats(log *logrus.Entry, stats parser.Stats, invalid int, start time.Time) {
+	if stats.Errors > 0 {
+		log.WithFields(logrus.Fields{
+			"parse_errors":    stats.Errors,
+			"invalid_records": invalid,
+		}).Warn("Registros mal formados omitidos")
+	}
+	log.WithFields(logrus.Fields{
+		"records":     stats.Records,
+		"authorities": stats.Authorities,
+		"duration_ms": time.Since(start).Milliseconds(),
+	}).Info("Registros leídos")
+}
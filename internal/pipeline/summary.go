@@ -0,0 +1,130 @@
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/fsoria-ttec/bne-converter/internal/report"
+	"github.com/fsoria-ttec/bne-converter/pkg/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Número de casos que se conservan como muestra en el informe
+const qualitySample = 20
+
+// fileSummary acumula la calidad de datos y las estadísticas de lengua y
+// lugar de publicación de los registros bibliográficos de un fichero, y el
+// resultado de su carga en base de datos
+type fileSummary struct {
+	quality   report.Quality
+	languages map[string]*report.CodeCount
+	countries map[string]*report.CodeCount
+	loaded    *report.Load
+}
+
+func newFileSummary() *fileSummary {
+	return &fileSummary{
+		languages: make(map[string]*report.CodeCount),
+		countries: make(map[string]*report.CodeCount),
+	}
+}
+
+func (s *fileSummary) observe(category string, record *models.Record) {
+	if record.IsAuthority() {
+		return
+	}
+	bibliographic := models.NewBibliographic(category, record)
+
+	if len(bibliographic.Languages) > 0 {
+		language := bibliographic.Languages[0]
+		count(s.languages, language.Code, language.Spanish, language.English)
+	}
+	if country := bibliographic.Country; country != nil {
+		count(s.countries, country.Code, country.Spanish, country.English)
+	}
+
+	if date := bibliographic.Date; date != nil && date.Conflict {
+		s.quality.DateConflicts++
+		s.addSample(report.QualityIssue{
+			ControlNumber: bibliographic.ControlNumber,
+			Field:         "008/26X",
+			Value:         fmt.Sprintf("%d / %s", date.Start, date.Text),
+			Problem:       "fecha del 008 distinta de la de publicación",
+		})
+	}
+
+	for _, number := range bibliographic.Numbers {
+		if !number.Invalid() {
+			continue
+		}
+
+		problem := "ISBN no válido"
+		if number.Type == models.NumberISSN {
+			problem = "ISSN no válido"
+			s.quality.InvalidISSN++
+		} else {
+			s.quality.InvalidISBN++
+		}
+
+		s.addSample(report.QualityIssue{
+			ControlNumber: bibliographic.ControlNumber,
+			Field:         number.Tag + "$" + number.Subfield,
+			Value:         number.Value,
+			Problem:       problem,
+		})
+	}
+}
+
+func (s *fileSummary) addSample(issue report.QualityIssue) {
+	if len(s.quality.Samples) < qualitySample {
+		s.quality.Samples = append(s.quality.Samples, issue)
+	}
+}
+
+func (s *fileSummary) hasIssues() bool {
+	return s.quality.InvalidISBN > 0 || s.quality.InvalidISSN > 0 || s.quality.DateConflicts > 0
+}
+
+// apply incorpora el resumen al informe de la categoría
+func (s *fileSummary) apply(result *report.CategoryReport) {
+	if s.hasIssues() {
+		quality := s.quality
+		result.Quality = &quality
+	}
+	result.Languages = sortedCounts(s.languages)
+	result.Countries = sortedCounts(s.countries)
+	result.Load = s.loaded
+}
+
+func (s *fileSummary) log(log *logrus.Entry) {
+	if s.hasIssues() {
+		log.WithFields(logrus.Fields{
+			"invalid_isbn":   s.quality.InvalidISBN,
+			"invalid_issn":   s.quality.InvalidISSN,
+			"date_conflicts": s.quality.DateConflicts,
+		}).Warn("Problemas de calidad de datos")
+	}
+}
+
+func count(counts map[string]*report.CodeCount, code, spanish, english string) {
+	if entry, exists := counts[code]; exists {
+		entry.Count++
+		return
+	}
+	counts[code] = &report.CodeCount{Code: code, Spanish: spanish, English: english, Count: 1}
+}
+
+// sortedCounts ordena de mayor a menor número de registros
+func sortedCounts(counts map[string]*report.CodeCount) []report.CodeCount {
+	var sorted []report.CodeCount
+	for _, entry := range counts {
+		sorted = append(sorted, *entry)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Code < sorted[j].Code
+	})
+	return sorted
+}
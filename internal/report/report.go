@@ -0,0 +1,263 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Resultados de procesamiento de una categoría
+const (
+	ResultOK    = "ok"
+	ResultError = "error"
+)
+
+// Resultado de la carga en base de datos de una categoría
+const (
+	LoadSwapped    = "swapped"     // transacción de carga validada y confirmada
+	LoadRolledBack = "rolled_back" // transacción deshecha; se conservan los datos anteriores
+)
+
+// Estados de una ejecución
+const (
+	RunQueued    = "queued"
+	RunRunning   = "running"
+	RunCompleted = "completed"
+	RunFailed    = "failed"
+)
+
+type CategoryReport struct {
+	Category    string    `json:"category"`
+	Result      string    `json:"result"`
+	Error       string    `json:"error,omitempty"`
+	FilePath    string    `json:"file_path,omitempty"`
+	RecordCount int       `json:"record_count"`
+	ParseErrors int       `json:"parse_errors"`
+	ProcessedAt time.Time `json:"processed_at"`
+	PublishedAt time.Time `json:"published_at"` // última publicación en CKAN
+	LastSuccess time.Time `json:"last_success"` // último procesamiento correcto
+
+	// Enlace de encabezamientos con autoridades
+	LinkedHeadings    int                `json:"linked_headings,omitempty"`
+	UnmatchedHeadings int                `json:"unmatched_headings,omitempty"`
+	TopUnmatched      []UnmatchedHeading `json:"top_unmatched,omitempty"`
+
+	Quality *Quality `json:"quality,omitempty"`
+
+	// Cambios respecto a la versión anterior del fichero
+	Changes *Changes `json:"changes,omitempty"`
+
+	// Resultado de la carga incremental en base de datos
+	Load *Load `json:"load,omitempty"`
+
+	// Lengua principal y lugar de publicación de los registros
+	Languages []CodeCount `json:"languages,omitempty"`
+	Countries []CodeCount `json:"countries,omitempty"`
+}
+
+type Changes struct {
+	Added      int    `json:"added"`
+	Deleted    int    `json:"deleted"`
+	Modified   int    `json:"modified"`
+	Duplicates int    `json:"duplicates,omitempty"` // números de control repetidos en el fichero nuevo
+	Changelog  string `json:"changelog"`            // ruta sin extensión de los ficheros JSON y CSV
+}
+
+type Load struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	Added      int    `json:"added"`
+	Updated    int    `json:"updated"`
+	Unchanged  int    `json:"unchanged"`
+	Deleted    int    `json:"deleted"` // marcados como eliminados
+	Duplicates int    `json:"duplicates,omitempty"`
+	Diverged   int    `json:"diverged,omitempty"` // registros que no coinciden con los cambios del fichero
+}
+
+type CodeCount struct {
+	Code    string `json:"code"`
+	Spanish string `json:"es"`
+	English string `json:"en"`
+	Count   int    `json:"count"`
+}
+
+// Quality resume los problemas de calidad de datos detectados en la carga
+type Quality struct {
+	InvalidISBN   int            `json:"invalid_isbn,omitempty"`
+	InvalidISSN   int            `json:"invalid_issn,omitempty"`
+	DateConflicts int            `json:"date_conflicts,omitempty"` // 008 y 26X no coinciden
+	Samples       []QualityIssue `json:"samples,omitempty"`        // primeros casos detectados
+}
+
+type QualityIssue struct {
+	ControlNumber string `json:"control_number"`
+	Field         string `json:"field"`
+	Value         string `json:"value"`
+	Problem       string `json:"problem"`
+}
+
+type UnmatchedHeading struct {
+	Tag         string `json:"tag"`
+	Heading     string `json:"heading"`
+	Occurrences int    `json:"occurrences"`
+}
+
+type Run struct {
+	ID         string                    `json:"id"`
+	Mode       string                    `json:"mode"`
+	Status     string                    `json:"status"`
+	Force      bool                      `json:"force"`
+	Requested  []string                  `json:"requested"`
+	StartedAt  time.Time                 `json:"started_at"`
+	FinishedAt time.Time                 `json:"finished_at"`
+	Categories map[string]CategoryReport `json:"categories"`
+	mu         sync.Mutex
+}
+
+// Report es el contenido persistido: la última ejecución y el último
+// resultado conocido de cada categoría
+type Report struct {
+	LastRun    *Run                      `json:"last_run,omitempty"`
+	Categories map[string]CategoryReport `json:"categories"`
+}
+
+type Store struct {
+	path   string
+	mu     sync.Mutex
+	report Report
+}
+
+func NewRun(mode string, requested []string, force bool) *Run {
+	now := time.Now()
+	return &Run{
+		ID:         now.Format("20060102-150405.000"),
+		Mode:       mode,
+		Status:     RunQueued,
+		Force:      force,
+		Requested:  requested,
+		StartedAt:  now,
+		Categories: make(map[string]CategoryReport),
+	}
+}
+
+func (r *Run) SetStatus(status string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Status = status
+}
+
+// Progress devuelve las categorías terminadas y las solicitadas
+func (r *Run) Progress() (int, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.Categories), len(r.Requested)
+}
+
+// MarshalJSON serializa la ejecución bloqueándola, ya que puede seguir en curso
+func (r *Run) MarshalJSON() ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	type run Run
+	return json.Marshal((*run)(r))
+}
+
+func (r *Run) Add(category CategoryReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Categories[category.Category] = category
+}
+
+func (r *Run) Result(category string) (CategoryReport, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result, exists := r.Categories[category]
+	return result, exists
+}
+
+// HasErrors indica si alguna categoría de la ejecución ha fallado
+func (r *Run) HasErrors() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, category := range r.Categories {
+		if category.Result == ResultError {
+			return true
+		}
+	}
+	return false
+}
+
+func NewStore(basePath string) (*Store, error) {
+	store := &Store{
+		path: filepath.Join(basePath, "run_report.json"),
+		report: Report{
+			Categories: make(map[string]CategoryReport),
+		},
+	}
+
+	if err := store.load(); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error cargando informe de ejecución (%w)", err)
+	}
+
+	return store, nil
+}
+
+func (s *Store) load() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, &s.report); err != nil {
+		return err
+	}
+	if s.report.Categories == nil {
+		s.report.Categories = make(map[string]CategoryReport)
+	}
+	return nil
+}
+
+// Save cierra la ejecución y la incorpora al informe persistido
+func (s *Store) Save(run *Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run.mu.Lock()
+	run.FinishedAt = time.Now()
+	for category, result := range run.Categories {
+		s.report.Categories[category] = result
+	}
+	run.mu.Unlock()
+
+	s.report.LastRun = run
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("error creando directorio del informe (%w)", err)
+	}
+
+	data, err := json.MarshalIndent(s.report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error serializando informe de ejecución (%w)", err)
+	}
+
+	return os.WriteFile(s.path, data, 0644)
+}
+
+func (s *Store) LastRun() *Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.report.LastRun
+}
+
+func (s *Store) Category(category string) (CategoryReport, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, exists := s.report.Categories[category]
+	return result, exists
+}
@@ -0,0 +1,175 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // zonas horarias embebidas para entornos sin tzdata
+
+	"github.com/robfig/cron/v3" // expresiones cron
+)
+
+// Límite de iteraciones al buscar una ejecución cron dentro de la ventana
+const maxWindowSearch = 10000
+
+// Schedule calcula el siguiente momento de ejecución posterior a t
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// Window es una franja horaria diaria, que puede cruzar la medianoche (22:00-06:00)
+type Window struct {
+	start int // minutos desde medianoche
+	end   int
+}
+
+type intervalSchedule struct {
+	interval time.Duration
+	window   *Window
+}
+
+type windowedSchedule struct {
+	schedule Schedule
+	window   *Window
+}
+
+// New construye la planificación: la expresión cron tiene prioridad sobre el
+// intervalo y, si hay ventana, solo se planifica dentro de ella
+func New(expr string, interval time.Duration, window *Window) (Schedule, error) {
+	if expr == "" {
+		return intervalSchedule{interval: interval, window: window}, nil
+	}
+
+	schedule, err := ParseCron(expr)
+	if err != nil {
+		return nil, err
+	}
+	if window == nil {
+		return schedule, nil
+	}
+	return windowedSchedule{schedule: schedule, window: window}, nil
+}
+
+func ParseCron(expr string) (Schedule, error) {
+	schedule, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, fmt.Errorf("expresión cron inválida %q (%w)", expr, err)
+	}
+	return schedule, nil
+}
+
+// ParseWindow interpreta una franja "HH:MM-HH:MM". Una cadena vacía equivale a
+// no tener ventana (nil), que contiene cualquier instante
+func ParseWindow(value string) (*Window, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	from, to, found := strings.Cut(value, "-")
+	if !found {
+		return nil, fmt.Errorf("ventana horaria inválida %q (se espera HH:MM-HH:MM)", value)
+	}
+
+	start, err := parseClock(from)
+	if err != nil {
+		return nil, fmt.Errorf("ventana horaria inválida %q (%w)", value, err)
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return nil, fmt.Errorf("ventana horaria inválida %q (%w)", value, err)
+	}
+
+	return &Window{start: start, end: end}, nil
+}
+
+// Location carga la zona horaria indicada; vacía equivale a la hora local
+func Location(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("zona horaria desconocida %q (%w)", name, err)
+	}
+	return location, nil
+}
+
+func (w *Window) Contains(t time.Time) bool {
+	if w == nil || w.start == w.end {
+		return true
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return minute >= w.start && minute < w.end
+	}
+	return minute >= w.start || minute < w.end
+}
+
+// NextOpen devuelve t si está dentro de la ventana o, si no, su próxima apertura
+func (w *Window) NextOpen(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+
+	var open time.Time
+	for day := 0; day < 3; day++ {
+		for _, open = range w.openings(t, day) {
+			// El día del cambio de hora de primavera la apertura puede caer
+			// en la hora que se salta y time.Date la desplaza fuera de la ventana
+			if open.After(t) && w.Contains(open) {
+				return open
+			}
+		}
+	}
+	return open
+}
+
+// openings devuelve la hora de apertura del día t+days, dos veces si se
+// repite con el cambio de hora de otoño (la primera antes)
+func (w *Window) openings(t time.Time, days int) []time.Time {
+	open := time.Date(t.Year(), t.Month(), t.Day()+days, w.start/60, w.start%60, 0, 0, t.Location())
+	if earlier := open.Add(-time.Hour); earlier.Hour() == open.Hour() && earlier.Minute() == open.Minute() {
+		return []time.Time{earlier, open}
+	}
+	return []time.Time{open}
+}
+
+func (w *Window) String() string {
+	if w == nil {
+		return "sin ventana"
+	}
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", w.start/60, w.start%60, w.end/60, w.end%60)
+}
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return s.window.NextOpen(t.Add(s.interval))
+}
+
+func (s windowedSchedule) Next(t time.Time) time.Time {
+	next := s.schedule.Next(t)
+	for i := 0; i < maxWindowSearch && !s.window.Contains(next); i++ {
+		next = s.schedule.Next(next)
+	}
+	return next
+}
+
+func parseClock(value string) (int, error) {
+	hours, minutes, found := strings.Cut(strings.TrimSpace(value), ":")
+	if !found {
+		return 0, fmt.Errorf("hora inválida %q", value)
+	}
+
+	h, err := strconv.Atoi(hours)
+	if err != nil || h < 0 || h > 23 {
+		return 0, fmt.Errorf("hora inválida %q", value)
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m < 0 || m > 59 {
+		return 0, fmt.Errorf("hora inválida %q", value)
+	}
+
+	return h*60 + m, nil
+}
@@ -0,0 +1,71 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Dir sirve los ficheros de un directorio local, como una réplica de los
+// volcados de la BNE o un recurso NFS montado
+type Dir struct {
+	root string
+}
+
+func NewDir(root string) *Dir {
+	return &Dir{root: root}
+}
+
+func (d *Dir) List(ctx context.Context) ([]FileInfo, error) {
+	entries, err := os.ReadDir(d.root)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer directorio de origen (%w)", err)
+	}
+
+	var files []FileInfo
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("error al leer %s (%w)", entry.Name(), err)
+		}
+		files = append(files, fileInfo(info))
+	}
+
+	return files, nil
+}
+
+func (d *Dir) Stat(ctx context.Context, name string) (FileInfo, error) {
+	info, err := os.Stat(d.Location(name))
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("error al consultar fichero de origen (%w)", err)
+	}
+	if !info.Mode().IsRegular() {
+		return FileInfo{}, fmt.Errorf("%s no es un fichero", d.Location(name))
+	}
+	return fileInfo(info), nil
+}
+
+func (d *Dir) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	file, err := os.Open(d.Location(name))
+	if err != nil {
+		return nil, fmt.Errorf("error al abrir fichero de origen (%w)", err)
+	}
+	return file, nil
+}
+
+func (d *Dir) Location(name string) string {
+	return filepath.Join(d.root, name)
+}
+
+func fileInfo(info os.FileInfo) FileInfo {
+	return FileInfo{
+		Name:         info.Name(),
+		Size:         info.Size(),
+		LastModified: info.ModTime(),
+	}
+}
@@ -0,0 +1,111 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Enlaces de una página de índice
+var hrefPattern = regexp.MustCompile(`(?i)href\s*=\s*["']([^"'?#]+)["']`)
+
+// HTTP sirve los ficheros publicados bajo una URL base. List interpreta los
+// enlaces de la página de índice; el tamaño y la fecha se obtienen con HEAD
+type HTTP struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewHTTP(baseURL string, timeout time.Duration) *HTTP {
+	return &HTTP{
+		baseURL: baseURL,
+		client: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+func (h *HTTP) List(ctx context.Context) ([]FileInfo, error) {
+	resp, err := h.do(ctx, http.MethodGet, h.baseURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer índice (%w)", err)
+	}
+
+	var files []FileInfo
+	seen := make(map[string]bool)
+	for _, match := range hrefPattern.FindAllStringSubmatch(string(content), -1) {
+		href, err := url.PathUnescape(match[1])
+		if err != nil || strings.HasSuffix(href, "/") {
+			continue
+		}
+
+		name := path.Base(href)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		files = append(files, FileInfo{Name: name, Size: -1})
+	}
+
+	return files, nil
+}
+
+func (h *HTTP) Stat(ctx context.Context, name string) (FileInfo, error) {
+	resp, err := h.do(ctx, http.MethodHead, h.Location(name))
+	if err != nil {
+		return FileInfo{}, err
+	}
+	resp.Body.Close()
+
+	info := FileInfo{Name: name, Size: resp.ContentLength}
+
+	// Sin Last-Modified válido la fecha queda a cero
+	if lastModified, err := time.Parse(time.RFC1123, resp.Header.Get("Last-Modified")); err == nil {
+		info.LastModified = lastModified
+	}
+
+	return info, nil
+}
+
+func (h *HTTP) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	resp, err := h.do(ctx, http.MethodGet, h.Location(name))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (h *HTTP) Location(name string) string {
+	return h.baseURL + name
+}
+
+func (h *HTTP) do(ctx context.Context, method, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creando petición %s (%w)", method, err)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error realizando petición %s (%w)", method, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("código de estado inesperado en %s (%d)", method, resp.StatusCode)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,107 @@
+package source
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const index = `<html><body>
+<a href="../">Superior</a>
+<a href="antiguos/">antiguos/</a>
+<a href="MONOMODERN-mrc_new.mrc">MONOMODERN</a>
+<a HREF='/registros/VIDEO-mrc_new.mrc'>VIDEO</a>
+<a href="./MONOMODERN-mrc_new.mrc">MONOMODERN otra vez</a>
+<a href="MONOMODERN-mrc_new.mrc?descarga=1">Enlace con consulta</a>
+<a href="Registros%20SONORO.mrc">SONORO</a>
+</body></html>`
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	modified := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/registros/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/registros/":
+			io.WriteString(w, index)
+		case "/registros/MONOMODERN-mrc_new.mrc":
+			w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+			http.ServeContent(w, r, "", modified, strings.NewReader("registros"))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPList(t *testing.T) {
+	server := newTestServer(t)
+	src, err := New(server.URL+"/registros", time.Second)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	files, err := src.List(context.Background())
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name)
+		if file.Size != -1 {
+			t.Errorf("%s con tamaño %d sin HEAD", file.Name, file.Size)
+		}
+	}
+	want := []string{"MONOMODERN-mrc_new.mrc", "VIDEO-mrc_new.mrc", "Registros SONORO.mrc"}
+	if len(names) != len(want) {
+		t.Fatalf("List = %q, se esperaba %q", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("List = %q, se esperaba %q", names, want)
+			break
+		}
+	}
+}
+
+func TestHTTPStatAndOpen(t *testing.T) {
+	server := newTestServer(t)
+	// Sin barra final el fichero se resuelve igualmente bajo /registros/
+	src, err := New(server.URL+"/registros", time.Second)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ctx := context.Background()
+
+	if want := server.URL + "/registros/MONOMODERN-mrc_new.mrc"; src.Location("MONOMODERN-mrc_new.mrc") != want {
+		t.Errorf("Location = %q, se esperaba %q", src.Location("MONOMODERN-mrc_new.mrc"), want)
+	}
+
+	info, err := src.Stat(ctx, "MONOMODERN-mrc_new.mrc")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Size != 9 || !info.LastModified.Equal(time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Stat = %+v", info)
+	}
+
+	reader, err := src.Open(ctx, "MONOMODERN-mrc_new.mrc")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer reader.Close()
+	if content, _ := io.ReadAll(reader); string(content) != "registros" {
+		t.Errorf("contenido %q", content)
+	}
+
+	if _, err := src.Stat(ctx, "VIDEO-mrc_new.mrc"); err == nil {
+		t.Error("Stat de un fichero inexistente sin error")
+	}
+}
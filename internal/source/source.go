@@ -0,0 +1,111 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileInfo describe un fichero publicado en el origen
+type FileInfo struct {
+	Name         string
+	Size         int64     // -1 si el origen no lo indica
+	LastModified time.Time // cero si el origen no lo indica
+}
+
+// Source es el origen de los ficheros MARC: la web de la BNE, una réplica
+// local o un recurso compartido montado en el sistema de ficheros
+type Source interface {
+	// List devuelve los ficheros disponibles. Según el origen solo se garantiza
+	// el nombre; Stat completa el resto de datos
+	List(ctx context.Context) ([]FileInfo, error)
+	Stat(ctx context.Context, name string) (FileInfo, error)
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	// Location devuelve la URL o ruta completa de un fichero, para logs e informes
+	Location(name string) string
+}
+
+// New crea el origen adecuado para base_url: http(s)://, file:// o una ruta local.
+// Los ficheros de un origen HTTP se resuelven bajo la URL base, a la que se
+// añade la barra final si falta. timeout solo se aplica a los orígenes HTTP
+func New(location string, timeout time.Duration) (Source, error) {
+	kind, target, err := parse(location)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case "http":
+		return NewHTTP(target, timeout), nil
+	default:
+		return NewDir(target), nil
+	}
+}
+
+// Validate comprueba que base_url corresponde a un origen soportado
+func Validate(location string) error {
+	_, _, err := parse(location)
+	return err
+}
+
+func parse(location string) (string, string, error) {
+	if location == "" {
+		return "", "", fmt.Errorf("origen vacío")
+	}
+
+	// Rutas locales sin esquema, incluidas las de Windows (C:\...)
+	if !strings.Contains(location, "://") {
+		return "dir", filepath.Clean(location), nil
+	}
+
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", "", fmt.Errorf("URL inválida (%w)", err)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		if u.Host == "" {
+			return "", "", fmt.Errorf("falta el host en %q", location)
+		}
+		if u.RawQuery != "" || u.Fragment != "" {
+			return "", "", fmt.Errorf("la URL base no admite consulta ni fragmento (%q)", location)
+		}
+		if !strings.HasSuffix(u.Path, "/") {
+			u.Path += "/"
+			u.RawPath = ""
+		}
+		return "http", u.String(), nil
+	case "file":
+		filePath := u.Path
+		switch {
+		// file://C:/ruta: la letra de unidad queda como host
+		case isDrive(u.Host):
+			filePath = u.Host + filePath
+		case u.Host != "" && u.Host != "localhost":
+			return "", "", fmt.Errorf("host no soportado en %q (solo ficheros locales)", location)
+		// file:///C:/ruta: la ruta de Windows lleva una barra delante de la unidad
+		case len(filePath) >= 3 && filePath[0] == '/' && isDrive(filePath[1:3]):
+			filePath = filePath[1:]
+		}
+		if filePath == "" {
+			return "", "", fmt.Errorf("falta la ruta en %q", location)
+		}
+		return "dir", filepath.FromSlash(filePath), nil
+	default:
+		return "", "", fmt.Errorf("esquema no soportado en %q (se espera http, https o file)", location)
+	}
+}
+
+// isDrive indica si value es una letra de unidad de Windows (C:)
+func isDrive(value string) bool {
+	if len(value) != 2 || value[1] != ':' {
+		return false
+	}
+	letter := value[0] | 0x20
+	return letter >= 'a' && letter <= 'z'
+}
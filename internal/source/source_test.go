@@ -0,0 +1,115 @@
+package source
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		location string
+		kind     string
+		target   string
+	}{
+		{location: "https://www.bne.es/SuministroRegistros/Bibliograficos/", kind: "http",
+			target: "https://www.bne.es/SuministroRegistros/Bibliograficos/"},
+		// Sin barra final los ficheros quedarían fuera del directorio
+		{location: "https://www.bne.es/SuministroRegistros/Bibliograficos", kind: "http",
+			target: "https://www.bne.es/SuministroRegistros/Bibliograficos/"},
+		{location: "http://replica.example.org", kind: "http", target: "http://replica.example.org/"},
+		{location: "file:///srv/mrc", kind: "dir", target: filepath.FromSlash("/srv/mrc")},
+		{location: "file://localhost/srv/mrc", kind: "dir", target: filepath.FromSlash("/srv/mrc")},
+		{location: "file:///srv/volcados%20BNE", kind: "dir", target: filepath.FromSlash("/srv/volcados BNE")},
+		// Unidades de Windows, con y sin barra delante
+		{location: "file:///C:/BNE/mrc", kind: "dir", target: filepath.FromSlash("C:/BNE/mrc")},
+		{location: "file://d:/BNE", kind: "dir", target: filepath.FromSlash("d:/BNE")},
+		{location: "file://localhost/C:/BNE", kind: "dir", target: filepath.FromSlash("C:/BNE")},
+		{location: "./mrc/replica", kind: "dir", target: filepath.Clean("./mrc/replica")},
+	}
+
+	for _, test := range tests {
+		kind, target, err := parse(test.location)
+		if err != nil {
+			t.Errorf("parse(%q): %v", test.location, err)
+			continue
+		}
+		if kind != test.kind || target != test.target {
+			t.Errorf("parse(%q) = %s %q, se esperaba %s %q", test.location, kind, target, test.kind, test.target)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, location := range []string{
+		"",
+		"ftp://www.bne.es/registros/",
+		"https:///registros/",
+		"https://www.bne.es/registros/?lista=1",
+		"file://servidor/compartido/mrc",
+		"file://",
+	} {
+		if _, _, err := parse(location); err == nil {
+			t.Errorf("parse(%q) sin error", location)
+		}
+	}
+}
+
+func TestDir(t *testing.T) {
+	root := t.TempDir()
+	modified := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	if err := os.WriteFile(filepath.Join(root, "MONOMODERN-mrc_new.mrc"), []byte("registros"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(root, "MONOMODERN-mrc_new.mrc"), modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "antiguos"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := New("file://"+filepath.ToSlash(root), time.Second)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ctx := context.Background()
+
+	// Los directorios no se listan
+	files, err := src.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(files) != 1 || files[0].Name != "MONOMODERN-mrc_new.mrc" || files[0].Size != 9 {
+		t.Fatalf("List = %+v", files)
+	}
+
+	info, err := src.Stat(ctx, "MONOMODERN-mrc_new.mrc")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if !info.LastModified.Equal(modified) {
+		t.Errorf("LastModified = %s, se esperaba %s", info.LastModified, modified)
+	}
+	if _, err := src.Stat(ctx, "antiguos"); err == nil {
+		t.Error("Stat de un directorio sin error")
+	}
+	if _, err := src.Stat(ctx, "VIDEO-mrc_new.mrc"); err == nil {
+		t.Error("Stat de un fichero inexistente sin error")
+	}
+
+	reader, err := src.Open(ctx, "MONOMODERN-mrc_new.mrc")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer reader.Close()
+	if content, _ := io.ReadAll(reader); string(content) != "registros" {
+		t.Errorf("contenido %q", content)
+	}
+
+	if want := filepath.Join(root, "VIDEO-mrc_new.mrc"); src.Location("VIDEO-mrc_new.mrc") != want {
+		t.Errorf("Location = %q, se esperaba %q", src.Location("VIDEO-mrc_new.mrc"), want)
+	}
+}
@@ -0,0 +1,224 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fsoria-ttec/bne-converter/internal/constants"
+	"github.com/fsoria-ttec/bne-converter/internal/metadata"
+	"github.com/fsoria-ttec/bne-converter/internal/report"
+)
+
+// Códigos de salida compatibles con Nagios
+const (
+	CodeOK       = 0
+	CodeWarning  = 1
+	CodeCritical = 2
+	CodeUnknown  = 3
+)
+
+var stateNames = map[int]string{
+	CodeOK:       "OK",
+	CodeWarning:  "WARNING",
+	CodeCritical: "CRITICAL",
+	CodeUnknown:  "UNKNOWN",
+}
+
+// RemoteChecker consulta la fecha de modificación de los ficheros publicados
+type RemoteChecker interface {
+	RemoteLastModified(ctx context.Context, category string) (time.Time, error)
+}
+
+type CategoryStatus struct {
+	Category           string    `json:"category"`
+	Description        string    `json:"description"`
+	State              string    `json:"state"`
+	Code               int       `json:"code"`
+	Reason             string    `json:"reason,omitempty"`
+	RemoteLastModified time.Time `json:"remote_last_modified"`
+	RemoteError        string    `json:"remote_error,omitempty"`
+	LocalLastModified  time.Time `json:"local_last_modified"`
+	Size               int64     `json:"size"`
+	Checksum           string    `json:"checksum"`
+	RecordCount        int       `json:"record_count"`
+	LastResult         string    `json:"last_result"`
+	LastError          string    `json:"last_error,omitempty"`
+	LastProcessed      time.Time `json:"last_processed"`
+	LastPublished      time.Time `json:"last_published"`
+}
+
+type Summary struct {
+	State      string           `json:"state"`
+	Code       int              `json:"code"`
+	LastRun    *report.Run      `json:"last_run,omitempty"`
+	Categories []CategoryStatus `json:"categories"`
+}
+
+// Collect reúne el estado de las categorías indicadas a partir de los metadatos,
+// el informe de la última ejecución y, si remote no es nil, la web de la BNE
+func Collect(ctx context.Context, categories []constants.Category, remote RemoteChecker,
+	files *metadata.MetadataStore, reports *report.Store) Summary {
+	summary := Summary{
+		LastRun:    reports.LastRun(),
+		Categories: make([]CategoryStatus, len(categories)),
+	}
+
+	var wg sync.WaitGroup
+	for i, category := range categories {
+		status := CategoryStatus{
+			Category:    category.Id,
+			Description: category.Description,
+		}
+
+		if file, exists := files.Get(category.Id); exists {
+			status.LocalLastModified = file.LastModified
+			status.Size = file.Size
+			status.Checksum = file.Checksum
+		}
+
+		if result, exists := reports.Category(category.Id); exists {
+			status.RecordCount = result.RecordCount
+			status.LastResult = result.Result
+			status.LastError = result.Error
+			status.LastProcessed = result.ProcessedAt
+			status.LastPublished = result.PublishedAt
+		}
+
+		summary.Categories[i] = status
+
+		if remote == nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func(status *CategoryStatus) {
+			defer wg.Done()
+			lastModified, err := remote.RemoteLastModified(ctx, status.Category)
+			if err != nil {
+				status.RemoteError = err.Error()
+				return
+			}
+			status.RemoteLastModified = lastModified
+		}(&summary.Categories[i])
+	}
+	wg.Wait()
+
+	for i := range summary.Categories {
+		status := &summary.Categories[i]
+		status.Code, status.Reason = evaluate(status)
+		status.State = stateNames[status.Code]
+
+		if status.Code > summary.Code {
+			summary.Code = status.Code
+		}
+	}
+	summary.State = stateNames[summary.Code]
+
+	return summary
+}
+
+func evaluate(status *CategoryStatus) (int, string) {
+	switch {
+	case status.LastResult == report.ResultError:
+		return CodeCritical, "último procesamiento fallido"
+	case status.LocalLastModified.IsZero():
+		return CodeWarning, "sin descargar"
+	case status.RemoteError != "":
+		return CodeWarning, "error consultando la BNE"
+	case status.RemoteLastModified.After(status.LocalLastModified):
+		return CodeWarning, "actualización pendiente"
+	}
+	return CodeOK, ""
+}
+
+func WriteJSON(w io.Writer, summary Summary) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(summary)
+}
+
+func WriteTable(w io.Writer, summary Summary, timestampFormat string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "CATEGORÍA\tESTADO\tREMOTO\tLOCAL\tTAMAÑO\tCHECKSUM\tREGISTROS\tRESULTADO\tCKAN\tMOTIVO")
+	for _, status := range summary.Categories {
+		remote := formatTime(status.RemoteLastModified, timestampFormat)
+		if status.RemoteError != "" {
+			remote = "error"
+		}
+
+		checksum := status.Checksum
+		if len(checksum) > 12 {
+			checksum = checksum[:12]
+		}
+
+		result := status.LastResult
+		if result == "" {
+			result = "-"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			status.Category,
+			status.State,
+			remote,
+			formatTime(status.LocalLastModified, timestampFormat),
+			formatSize(status.Size),
+			orDash(checksum),
+			status.RecordCount,
+			result,
+			formatTime(status.LastPublished, timestampFormat),
+			orDash(status.Reason),
+		)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if summary.LastRun != nil {
+		fmt.Fprintf(w, "\nÚltima ejecución: %s (%s) %s - %s\n",
+			summary.LastRun.ID,
+			summary.LastRun.Mode,
+			formatTime(summary.LastRun.StartedAt, timestampFormat),
+			formatTime(summary.LastRun.FinishedAt, timestampFormat),
+		)
+	}
+	_, err := fmt.Fprintf(w, "Estado: %s\n", summary.State)
+	return err
+}
+
+func formatTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(layout)
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size <= 0 {
+		return "-"
+	}
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
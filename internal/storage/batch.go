@@ -0,0 +1,544 @@
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/fsoria-ttec/bne-converter/internal/constants"
+	"github.com/fsoria-ttec/bne-converter/pkg/models"
+	"github.com/lib/pq" // arrays de PostgreSQL
+)
+
+// ErrInvalidRecord indica un registro que no se puede guardar; la carga continúa
+var ErrInvalidRecord = errors.New("registro no válido para su almacenamiento")
+
+// ErrValidation indica una carga que no supera las comprobaciones previas a
+// confirmarla; los datos anteriores de la categoría quedan intactos
+var ErrValidation = errors.New("la carga no supera la validación")
+
+// ErrDatestamp indica una carga confirmada cuyos registros conservan como
+// datestamp el inicio de la transacción; la recolección selectiva puede
+// omitirlos
+var ErrDatestamp = errors.New("carga confirmada sin sellar sus registros")
+
+// Versión de las columnas y tablas que se derivan del registro MARC (fechas,
+// idiomas, encabezamientos, identificadores, ISBN/ISSN, búsqueda). Hay que
+// incrementarla al cambiar cómo se derivan: los registros sin cambios
+// guardados con una versión anterior se derivan de nuevo en la siguiente carga
+const derivationVersion = 1
+
+const (
+	// Al derivar de nuevo un registro sin cambios (último parámetro a true) se
+	// conserva su versión vigente: no cambian sus fechas ni su ejecución
+	upsertBibliographic = `INSERT INTO bibliographic_records
+		(control_number, category, leader, title, author, date_start, date_end,
+			date_precision, date_type, language, country, record, search_vector, content_hash,
+			run_id, updated_at, datestamp, derivation_version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, bne_search_vector($12), $13, $14,
+			now(), now(), $15)
+		ON CONFLICT (control_number) DO UPDATE SET
+			category = EXCLUDED.category,
+			leader = EXCLUDED.leader,
+			title = EXCLUDED.title,
+			author = EXCLUDED.author,
+			date_start = EXCLUDED.date_start,
+			date_end = EXCLUDED.date_end,
+			date_precision = EXCLUDED.date_precision,
+			date_type = EXCLUDED.date_type,
+			language = EXCLUDED.language,
+			country = EXCLUDED.country,
+			record = EXCLUDED.record,
+			search_vector = EXCLUDED.search_vector,
+			content_hash = EXCLUDED.content_hash,
+			derivation_version = EXCLUDED.derivation_version,
+			run_id = CASE WHEN $16 THEN bibliographic_records.run_id ELSE EXCLUDED.run_id END,
+			updated_at = CASE WHEN $16 THEN bibliographic_records.updated_at ELSE EXCLUDED.updated_at END,
+			datestamp = CASE WHEN $16 THEN bibliographic_records.datestamp ELSE EXCLUDED.datestamp END,
+			deleted_at = NULL`
+
+	upsertAuthority = `INSERT INTO authority_records
+		(control_number, category, leader, authority_type, heading, heading_key, record,
+			content_hash, run_id, updated_at, datestamp, derivation_version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, now(), now(), $10)
+		ON CONFLICT (control_number) DO UPDATE SET
+			category = EXCLUDED.category,
+			leader = EXCLUDED.leader,
+			authority_type = EXCLUDED.authority_type,
+			heading = EXCLUDED.heading,
+			heading_key = EXCLUDED.heading_key,
+			record = EXCLUDED.record,
+			content_hash = EXCLUDED.content_hash,
+			derivation_version = EXCLUDED.derivation_version,
+			run_id = CASE WHEN $11 THEN authority_records.run_id ELSE EXCLUDED.run_id END,
+			updated_at = CASE WHEN $11 THEN authority_records.updated_at ELSE EXCLUDED.updated_at END,
+			datestamp = CASE WHEN $11 THEN authority_records.datestamp ELSE EXCLUDED.datestamp END,
+			deleted_at = NULL`
+
+	// Huellas de los registros vigentes de la categoría en ambas tablas
+	selectHashes = `SELECT false, control_number, content_hash, derivation_version FROM bibliographic_records
+			WHERE category = $1 AND deleted_at IS NULL
+		UNION ALL
+		SELECT true, control_number, content_hash, derivation_version FROM authority_records
+			WHERE category = $1 AND deleted_at IS NULL`
+
+	// La versión eliminada pasa al histórico y el registro queda como marca
+	// de eliminación vigente desde ese momento
+	tombstoneBibliographic = `UPDATE bibliographic_records
+		SET deleted_at = now(), updated_at = now(), datestamp = now(), run_id = $3
+		WHERE category = $1 AND control_number = ANY($2) AND deleted_at IS NULL`
+
+	countLive = `SELECT
+		(SELECT count(*) FROM bibliographic_records WHERE category = $1 AND deleted_at IS NULL) +
+		(SELECT count(*) FROM authority_records WHERE category = $1 AND deleted_at IS NULL)`
+
+	tombstoneAuthority = `UPDATE authority_records
+		SET deleted_at = now(), updated_at = now(), datestamp = now(), run_id = $3
+		WHERE category = $1 AND control_number = ANY($2) AND deleted_at IS NULL`
+
+	// Tras confirmar la carga, los registros que escribió pasan a tener como
+	// datestamp un momento posterior a la confirmación: un recolector que los
+	// vea después no puede haber recibido ya una fecha de respuesta mayor
+	stampBibliographic = `UPDATE bibliographic_records SET datestamp = now()
+		WHERE category = $1 AND run_id = $2`
+
+	stampAuthority = `UPDATE authority_records SET datestamp = now()
+		WHERE category = $1 AND run_id = $2`
+
+	// Copia al histórico la versión almacenada antes de sustituirla
+	archiveBibliographic = `INSERT INTO record_history
+		(record_family, control_number, category, record, content_hash, deleted, valid_from, valid_to, run_id)
+		SELECT $3, control_number, category, record, content_hash, deleted_at IS NOT NULL, updated_at, now(), $2
+		FROM bibliographic_records WHERE control_number = ANY($1)`
+
+	archiveAuthority = `INSERT INTO record_history
+		(record_family, control_number, category, record, content_hash, deleted, valid_from, valid_to, run_id)
+		SELECT $3, control_number, category, record, content_hash, deleted_at IS NOT NULL, updated_at, now(), $2
+		FROM authority_records WHERE control_number = ANY($1)`
+
+	deleteAuthorityHeadings = `DELETE FROM authority_headings WHERE control_number = $1`
+
+	insertAuthorityHeading = `INSERT INTO authority_headings
+		(control_number, tag, authority_type, heading, heading_key)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	deleteBibliographicHeadings = `DELETE FROM bibliographic_headings WHERE control_number = $1`
+
+	insertBibliographicHeading = `INSERT INTO bibliographic_headings
+		(control_number, tag, position, authority_type, heading, heading_key, authority_ref)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	deleteIdentifiers = `DELETE FROM external_identifiers WHERE record_family = $1 AND control_number = $2`
+
+	insertIdentifier = `INSERT INTO external_identifiers
+		(record_family, control_number, scheme, value, tag)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	deleteStandardNumbers = `DELETE FROM standard_numbers WHERE control_number = $1`
+
+	insertStandardNumber = `INSERT INTO standard_numbers
+		(control_number, number_type, value, isbn10, qualifier, tag, subfield, valid)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+)
+
+// Batch agrupa en una transacción los registros del fichero de una
+// categoría, escritos directamente en las tablas definitivas. Las consultas
+// externas siguen viendo los datos anteriores hasta que la carga se valida
+// (recuentos de Commit) y se confirma de una vez. Solo se escriben los registros nuevos o cuyo
+// contenido ha cambiado; con los cambios respecto a la versión anterior del
+// fichero (Expect), solo se comparan los que figuran en ellos
+type Batch struct {
+	ctx        context.Context
+	db         *sql.DB
+	tx         *sql.Tx
+	runID      string
+	category   string
+	statements map[string]*sql.Stmt
+	hashes     map[recordKey]storedRecord // registros vigentes aún no vistos en el fichero
+	seen       map[recordKey]bool         // registros del fichero guardados o sin cambios
+	changed    map[string]bool            // añadidos o modificados según Expect; nil sin cambios
+	deleted    map[string]bool            // eliminados según Expect
+	stats      LoadStats
+}
+
+type recordKey struct {
+	authority     bool
+	controlNumber string
+}
+
+type storedRecord struct {
+	hash       string
+	derivation int
+}
+
+// LoadStats resume la carga incremental de una categoría
+type LoadStats struct {
+	Added     int
+	Updated   int
+	Unchanged int
+	Deleted   int
+	// Números de control repetidos en el fichero; prevalece el primero
+	Duplicates int
+	// Registros en que la base de datos no coincide con los cambios esperados
+	Diverged int
+}
+
+func (s *Store) Begin(ctx context.Context, runID, category string) (*Batch, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error iniciando transacción (%w)", err)
+	}
+
+	batch := &Batch{
+		ctx:        ctx,
+		db:         s.db,
+		tx:         tx,
+		runID:      runID,
+		category:   category,
+		statements: make(map[string]*sql.Stmt),
+		hashes:     make(map[recordKey]storedRecord),
+		seen:       make(map[recordKey]bool),
+	}
+
+	if err := batch.loadHashes(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	for _, query := range []string{upsertBibliographic, upsertAuthority, archiveBibliographic, archiveAuthority,
+		deleteAuthorityHeadings, insertAuthorityHeading, deleteBibliographicHeadings, insertBibliographicHeading,
+		deleteIdentifiers, insertIdentifier, deleteStandardNumbers, insertStandardNumber} {
+		statement, err := tx.PrepareContext(ctx, query)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("error preparando consulta (%w)", err)
+		}
+		batch.statements[query] = statement
+	}
+
+	return batch, nil
+}
+
+// Expect indica los números de control añadidos, modificados y eliminados
+// respecto a la versión anterior del fichero, cuando es la que está cargada.
+// Los registros que no figuran en ellos se dan por sin cambios si tienen una
+// versión almacenada, sin serializarlos ni compararlos. Los que no coinciden
+// con la base de datos se guardan o eliminan según esta y se cuentan como
+// divergentes
+func (b *Batch) Expect(added, modified, deleted []string) {
+	b.changed = make(map[string]bool, len(added)+len(modified))
+	b.deleted = make(map[string]bool, len(deleted))
+	for _, controlNumbers := range [][]string{added, modified} {
+		for _, controlNumber := range controlNumbers {
+			b.changed[controlNumber] = true
+		}
+	}
+	for _, controlNumber := range deleted {
+		b.deleted[controlNumber] = true
+	}
+}
+
+func (b *Batch) loadHashes() error {
+	rows, err := b.tx.QueryContext(b.ctx, selectHashes, b.category)
+	if err != nil {
+		return fmt.Errorf("error consultando registros vigentes (%w)", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key recordKey
+		var stored storedRecord
+		if err := rows.Scan(&key.authority, &key.controlNumber, &stored.hash, &stored.derivation); err != nil {
+			return fmt.Errorf("error consultando registros vigentes (%w)", err)
+		}
+		b.hashes[key] = stored
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error consultando registros vigentes (%w)", err)
+	}
+	return nil
+}
+
+// Save guarda el registro en las tablas de su familia según la cabecera,
+// salvo que su contenido coincida con el ya almacenado; si coincide pero se
+// derivó con una versión anterior, solo se derivan de nuevo sus datos. Un
+// registro que no se puede guardar no cuenta como visto: si tenía una versión
+// almacenada, esta se marca como eliminada al confirmar. Un número de control
+// repetido en el fichero solo cuenta como duplicado y se conserva la primera
+// aparición
+func (b *Batch) Save(record *models.Record) error {
+	if record.ControlNumber() == "" {
+		return fmt.Errorf("%w: sin número de control (001)", ErrInvalidRecord)
+	}
+
+	key := recordKey{authority: record.IsAuthority(), controlNumber: record.ControlNumber()}
+	if b.seen[key] {
+		b.stats.Duplicates++
+		return nil
+	}
+
+	previous, exists := b.hashes[key]
+	if b.changed != nil && !b.changed[key.controlNumber] {
+		if exists && previous.derivation == derivationVersion {
+			delete(b.hashes, key)
+			b.seen[key] = true
+			b.stats.Unchanged++
+			return nil
+		}
+		if !exists {
+			b.stats.Diverged++
+		}
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("error serializando registro %s (%w)", record.ControlNumber(), err)
+	}
+	sum := md5.Sum(data)
+	hash := hex.EncodeToString(sum[:])
+
+	unchanged := exists && previous.hash == hash
+	if unchanged && b.changed[key.controlNumber] {
+		b.stats.Diverged++
+	}
+	if unchanged && previous.derivation == derivationVersion {
+		delete(b.hashes, key)
+		b.seen[key] = true
+		b.stats.Unchanged++
+		return nil
+	}
+
+	var authority *models.Authority
+	if key.authority {
+		if authority, err = models.NewAuthority(b.category, record); err != nil {
+			return fmt.Errorf("%w: %s (%v)", ErrInvalidRecord, record.ControlNumber(), err)
+		}
+	}
+	if !unchanged {
+		if err := b.archive(key.authority, []string{key.controlNumber}); err != nil {
+			return err
+		}
+	}
+	if key.authority {
+		err = b.saveAuthority(authority, data, hash, unchanged)
+	} else {
+		err = b.saveBibliographic(models.NewBibliographic(b.category, record), data, hash, unchanged)
+	}
+	if err != nil {
+		return err
+	}
+
+	delete(b.hashes, key)
+	b.seen[key] = true
+	if unchanged {
+		b.stats.Unchanged++
+	} else if exists {
+		b.stats.Updated++
+	} else {
+		b.stats.Added++
+	}
+	return nil
+}
+
+// Commit marca como eliminados, con tombstone, los registros vigentes que no
+// figuraban en el fichero, valida la carga frente al número de registros
+// leídos del fichero (parsed) y la confirma. Si algo falla se deshace por
+// completo y los datos anteriores de la categoría quedan intactos. Si la carga
+// se confirma pero no se pueden sellar sus registros devuelve ErrDatestamp
+func (b *Batch) Commit(tombstone bool, parsed int) (LoadStats, error) {
+	if err := b.commit(tombstone, parsed); err != nil {
+		b.tx.Rollback()
+		return b.stats, err
+	}
+	return b.stats, b.stamp()
+}
+
+func (b *Batch) commit(tombstone bool, parsed int) error {
+	if tombstone {
+		var bibliographic, authority []string
+		for key := range b.hashes {
+			if b.deleted != nil && !b.deleted[key.controlNumber] {
+				b.stats.Diverged++
+			}
+			if key.authority {
+				authority = append(authority, key.controlNumber)
+			} else {
+				bibliographic = append(bibliographic, key.controlNumber)
+			}
+		}
+
+		for query, controlNumbers := range map[string][]string{
+			tombstoneBibliographic: bibliographic,
+			tombstoneAuthority:     authority,
+		} {
+			if len(controlNumbers) == 0 {
+				continue
+			}
+			if err := b.archive(query == tombstoneAuthority, controlNumbers); err != nil {
+				return err
+			}
+			if _, err := b.tx.ExecContext(b.ctx, query, b.category, pq.Array(controlNumbers), b.runID); err != nil {
+				return fmt.Errorf("error marcando registros eliminados (%w)", err)
+			}
+		}
+		b.stats.Deleted = len(b.hashes)
+	}
+
+	if err := b.validate(tombstone, parsed); err != nil {
+		return err
+	}
+
+	if err := b.tx.Commit(); err != nil {
+		return fmt.Errorf("error confirmando transacción (%w)", err)
+	}
+	return nil
+}
+
+// stamp sella con la hora actual, en una transacción breve posterior a la
+// carga, los registros escritos o eliminados por la ejecución
+func (b *Batch) stamp() error {
+	tx, err := b.db.BeginTx(b.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrDatestamp, err)
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{stampBibliographic, stampAuthority} {
+		if _, err := tx.ExecContext(b.ctx, query, b.category, b.runID); err != nil {
+			return fmt.Errorf("%w (%v)", ErrDatestamp, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w (%v)", ErrDatestamp, err)
+	}
+	return nil
+}
+
+// validate comprueba que se han guardado todos los registros leídos y que los
+// registros vigentes de la categoría son los del fichero. Sin marcas de
+// eliminación pueden quedar además registros de cargas anteriores
+func (b *Batch) validate(tombstone bool, parsed int) error {
+	stored := b.stats.Added + b.stats.Updated + b.stats.Unchanged + b.stats.Duplicates
+	if stored != parsed {
+		return fmt.Errorf("%w: %d registros leídos y %d guardados", ErrValidation, parsed, stored)
+	}
+
+	var live int
+	if err := b.tx.QueryRowContext(b.ctx, countLive, b.category).Scan(&live); err != nil {
+		return fmt.Errorf("error contando registros vigentes (%w)", err)
+	}
+	if live < len(b.seen) || (tombstone && live != len(b.seen)) {
+		return fmt.Errorf("%w: %d registros distintos en el fichero y %d vigentes en la base de datos",
+			ErrValidation, len(b.seen), live)
+	}
+	return nil
+}
+
+// archive copia al histórico la versión almacenada de los registros, si existe
+func (b *Batch) archive(authority bool, controlNumbers []string) error {
+	query, family := archiveBibliographic, constants.FamilyBibliographic
+	if authority {
+		query, family = archiveAuthority, constants.FamilyAuthority
+	}
+	if _, err := b.statements[query].ExecContext(b.ctx, pq.Array(controlNumbers), b.runID, family); err != nil {
+		return fmt.Errorf("error guardando versión anterior (%w)", err)
+	}
+	return nil
+}
+
+func (b *Batch) Rollback() error {
+	return b.tx.Rollback()
+}
+
+// saveBibliographic guarda el registro y sus datos derivados; con rederive
+// solo cambian estos últimos
+func (b *Batch) saveBibliographic(bibliographic *models.Bibliographic, record []byte, hash string, rederive bool) error {
+	var dateStart, dateEnd sql.NullInt64
+	precision, dateType := models.DatePrecisionUnknown, ""
+	if date := bibliographic.Date; date != nil {
+		dateStart = sql.NullInt64{Int64: int64(date.Start), Valid: date.Start != 0}
+		dateEnd = sql.NullInt64{Int64: int64(date.End), Valid: date.End != 0}
+		precision, dateType = date.Precision, date.Type
+	}
+
+	var language, country string
+	if len(bibliographic.Languages) > 0 {
+		language = bibliographic.Languages[0].Code
+	}
+	if bibliographic.Country != nil {
+		country = bibliographic.Country.Code
+	}
+
+	_, err := b.statements[upsertBibliographic].ExecContext(b.ctx,
+		bibliographic.ControlNumber, bibliographic.Category, bibliographic.Record.Leader,
+		bibliographic.Title, bibliographic.Author, dateStart, dateEnd, precision, dateType,
+		language, country, record, hash, b.runID, derivationVersion, rederive)
+	if err != nil {
+		return fmt.Errorf("error guardando registro bibliográfico %s (%w)", bibliographic.ControlNumber, err)
+	}
+
+	// Los enlaces se recalculan en la etapa de enlace tras la carga
+	if _, err := b.statements[deleteBibliographicHeadings].ExecContext(b.ctx, bibliographic.ControlNumber); err != nil {
+		return fmt.Errorf("error actualizando encabezamientos de %s (%w)", bibliographic.ControlNumber, err)
+	}
+	for _, heading := range bibliographic.Headings {
+		if _, err := b.statements[insertBibliographicHeading].ExecContext(b.ctx,
+			bibliographic.ControlNumber, heading.Tag, heading.Position, heading.Type,
+			heading.Heading, heading.Key, heading.AuthorityRef); err != nil {
+			return fmt.Errorf("error guardando encabezamiento de %s (%w)", bibliographic.ControlNumber, err)
+		}
+	}
+
+	if _, err := b.statements[deleteStandardNumbers].ExecContext(b.ctx, bibliographic.ControlNumber); err != nil {
+		return fmt.Errorf("error actualizando ISBN/ISSN de %s (%w)", bibliographic.ControlNumber, err)
+	}
+	for _, number := range bibliographic.Numbers {
+		if _, err := b.statements[insertStandardNumber].ExecContext(b.ctx,
+			bibliographic.ControlNumber, number.Type, number.Value, number.ISBN10,
+			number.Qualifier, number.Tag, number.Subfield, number.Valid); err != nil {
+			return fmt.Errorf("error guardando ISBN/ISSN de %s (%w)", bibliographic.ControlNumber, err)
+		}
+	}
+
+	return b.saveIdentifiers(constants.FamilyBibliographic, bibliographic.ControlNumber, bibliographic.Identifiers)
+}
+
+func (b *Batch) saveAuthority(authority *models.Authority, record []byte, hash string, rederive bool) error {
+	_, err := b.statements[upsertAuthority].ExecContext(b.ctx,
+		authority.ControlNumber, authority.Category, authority.Record.Leader,
+		authority.Type, authority.Heading, authority.HeadingKey, record, hash, b.runID,
+		derivationVersion, rederive)
+	if err != nil {
+		return fmt.Errorf("error guardando registro de autoridad %s (%w)", authority.ControlNumber, err)
+	}
+
+	// Los encabezamientos se sustituyen por completo en cada carga
+	if _, err := b.statements[deleteAuthorityHeadings].ExecContext(b.ctx, authority.ControlNumber); err != nil {
+		return fmt.Errorf("error actualizando encabezamientos de %s (%w)", authority.ControlNumber, err)
+	}
+	headings := append(append([]models.Heading{}, authority.Variants...), authority.Related...)
+	for _, heading := range headings {
+		if _, err := b.statements[insertAuthorityHeading].ExecContext(b.ctx,
+			authority.ControlNumber, heading.Tag, heading.Type, heading.Value, heading.Key); err != nil {
+			return fmt.Errorf("error guardando encabezamiento de %s (%w)", authority.ControlNumber, err)
+		}
+	}
+	return b.saveIdentifiers(constants.FamilyAuthority, authority.ControlNumber, authority.Identifiers)
+}
+
+// saveIdentifiers sustituye los identificadores externos del registro
+func (b *Batch) saveIdentifiers(family, controlNumber string, identifiers []models.Identifier) error {
+	if _, err := b.statements[deleteIdentifiers].ExecContext(b.ctx, family, controlNumber); err != nil {
+		return fmt.Errorf("error actualizando identificadores de %s (%w)", controlNumber, err)
+	}
+	for _, identifier := range identifiers {
+		if _, err := b.statements[insertIdentifier].ExecContext(b.ctx,
+			family, controlNumber, identifier.Scheme, identifier.Value, identifier.Tag); err != nil {
+			return fmt.Errorf("error guardando identificador de %s (%w)", controlNumber, err)
+		}
+	}
+	return nil
+}
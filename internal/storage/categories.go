@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Registros vigentes y eliminados por categoría y último cambio en cada una
+const selectCategoryCounts = `SELECT category,
+		count(*) FILTER (WHERE deleted_at IS NULL),
+		count(*) FILTER (WHERE deleted_at IS NOT NULL),
+		max(updated_at)
+	FROM (
+		SELECT category, deleted_at, updated_at FROM bibliographic_records
+		UNION ALL
+		SELECT category, deleted_at, updated_at FROM authority_records
+	) records
+	GROUP BY category`
+
+// CategoryCount resume los registros almacenados de una categoría
+type CategoryCount struct {
+	Category  string    `json:"category"`
+	Records   int       `json:"records"`
+	Deleted   int       `json:"deleted"`
+	UpdatedAt time.Time `json:"updated_at"` // último alta, cambio o eliminación
+}
+
+// CategoryCounts devuelve el resumen de las categorías con registros almacenados
+func (s *Store) CategoryCounts(ctx context.Context) (map[string]CategoryCount, error) {
+	rows, err := s.db.QueryContext(ctx, selectCategoryCounts)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando categorías (%w)", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]CategoryCount)
+	for rows.Next() {
+		var count CategoryCount
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&count.Category, &count.Records, &count.Deleted, &updatedAt); err != nil {
+			return nil, fmt.Errorf("error leyendo categorías (%w)", err)
+		}
+		count.UpdatedAt = updatedAt.Time
+		counts[count.Category] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error leyendo categorías (%w)", err)
+	}
+	return counts, nil
+}
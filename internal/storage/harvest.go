@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/fsoria-ttec/bne-converter/internal/constants"
+)
+
+// Versiones vigentes de ambas tablas, incluidas las marcas de eliminación,
+// ordenadas por su datestamp. $6 y $7 son la posición del último registro de
+// la página anterior
+const selectHarvest = `SELECT family, control_number, category, record, deleted, valid_from, valid_to, run_id, datestamp
+	FROM (
+		SELECT $1::text AS family, control_number, category, record,
+			deleted_at IS NOT NULL AS deleted, updated_at AS valid_from,
+			NULL::timestamptz AS valid_to, run_id, datestamp
+		FROM bibliographic_records
+		UNION ALL
+		SELECT $2::text, control_number, category, record,
+			deleted_at IS NOT NULL, updated_at, NULL::timestamptz, run_id, datestamp
+		FROM authority_records
+	) current
+	WHERE ($3 = '' OR category = $3)
+		AND ($4::timestamptz IS NULL OR datestamp >= $4)
+		AND ($5::timestamptz IS NULL OR datestamp < $5)
+		AND ($6::timestamptz IS NULL OR (datestamp, control_number) > ($6, $7::text))
+	ORDER BY datestamp, control_number
+	LIMIT $8`
+
+const selectEarliestDatestamp = `SELECT least(
+		(SELECT min(datestamp) FROM bibliographic_records),
+		(SELECT min(datestamp) FROM authority_records))`
+
+// HarvestQuery selecciona versiones vigentes por categoría y datestamp. Las
+// fechas a cero no filtran; Until es exclusiva
+type HarvestQuery struct {
+	Category string
+	From     time.Time
+	Until    time.Time
+	// Posición del último registro devuelto en la página anterior
+	AfterDatestamp     time.Time
+	AfterControlNumber string
+	Limit              int
+}
+
+// Harvest devuelve las versiones vigentes, eliminadas o no, que cumplen la
+// consulta, de la más antigua a la más reciente
+func (s *Store) Harvest(ctx context.Context, query HarvestQuery) ([]*RecordVersion, error) {
+	rows, err := s.db.QueryContext(ctx, selectHarvest,
+		constants.FamilyBibliographic, constants.FamilyAuthority, query.Category,
+		nullTime(query.From), nullTime(query.Until),
+		nullTime(query.AfterDatestamp), query.AfterControlNumber, query.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando registros para recolección (%w)", err)
+	}
+	versions, err := scanVersions(rows)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando registros para recolección (%w)", err)
+	}
+	return versions, nil
+}
+
+// EarliestDatestamp devuelve el datestamp de la versión vigente más antigua,
+// o false si no hay registros
+func (s *Store) EarliestDatestamp(ctx context.Context) (time.Time, bool, error) {
+	var earliest sql.NullTime
+	if err := s.db.QueryRowContext(ctx, selectEarliestDatestamp).Scan(&earliest); err != nil {
+		return time.Time{}, false, fmt.Errorf("error consultando fecha más antigua (%w)", err)
+	}
+	return earliest.Time, earliest.Valid, nil
+}
+
+func nullTime(value time.Time) sql.NullTime {
+	return sql.NullTime{Time: value, Valid: !value.IsZero()}
+}
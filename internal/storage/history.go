@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fsoria-ttec/bne-converter/internal/constants"
+	"github.com/fsoria-ttec/bne-converter/pkg/models"
+)
+
+// ErrNotFound indica un registro que no existía en la fecha consultada
+var ErrNotFound = errors.New("registro no encontrado")
+
+// Versiones vigentes de ambas tablas y versiones anteriores del histórico.
+// Las versiones anteriores no tienen datestamp propio: se usa su inicio
+const recordVersions = `SELECT family, control_number, category, record, deleted, valid_from, valid_to, run_id, datestamp
+	FROM (
+		SELECT $2::text AS family, control_number, category, record,
+			deleted_at IS NOT NULL AS deleted, updated_at AS valid_from,
+			NULL::timestamptz AS valid_to, run_id, datestamp
+		FROM bibliographic_records WHERE control_number = $1
+		UNION ALL
+		SELECT $3::text, control_number, category, record,
+			deleted_at IS NOT NULL, updated_at, NULL::timestamptz, run_id, datestamp
+		FROM authority_records WHERE control_number = $1
+		UNION ALL
+		SELECT record_family, control_number, category, record,
+			deleted, valid_from, valid_to, run_id, valid_from
+		FROM record_history WHERE control_number = $1
+	) versions`
+
+const (
+	selectRecordAsOf = recordVersions + `
+	WHERE valid_from <= $4 AND (valid_to IS NULL OR valid_to > $4)
+	ORDER BY valid_from DESC
+	LIMIT 1`
+
+	selectRecordHistory = recordVersions + `
+	ORDER BY valid_from, valid_to NULLS LAST`
+
+	selectCurrentRecord = recordVersions + `
+	WHERE valid_to IS NULL
+	ORDER BY family
+	LIMIT 1`
+)
+
+// RecordVersion es una versión de un registro y su intervalo de validez
+type RecordVersion struct {
+	Family        string         `json:"family"`
+	ControlNumber string         `json:"control_number"`
+	Category      string         `json:"category"`
+	Record        *models.Record `json:"record"`
+	Deleted       bool           `json:"deleted"` // marca de eliminación: el registro no figuraba en el fichero
+	ValidFrom     time.Time      `json:"valid_from"`
+	ValidTo       *time.Time     `json:"valid_to,omitempty"` // nil en la versión vigente
+	// Ejecución que escribió la versión vigente o que sustituyó a una anterior
+	RunID string `json:"run_id"`
+	// Confirmación de la versión vigente, posterior a ValidFrom; es la fecha
+	// de la recolección selectiva
+	Datestamp time.Time `json:"datestamp"`
+}
+
+// Record devuelve la versión vigente del registro, que puede ser una marca de
+// eliminación
+func (s *Store) Record(ctx context.Context, controlNumber string) (*RecordVersion, error) {
+	rows, err := s.db.QueryContext(ctx, selectCurrentRecord, controlNumber,
+		constants.FamilyBibliographic, constants.FamilyAuthority)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando registro %s (%w)", controlNumber, err)
+	}
+	versions, err := scanVersions(rows)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando registro %s (%w)", controlNumber, err)
+	}
+	if len(versions) == 0 {
+		return nil, ErrNotFound
+	}
+	return versions[0], nil
+}
+
+// RecordAsOf devuelve la versión del registro vigente en la fecha indicada.
+// Si en esa fecha el registro estaba eliminado se devuelve la marca de
+// eliminación, con Deleted a true
+func (s *Store) RecordAsOf(ctx context.Context, controlNumber string, at time.Time) (*RecordVersion, error) {
+	rows, err := s.db.QueryContext(ctx, selectRecordAsOf, controlNumber,
+		constants.FamilyBibliographic, constants.FamilyAuthority, at)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando registro %s (%w)", controlNumber, err)
+	}
+	versions, err := scanVersions(rows)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando registro %s (%w)", controlNumber, err)
+	}
+	if len(versions) == 0 {
+		return nil, ErrNotFound
+	}
+	return versions[0], nil
+}
+
+// RecordHistory devuelve todas las versiones del registro, de la más antigua
+// a la vigente
+func (s *Store) RecordHistory(ctx context.Context, controlNumber string) ([]*RecordVersion, error) {
+	rows, err := s.db.QueryContext(ctx, selectRecordHistory, controlNumber,
+		constants.FamilyBibliographic, constants.FamilyAuthority)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando histórico de %s (%w)", controlNumber, err)
+	}
+	versions, err := scanVersions(rows)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando histórico de %s (%w)", controlNumber, err)
+	}
+	if len(versions) == 0 {
+		return nil, ErrNotFound
+	}
+	return versions, nil
+}
+
+func scanVersions(rows *sql.Rows) ([]*RecordVersion, error) {
+	defer rows.Close()
+
+	var versions []*RecordVersion
+	for rows.Next() {
+		version := &RecordVersion{}
+		var record []byte
+		var validTo sql.NullTime
+		if err := rows.Scan(&version.Family, &version.ControlNumber, &version.Category, &record,
+			&version.Deleted, &version.ValidFrom, &validTo, &version.RunID, &version.Datestamp); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(record, &version.Record); err != nil {
+			return nil, err
+		}
+		if validTo.Valid {
+			version.ValidTo = &validTo.Time
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq" // arrays de PostgreSQL
+)
+
+// Métodos de enlace, por orden de prioridad
+const (
+	LinkSubfield0 = "subfield_0" // identificador explícito en $0
+	LinkHeading   = "heading"    // forma autorizada (1XX)
+	LinkVariant   = "variant"    // forma no aceptada (4XX)
+)
+
+// Número de encabezamientos sin enlazar que se incluyen en el resumen
+const unmatchedSample = 20
+
+const (
+	resetLinks = `UPDATE bibliographic_headings h
+		SET authority_id = NULL, link_method = NULL
+		FROM bibliographic_records b
+		WHERE b.control_number = h.control_number AND b.category = ANY($1)`
+
+	// $0 solo enlaza con registros de autoridad (cabecera/06 = z)
+	linkBySubfield0 = `UPDATE bibliographic_headings h
+		SET authority_id = a.control_number, link_method = 'subfield_0'
+		FROM bibliographic_records b, authority_records a
+		WHERE b.control_number = h.control_number AND b.category = ANY($1)
+			AND h.authority_ref <> '' AND a.control_number = h.authority_ref
+			AND a.deleted_at IS NULL AND substr(a.leader, 7, 1) = 'z'`
+
+	// Solo se enlazan formas que identifican a una única autoridad
+	linkByHeading = `WITH candidates AS (
+			SELECT authority_type, heading_key, min(control_number) AS control_number
+			FROM authority_records
+			WHERE heading_key <> '' AND deleted_at IS NULL
+			GROUP BY authority_type, heading_key
+			HAVING count(*) = 1
+		)
+		UPDATE bibliographic_headings h
+		SET authority_id = c.control_number, link_method = 'heading'
+		FROM bibliographic_records b, candidates c
+		WHERE b.control_number = h.control_number AND b.category = ANY($1)
+			AND h.authority_id IS NULL
+			AND c.authority_type = h.authority_type AND c.heading_key = h.heading_key`
+
+	linkByVariant = `WITH candidates AS (
+			SELECT h.authority_type, h.heading_key, min(h.control_number) AS control_number
+			FROM authority_headings h
+			JOIN authority_records a ON a.control_number = h.control_number
+			WHERE h.tag LIKE '4%' AND h.heading_key <> '' AND a.deleted_at IS NULL
+			GROUP BY h.authority_type, h.heading_key
+			HAVING count(DISTINCT h.control_number) = 1
+		)
+		UPDATE bibliographic_headings h
+		SET authority_id = c.control_number, link_method = 'variant'
+		FROM bibliographic_records b, candidates c
+		WHERE b.control_number = h.control_number AND b.category = ANY($1)
+			AND h.authority_id IS NULL
+			AND c.authority_type = h.authority_type AND c.heading_key = h.heading_key`
+
+	countLinks = `SELECT b.category,
+			count(h.authority_id),
+			count(*) - count(h.authority_id)
+		FROM bibliographic_headings h
+		JOIN bibliographic_records b ON b.control_number = h.control_number
+		WHERE b.category = ANY($1) AND b.deleted_at IS NULL
+		GROUP BY b.category`
+
+	selectUnmatched = `SELECT category, tag, heading, occurrences
+		FROM (
+			SELECT b.category, h.tag, h.heading, count(*) AS occurrences,
+				row_number() OVER (PARTITION BY b.category ORDER BY count(*) DESC, h.heading) AS position
+			FROM bibliographic_headings h
+			JOIN bibliographic_records b ON b.control_number = h.control_number
+			WHERE b.category = ANY($1) AND b.deleted_at IS NULL AND h.authority_id IS NULL
+			GROUP BY b.category, h.tag, h.heading
+		) ranked
+		WHERE position <= $2
+		ORDER BY category, position`
+)
+
+// LinkStats resume el enlace de encabezamientos de una categoría
+type LinkStats struct {
+	Linked    int
+	Unmatched int
+	// Encabezamientos sin enlazar más frecuentes
+	TopUnmatched []UnmatchedHeading
+}
+
+type UnmatchedHeading struct {
+	Tag         string `json:"tag"`
+	Heading     string `json:"heading"`
+	Occurrences int    `json:"occurrences"`
+}
+
+// LinkHeadings enlaza los encabezamientos de los registros bibliográficos de
+// las categorías indicadas con los registros de autoridad: primero por $0 y,
+// si no, por la forma normalizada del encabezamiento o de sus variantes
+func (s *Store) LinkHeadings(ctx context.Context, categories []string) (map[string]*LinkStats, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error iniciando transacción (%w)", err)
+	}
+	defer tx.Rollback()
+
+	scope := pq.Array(categories)
+	for _, query := range []string{resetLinks, linkBySubfield0, linkByHeading, linkByVariant} {
+		if _, err := tx.ExecContext(ctx, query, scope); err != nil {
+			return nil, fmt.Errorf("error enlazando encabezamientos (%w)", err)
+		}
+	}
+
+	stats := make(map[string]*LinkStats)
+	rows, err := tx.QueryContext(ctx, countLinks, scope)
+	if err != nil {
+		return nil, fmt.Errorf("error contando enlaces (%w)", err)
+	}
+	for rows.Next() {
+		var category string
+		result := &LinkStats{}
+		if err := rows.Scan(&category, &result.Linked, &result.Unmatched); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error contando enlaces (%w)", err)
+		}
+		stats[category] = result
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error contando enlaces (%w)", err)
+	}
+
+	rows, err = tx.QueryContext(ctx, selectUnmatched, scope, unmatchedSample)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando encabezamientos sin enlazar (%w)", err)
+	}
+	for rows.Next() {
+		var category string
+		var heading UnmatchedHeading
+		if err := rows.Scan(&category, &heading.Tag, &heading.Heading, &heading.Occurrences); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error consultando encabezamientos sin enlazar (%w)", err)
+		}
+		if result, exists := stats[category]; exists {
+			result.TopUnmatched = append(result.TopUnmatched, heading)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error consultando encabezamientos sin enlazar (%w)", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error confirmando enlaces (%w)", err)
+	}
+	return stats, nil
+}
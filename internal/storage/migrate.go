@@ -0,0 +1,341 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Migraciones del esquema: NNNN_nombre.up.sql y su reversión NNNN_nombre.down.sql
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Clave del bloqueo consultivo que impide que dos procesos migren a la vez
+const migrationLockKey = 0x424e45
+
+// ErrSchemaAhead indica que la base de datos tiene migraciones que esta
+// versión de la aplicación no conoce
+var ErrSchemaAhead = errors.New("esquema de base de datos más reciente que la aplicación")
+
+// ErrSchemaBehind indica que faltan migraciones de esta versión de la aplicación
+var ErrSchemaBehind = errors.New("esquema de base de datos anterior al que requiere la aplicación")
+
+var migrationPattern = regexp.MustCompile(`^(\d{4})_(\w+)\.(up|down)\.sql$`)
+
+// Migration es un cambio del esquema con su reversión
+type Migration struct {
+	Version int
+	Name    string
+	up      string
+	down    string
+}
+
+// MigrationState es una migración y, si se ha aplicado, cuándo y con qué
+// versión de la aplicación
+type MigrationState struct {
+	Version    int        `json:"version"`
+	Name       string     `json:"name"`
+	Applied    bool       `json:"applied"`
+	AppliedAt  *time.Time `json:"applied_at,omitempty"`
+	AppVersion string     `json:"app_version,omitempty"`
+	Unknown    bool       `json:"unknown,omitempty"` // aplicada por una versión posterior
+}
+
+var migrations = loadMigrations()
+
+// loadMigrations lee las migraciones embebidas. Al ser parte del binario, una
+// migración mal nombrada o sin pareja es un error de programación
+func loadMigrations() []Migration {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		panic(fmt.Sprintf("migraciones no encontradas (%v)", err))
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationPattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			panic(fmt.Sprintf("nombre de migración no válido: %s", entry.Name()))
+		}
+		content, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			panic(fmt.Sprintf("error leyendo migración %s (%v)", entry.Name(), err))
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			panic(fmt.Sprintf("migración %04d con nombres distintos: %s y %s", version, migration.Name, match[2]))
+		}
+		if match[3] == "up" {
+			migration.up = string(content)
+		} else {
+			migration.down = string(content)
+		}
+	}
+
+	var list []Migration
+	for _, migration := range byVersion {
+		if migration.up == "" || migration.down == "" {
+			panic(fmt.Sprintf("migración %04d_%s sin up o down", migration.Version, migration.Name))
+		}
+		list = append(list, *migration)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+
+	for i, migration := range list {
+		if migration.Version != i+1 {
+			panic(fmt.Sprintf("falta la migración %04d", i+1))
+		}
+	}
+	return list
+}
+
+// LatestVersion devuelve la versión de esquema que espera esta aplicación
+func LatestVersion() int {
+	return len(migrations)
+}
+
+// Registro de las migraciones aplicadas
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version     INTEGER PRIMARY KEY,
+	name        TEXT NOT NULL,
+	app_version TEXT NOT NULL,
+	applied_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+
+const migrationsTableExists = `SELECT to_regclass('schema_migrations') IS NOT NULL`
+
+const selectMigrations = `SELECT version, name, app_version, applied_at
+	FROM schema_migrations
+	ORDER BY version`
+
+const insertMigration = `INSERT INTO schema_migrations (version, name, app_version)
+	VALUES ($1, $2, $3)`
+
+const deleteMigration = `DELETE FROM schema_migrations WHERE version = $1`
+
+// withMigrationLock ejecuta fn en una conexión que tiene el bloqueo de
+// migraciones. El bloqueo es de sesión, así que se libera también si el
+// proceso muere
+func (s *Store) withMigrationLock(ctx context.Context, fn func(*sql.Conn) error) error {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error obteniendo conexión para migrar (%w)", err)
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, migrationLockKey).Scan(&locked); err != nil {
+		return fmt.Errorf("error obteniendo bloqueo de migraciones (%w)", err)
+	}
+	if !locked {
+		s.logger.Info("Otro proceso está migrando la base de datos, esperando...")
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+			return fmt.Errorf("error esperando bloqueo de migraciones (%w)", err)
+		}
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey); err != nil {
+			s.logger.Warnf("Error liberando bloqueo de migraciones: %v", err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, createMigrationsTable); err != nil {
+		return fmt.Errorf("error creando tabla de migraciones (%w)", err)
+	}
+	return fn(conn)
+}
+
+// queryer es una conexión o el pool completo
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// appliedMigrations devuelve las migraciones registradas por versión
+func appliedMigrations(ctx context.Context, conn queryer) (map[int]MigrationState, error) {
+	rows, err := conn.QueryContext(ctx, selectMigrations)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando migraciones aplicadas (%w)", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]MigrationState)
+	for rows.Next() {
+		state := MigrationState{Applied: true}
+		var appliedAt time.Time
+		if err := rows.Scan(&state.Version, &state.Name, &state.AppVersion, &appliedAt); err != nil {
+			return nil, fmt.Errorf("error leyendo migraciones aplicadas (%w)", err)
+		}
+		state.AppliedAt = &appliedAt
+		state.Unknown = state.Version > LatestVersion()
+		applied[state.Version] = state
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error leyendo migraciones aplicadas (%w)", err)
+	}
+	return applied, nil
+}
+
+// checkAhead falla si la base de datos tiene migraciones posteriores a las
+// que incluye el binario: esta versión no sabe trabajar con ese esquema
+func checkAhead(applied map[int]MigrationState, appVersion string) error {
+	for _, state := range applied {
+		if state.Unknown {
+			return fmt.Errorf("%w: migración %04d_%s aplicada por la versión %s, la versión %s llega hasta la %04d",
+				ErrSchemaAhead, state.Version, state.Name, state.AppVersion, appVersion, LatestVersion())
+		}
+	}
+	return nil
+}
+
+// readMigrations devuelve las migraciones aplicadas sin tomar el bloqueo ni
+// escribir nada. Sin tabla de migraciones, initialized es false
+func (s *Store) readMigrations(ctx context.Context) (applied map[int]MigrationState, initialized bool, err error) {
+	if err := s.db.QueryRowContext(ctx, migrationsTableExists).Scan(&initialized); err != nil {
+		return nil, false, fmt.Errorf("error consultando tabla de migraciones (%w)", err)
+	}
+	if !initialized {
+		return map[int]MigrationState{}, false, nil
+	}
+	applied, err = appliedMigrations(ctx, s.db)
+	return applied, true, err
+}
+
+// MigrationStatus devuelve el estado de todas las migraciones, incluidas las
+// aplicadas por versiones posteriores de la aplicación. Es de solo lectura:
+// sin tabla de migraciones, initialized es false y todas figuran pendientes
+func (s *Store) MigrationStatus(ctx context.Context) (states []MigrationState, initialized bool, err error) {
+	applied, initialized, err := s.readMigrations(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, migration := range migrations {
+		state, exists := applied[migration.Version]
+		if !exists {
+			state = MigrationState{Version: migration.Version, Name: migration.Name}
+		}
+		states = append(states, state)
+		delete(applied, migration.Version)
+	}
+	for _, state := range applied {
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Version < states[j].Version })
+	return states, initialized, nil
+}
+
+// CheckSchema comprueba, sin modificar la base de datos, que el esquema es el
+// que requiere la versión appVersion de la aplicación: todas sus migraciones
+// aplicadas y ninguna posterior. Devuelve ErrSchemaBehind o ErrSchemaAhead
+func (s *Store) CheckSchema(ctx context.Context, appVersion string) error {
+	applied, _, err := s.readMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	if err := checkAhead(applied, appVersion); err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, migration := range migrations {
+		if _, exists := applied[migration.Version]; !exists {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%w: la versión %s requiere el esquema %04d y faltan %d migraciones",
+			ErrSchemaBehind, appVersion, LatestVersion(), pending)
+	}
+	return nil
+}
+
+// Migrate aplica las migraciones pendientes, cada una en su transacción, y
+// registra la versión de la aplicación que las aplicó. Devuelve cuántas aplicó
+func (s *Store) Migrate(ctx context.Context, appVersion string) (int, error) {
+	count := 0
+	err := s.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := checkAhead(applied, appVersion); err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if _, exists := applied[migration.Version]; exists {
+				continue
+			}
+			err := runMigration(ctx, conn, migration.up, insertMigration, migration.Version, migration.Name, appVersion)
+			if err != nil {
+				return fmt.Errorf("error aplicando migración %04d_%s (%w)", migration.Version, migration.Name, err)
+			}
+			s.logger.Infof("Migración %04d_%s aplicada", migration.Version, migration.Name)
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Rollback revierte las últimas steps migraciones aplicadas, de la más
+// reciente a la más antigua. Devuelve cuántas revirtió
+func (s *Store) Rollback(ctx context.Context, steps int, appVersion string) (int, error) {
+	count := 0
+	err := s.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := checkAhead(applied, appVersion); err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+			migration := migrations[i]
+			if _, exists := applied[migration.Version]; !exists {
+				continue
+			}
+			err := runMigration(ctx, conn, migration.down, deleteMigration, migration.Version)
+			if err != nil {
+				return fmt.Errorf("error revirtiendo migración %04d_%s (%w)", migration.Version, migration.Name, err)
+			}
+			s.logger.Infof("Migración %04d_%s revertida", migration.Version, migration.Name)
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// runMigration ejecuta el SQL de una migración y actualiza su registro en la
+// misma transacción
+func runMigration(ctx context.Context, conn *sql.Conn, script, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS authority_headings;
+DROP TABLE IF EXISTS authority_records;
+DROP TABLE IF EXISTS bibliographic_records;
@@ -0,0 +1,36 @@
+-- Tablas de registros bibliográficos y de autoridad. El registro MARC completo
+-- se guarda en JSONB; el resto de columnas son los datos más consultados
+CREATE TABLE IF NOT EXISTS bibliographic_records (
+	control_number TEXT PRIMARY KEY,
+	category       TEXT NOT NULL,
+	leader         TEXT NOT NULL,
+	title          TEXT NOT NULL DEFAULT '',
+	author         TEXT NOT NULL DEFAULT '',
+	record         JSONB NOT NULL,
+	updated_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS bibliographic_records_category_idx
+	ON bibliographic_records (category);
+
+CREATE TABLE IF NOT EXISTS authority_records (
+	control_number TEXT PRIMARY KEY,
+	category       TEXT NOT NULL,
+	leader         TEXT NOT NULL,
+	authority_type TEXT NOT NULL,
+	heading        TEXT NOT NULL,
+	record         JSONB NOT NULL,
+	updated_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS authority_records_heading_idx
+	ON authority_records (authority_type, heading);
+
+-- Formas no aceptadas (4XX) y encabezamientos relacionados (5XX)
+CREATE TABLE IF NOT EXISTS authority_headings (
+	control_number TEXT NOT NULL REFERENCES authority_records (control_number) ON DELETE CASCADE,
+	tag            TEXT NOT NULL,
+	heading        TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS authority_headings_control_number_idx
+	ON authority_headings (control_number);
+CREATE INDEX IF NOT EXISTS authority_headings_heading_idx
+	ON authority_headings (heading);
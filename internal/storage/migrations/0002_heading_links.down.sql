@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS bibliographic_headings;
+DROP INDEX IF EXISTS authority_headings_heading_key_idx;
+ALTER TABLE authority_headings DROP COLUMN IF EXISTS heading_key;
+ALTER TABLE authority_headings DROP COLUMN IF EXISTS authority_type;
+DROP INDEX IF EXISTS authority_records_heading_key_idx;
+ALTER TABLE authority_records DROP COLUMN IF EXISTS heading_key;
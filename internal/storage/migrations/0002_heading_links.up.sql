@@ -0,0 +1,24 @@
+-- Formas normalizadas para enlazar encabezamientos bibliográficos
+ALTER TABLE authority_records ADD COLUMN IF NOT EXISTS heading_key TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS authority_records_heading_key_idx
+	ON authority_records (authority_type, heading_key);
+ALTER TABLE authority_headings ADD COLUMN IF NOT EXISTS authority_type TEXT NOT NULL DEFAULT '';
+ALTER TABLE authority_headings ADD COLUMN IF NOT EXISTS heading_key TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS authority_headings_heading_key_idx
+	ON authority_headings (authority_type, heading_key);
+
+-- Encabezamientos bibliográficos y la autoridad con la que se han enlazado
+CREATE TABLE IF NOT EXISTS bibliographic_headings (
+	control_number TEXT NOT NULL REFERENCES bibliographic_records (control_number) ON DELETE CASCADE,
+	tag            TEXT NOT NULL,
+	position       INTEGER NOT NULL,
+	authority_type TEXT NOT NULL,
+	heading        TEXT NOT NULL,
+	heading_key    TEXT NOT NULL,
+	authority_ref  TEXT NOT NULL DEFAULT '',
+	authority_id   TEXT,
+	link_method    TEXT,
+	PRIMARY KEY (control_number, tag, position)
+);
+CREATE INDEX IF NOT EXISTS bibliographic_headings_authority_id_idx
+	ON bibliographic_headings (authority_id);
@@ -0,0 +1,11 @@
+-- Identificadores externos (VIAF, ISNI, Wikidata...) de ambas familias
+CREATE TABLE IF NOT EXISTS external_identifiers (
+	record_family  TEXT NOT NULL,
+	control_number TEXT NOT NULL,
+	scheme         TEXT NOT NULL,
+	value          TEXT NOT NULL,
+	tag            TEXT NOT NULL,
+	PRIMARY KEY (record_family, control_number, scheme, value)
+);
+CREATE INDEX IF NOT EXISTS external_identifiers_value_idx
+	ON external_identifiers (scheme, value);
@@ -0,0 +1,15 @@
+-- ISBN e ISSN normalizados para búsquedas por número
+CREATE TABLE IF NOT EXISTS standard_numbers (
+	control_number TEXT NOT NULL REFERENCES bibliographic_records (control_number) ON DELETE CASCADE,
+	number_type    TEXT NOT NULL,
+	value          TEXT NOT NULL,
+	isbn10         TEXT NOT NULL DEFAULT '',
+	qualifier      TEXT NOT NULL DEFAULT '',
+	tag            TEXT NOT NULL,
+	subfield       TEXT NOT NULL,
+	valid          BOOLEAN NOT NULL
+);
+CREATE INDEX IF NOT EXISTS standard_numbers_control_number_idx
+	ON standard_numbers (control_number);
+CREATE INDEX IF NOT EXISTS standard_numbers_value_idx
+	ON standard_numbers (number_type, value);
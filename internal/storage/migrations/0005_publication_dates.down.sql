@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS bibliographic_records_date_idx;
+ALTER TABLE bibliographic_records DROP COLUMN IF EXISTS date_type;
+ALTER TABLE bibliographic_records DROP COLUMN IF EXISTS date_precision;
+ALTER TABLE bibliographic_records DROP COLUMN IF EXISTS date_end;
+ALTER TABLE bibliographic_records DROP COLUMN IF EXISTS date_start;
@@ -0,0 +1,7 @@
+-- Fecha de publicación normalizada; date_end NULL indica un rango abierto
+ALTER TABLE bibliographic_records ADD COLUMN IF NOT EXISTS date_start INTEGER;
+ALTER TABLE bibliographic_records ADD COLUMN IF NOT EXISTS date_end INTEGER;
+ALTER TABLE bibliographic_records ADD COLUMN IF NOT EXISTS date_precision TEXT NOT NULL DEFAULT 'unknown';
+ALTER TABLE bibliographic_records ADD COLUMN IF NOT EXISTS date_type TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS bibliographic_records_date_idx
+	ON bibliographic_records (date_start, date_end);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS bibliographic_records_language_idx;
+ALTER TABLE bibliographic_records DROP COLUMN IF EXISTS country;
+ALTER TABLE bibliographic_records DROP COLUMN IF EXISTS language;
@@ -0,0 +1,5 @@
+-- Lengua principal y lugar de publicación (códigos MARC vigentes)
+ALTER TABLE bibliographic_records ADD COLUMN IF NOT EXISTS language TEXT NOT NULL DEFAULT '';
+ALTER TABLE bibliographic_records ADD COLUMN IF NOT EXISTS country TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS bibliographic_records_language_idx
+	ON bibliographic_records (language);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS authority_records_category_idx;
+ALTER TABLE authority_records DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE authority_records DROP COLUMN IF EXISTS content_hash;
+ALTER TABLE bibliographic_records DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE bibliographic_records DROP COLUMN IF EXISTS content_hash;
@@ -0,0 +1,8 @@
+-- Huella del contenido para la carga incremental y marca de registro
+-- eliminado de los ficheros de la BNE
+ALTER TABLE bibliographic_records ADD COLUMN IF NOT EXISTS content_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE bibliographic_records ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE authority_records ADD COLUMN IF NOT EXISTS content_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE authority_records ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS authority_records_category_idx
+	ON authority_records (category);
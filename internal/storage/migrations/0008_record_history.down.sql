@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS record_history;
+ALTER TABLE authority_records DROP COLUMN IF EXISTS run_id;
+ALTER TABLE bibliographic_records DROP COLUMN IF EXISTS run_id;
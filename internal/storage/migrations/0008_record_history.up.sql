@@ -0,0 +1,18 @@
+-- Versiones anteriores de los registros. run_id en las tablas de registros
+-- es la ejecución que escribió la versión vigente; en el histórico, la que
+-- la sustituyó o eliminó
+ALTER TABLE bibliographic_records ADD COLUMN IF NOT EXISTS run_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE authority_records ADD COLUMN IF NOT EXISTS run_id TEXT NOT NULL DEFAULT '';
+CREATE TABLE IF NOT EXISTS record_history (
+	record_family  TEXT NOT NULL,
+	control_number TEXT NOT NULL,
+	category       TEXT NOT NULL,
+	record         JSONB NOT NULL,
+	content_hash   TEXT NOT NULL,
+	deleted        BOOLEAN NOT NULL,
+	valid_from     TIMESTAMPTZ NOT NULL,
+	valid_to       TIMESTAMPTZ NOT NULL,
+	run_id         TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS record_history_control_number_idx
+	ON record_history (control_number, valid_from);
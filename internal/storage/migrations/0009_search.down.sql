@@ -0,0 +1,6 @@
+-- La extensión unaccent se conserva: puede usarla otra aplicación
+DROP INDEX IF EXISTS bibliographic_records_search_idx;
+ALTER TABLE bibliographic_records DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS bne_search_vector(JSONB);
+DROP FUNCTION IF EXISTS bne_field_text(JSONB, TEXT);
+DROP TEXT SEARCH CONFIGURATION IF EXISTS bne_spanish;
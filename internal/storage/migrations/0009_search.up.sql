@@ -0,0 +1,31 @@
+-- Búsqueda de texto completo en español, sin distinguir acentos
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+CREATE TEXT SEARCH CONFIGURATION bne_spanish (COPY = pg_catalog.spanish);
+ALTER TEXT SEARCH CONFIGURATION bne_spanish
+	ALTER MAPPING FOR hword, hword_part, word WITH unaccent, spanish_stem;
+
+-- Texto de los subcampos alfabéticos de los campos cuya etiqueta cumple el patrón
+CREATE FUNCTION bne_field_text(record JSONB, pattern TEXT) RETURNS TEXT
+	LANGUAGE sql IMMUTABLE
+	AS $$
+		SELECT coalesce(string_agg(subfield->>'value', ' '), '')
+		FROM jsonb_array_elements(record->'data_fields') AS field,
+			jsonb_array_elements(field->'subfields') AS subfield
+		WHERE field->>'tag' ~ pattern AND subfield->>'code' ~ '^[a-z]$'
+	$$;
+
+-- Documento de búsqueda ponderado: títulos, autores, materias y notas
+CREATE FUNCTION bne_search_vector(record JSONB) RETURNS tsvector
+	LANGUAGE sql STABLE
+	AS $$
+		SELECT setweight(to_tsvector('bne_spanish', bne_field_text(record, '^(130|240|245|246)$')), 'A') ||
+			setweight(to_tsvector('bne_spanish', bne_field_text(record, '^(100|110|111|700|710|711)$')), 'B') ||
+			setweight(to_tsvector('bne_spanish', bne_field_text(record, '^6')), 'C') ||
+			setweight(to_tsvector('bne_spanish', bne_field_text(record, '^5')), 'D')
+	$$;
+
+ALTER TABLE bibliographic_records ADD COLUMN IF NOT EXISTS search_vector tsvector;
+UPDATE bibliographic_records SET search_vector = bne_search_vector(record);
+CREATE INDEX IF NOT EXISTS bibliographic_records_search_idx
+	ON bibliographic_records USING GIN (search_vector);
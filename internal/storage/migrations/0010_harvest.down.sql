@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS authority_records_updated_at_idx;
+DROP INDEX IF EXISTS bibliographic_records_updated_at_idx;
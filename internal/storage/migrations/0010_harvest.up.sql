@@ -0,0 +1,5 @@
+-- Recolección OAI-PMH por fecha de la versión vigente
+CREATE INDEX IF NOT EXISTS bibliographic_records_updated_at_idx
+	ON bibliographic_records (updated_at, control_number);
+CREATE INDEX IF NOT EXISTS authority_records_updated_at_idx
+	ON authority_records (updated_at, control_number);
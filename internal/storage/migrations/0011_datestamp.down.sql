@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS authority_records_run_id_idx;
+DROP INDEX IF EXISTS bibliographic_records_run_id_idx;
+DROP INDEX IF EXISTS authority_records_datestamp_idx;
+DROP INDEX IF EXISTS bibliographic_records_datestamp_idx;
+CREATE INDEX IF NOT EXISTS bibliographic_records_updated_at_idx
+	ON bibliographic_records (updated_at, control_number);
+CREATE INDEX IF NOT EXISTS authority_records_updated_at_idx
+	ON authority_records (updated_at, control_number);
+ALTER TABLE authority_records DROP COLUMN IF EXISTS datestamp;
+ALTER TABLE bibliographic_records DROP COLUMN IF EXISTS datestamp;
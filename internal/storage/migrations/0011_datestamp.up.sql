@@ -0,0 +1,25 @@
+-- Momento en que se confirmó la versión vigente, posterior al fin de la
+-- transacción de carga. updated_at es el inicio de esa transacción y no
+-- sirve para la recolección selectiva
+ALTER TABLE bibliographic_records ADD COLUMN IF NOT EXISTS datestamp TIMESTAMPTZ;
+UPDATE bibliographic_records SET datestamp = updated_at WHERE datestamp IS NULL;
+ALTER TABLE bibliographic_records ALTER COLUMN datestamp SET DEFAULT now();
+ALTER TABLE bibliographic_records ALTER COLUMN datestamp SET NOT NULL;
+
+ALTER TABLE authority_records ADD COLUMN IF NOT EXISTS datestamp TIMESTAMPTZ;
+UPDATE authority_records SET datestamp = updated_at WHERE datestamp IS NULL;
+ALTER TABLE authority_records ALTER COLUMN datestamp SET DEFAULT now();
+ALTER TABLE authority_records ALTER COLUMN datestamp SET NOT NULL;
+
+DROP INDEX IF EXISTS bibliographic_records_updated_at_idx;
+DROP INDEX IF EXISTS authority_records_updated_at_idx;
+CREATE INDEX IF NOT EXISTS bibliographic_records_datestamp_idx
+	ON bibliographic_records (datestamp, control_number);
+CREATE INDEX IF NOT EXISTS authority_records_datestamp_idx
+	ON authority_records (datestamp, control_number);
+
+-- Sellado de los registros de una ejecución al confirmar su carga
+CREATE INDEX IF NOT EXISTS bibliographic_records_run_id_idx
+	ON bibliographic_records (run_id);
+CREATE INDEX IF NOT EXISTS authority_records_run_id_idx
+	ON authority_records (run_id);
@@ -0,0 +1,2 @@
+ALTER TABLE authority_records DROP COLUMN IF EXISTS derivation_version;
+ALTER TABLE bibliographic_records DROP COLUMN IF EXISTS derivation_version;
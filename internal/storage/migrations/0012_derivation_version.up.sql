@@ -0,0 +1,4 @@
+-- Versión con la que se derivaron las columnas y tablas del registro MARC.
+-- Los registros existentes quedan a 0 y se derivan de nuevo en la siguiente carga
+ALTER TABLE bibliographic_records ADD COLUMN IF NOT EXISTS derivation_version INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE authority_records ADD COLUMN IF NOT EXISTS derivation_version INTEGER NOT NULL DEFAULT 0;
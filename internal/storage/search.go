@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fsoria-ttec/bne-converter/pkg/models"
+)
+
+// Tamaño de página por defecto y máximo de las búsquedas
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// ErrInvalidCursor indica un cursor de paginación mal formado
+var ErrInvalidCursor = errors.New("cursor de paginación no válido")
+
+// Registros bibliográficos vigentes que cumplen la consulta y los filtros.
+// $1 consulta en sintaxis de buscador web ("frase exacta", -excluir, or); si
+// está vacía se devuelven todos, ordenados por número de control. Un rango
+// de años selecciona los registros cuya fecha de publicación se solapa con él
+const searchMatches = `WITH matches AS (
+		SELECT control_number, category, title, author, language, date_start, date_end,
+			CASE WHEN $1 = '' THEN 0::real
+				ELSE ts_rank(search_vector, websearch_to_tsquery('bne_spanish', $1)) END AS rank
+		FROM bibliographic_records
+		WHERE deleted_at IS NULL
+			AND ($1 = '' OR search_vector @@ websearch_to_tsquery('bne_spanish', $1))
+			AND ($2 = '' OR category = $2)
+			AND ($3 = '' OR language = $3)
+			AND ($4 = 0 OR (date_start IS NOT NULL AND (date_end IS NULL OR date_end >= $4)))
+			AND ($5 = 0 OR date_start <= $5)
+	)`
+
+const (
+	// Paginación por clave (rank, control_number): $6 y $7 son la posición
+	// del último resultado de la página anterior
+	searchPage = searchMatches + `
+	SELECT control_number, category, title, author, language, date_start, date_end, rank
+	FROM matches
+	WHERE $6 = '' OR rank < $7::real OR (rank = $7::real AND control_number > $6)
+	ORDER BY rank DESC, control_number
+	LIMIT $8`
+
+	searchCount = searchMatches + `
+	SELECT count(*) FROM matches`
+)
+
+// SearchFilters restringe los resultados de una búsqueda. Los campos vacíos
+// o a cero no filtran
+type SearchFilters struct {
+	Category string
+	Language string // código MARC de lengua
+	YearFrom int
+	YearTo   int
+}
+
+// Page selecciona una página de resultados. Cursor es el Next de la página
+// anterior; vacío para la primera
+type Page struct {
+	Limit  int
+	Cursor string
+}
+
+// SearchHit es un registro bibliográfico encontrado
+type SearchHit struct {
+	ControlNumber string  `json:"control_number"`
+	Category      string  `json:"category"`
+	Title         string  `json:"title"`
+	Author        string  `json:"author,omitempty"`
+	Language      string  `json:"language,omitempty"`
+	DateStart     int     `json:"date_start,omitempty"`
+	DateEnd       int     `json:"date_end,omitempty"` // 0 si el rango es abierto o no hay fecha
+	Rank          float32 `json:"rank"`
+}
+
+// SearchResult es una página de resultados
+type SearchResult struct {
+	Hits  []SearchHit `json:"hits"`
+	Total int         `json:"total"`
+	Next  string      `json:"next,omitempty"` // vacío en la última página
+}
+
+// Search busca en títulos, autores, materias y notas de los registros
+// bibliográficos, con lematización en español y sin distinguir acentos. Los
+// resultados se ordenan por relevancia
+func (s *Store) Search(ctx context.Context, query string, filters SearchFilters, page Page) (*SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if filters.Language != "" {
+		language, _ := models.ResolveLanguage(filters.Language)
+		filters.Language = language.Code
+	}
+
+	limit := page.Limit
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	limit = min(limit, MaxSearchLimit)
+
+	var afterRank float32
+	var afterControlNumber string
+	if page.Cursor != "" {
+		var err error
+		if afterRank, afterControlNumber, err = decodeCursor(page.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	args := []any{query, filters.Category, filters.Language, filters.YearFrom, filters.YearTo}
+
+	result := &SearchResult{Hits: []SearchHit{}}
+	if err := s.db.QueryRowContext(ctx, searchCount, args...).Scan(&result.Total); err != nil {
+		return nil, fmt.Errorf("error contando resultados de búsqueda (%w)", err)
+	}
+
+	// Se pide un resultado más para saber si hay página siguiente
+	rows, err := s.db.QueryContext(ctx, searchPage,
+		append(args, afterControlNumber, afterRank, limit+1)...)
+	if err != nil {
+		return nil, fmt.Errorf("error buscando registros (%w)", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hit SearchHit
+		var dateStart, dateEnd sql.NullInt64
+		if err := rows.Scan(&hit.ControlNumber, &hit.Category, &hit.Title, &hit.Author,
+			&hit.Language, &dateStart, &dateEnd, &hit.Rank); err != nil {
+			return nil, fmt.Errorf("error leyendo resultados de búsqueda (%w)", err)
+		}
+		hit.DateStart, hit.DateEnd = int(dateStart.Int64), int(dateEnd.Int64)
+		result.Hits = append(result.Hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error leyendo resultados de búsqueda (%w)", err)
+	}
+
+	if len(result.Hits) > limit {
+		result.Hits = result.Hits[:limit]
+		last := result.Hits[limit-1]
+		result.Next = encodeCursor(last.Rank, last.ControlNumber)
+	}
+	return result, nil
+}
+
+// El cursor es opaco para el cliente: relevancia y número de control del
+// último resultado de la página
+func encodeCursor(rank float32, controlNumber string) string {
+	value := strconv.FormatFloat(float64(rank), 'g', -1, 32) + ":" + controlNumber
+	return base64.RawURLEncoding.EncodeToString([]byte(value))
+}
+
+func decodeCursor(cursor string) (float32, string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, "", ErrInvalidCursor
+	}
+	rank, controlNumber, found := strings.Cut(string(data), ":")
+	if !found || controlNumber == "" {
+		return 0, "", ErrInvalidCursor
+	}
+	value, err := strconv.ParseFloat(rank, 32)
+	if err != nil {
+		return 0, "", ErrInvalidCursor
+	}
+	return float32(value), controlNumber, nil
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		rank          float32
+		controlNumber string
+	}{
+		{rank: 0, controlNumber: "bimo0000000001"},
+		{rank: 0.0607927, controlNumber: "bimo0001234567"},
+		{rank: 1e-20, controlNumber: "a:b"},
+	}
+
+	for _, test := range tests {
+		cursor := encodeCursor(test.rank, test.controlNumber)
+		rank, controlNumber, err := decodeCursor(cursor)
+		if err != nil || rank != test.rank || controlNumber != test.controlNumber {
+			t.Errorf("decodeCursor(%s) = %v, %q, %v; se esperaba %v, %q",
+				cursor, rank, controlNumber, err, test.rank, test.controlNumber)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	encode := base64.RawURLEncoding.EncodeToString
+	for _, cursor := range []string{
+		"",
+		"no válido",
+		encode([]byte("0.5")),
+		encode([]byte("0.5:")),
+		encode([]byte("rango:bimo0000000001")),
+		// Con relleno de base64 estándar
+		base64.StdEncoding.EncodeToString([]byte("0.5:bimo01")),
+	} {
+		if _, _, err := decodeCursor(cursor); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("decodeCursor(%q): %v, se esperaba ErrInvalidCursor", cursor, err)
+		}
+	}
+}
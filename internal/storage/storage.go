@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fsoria-ttec/bne-converter/internal/config"
+	_ "github.com/lib/pq"        // driver de PostgreSQL
+	"github.com/sirupsen/logrus" // logging
+)
+
+// Tiempo máximo para establecer la conexión inicial
+const connectTimeout = 10 * time.Second
+
+// Store guarda los registros procesados en PostgreSQL
+type Store struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+func New(cfg *config.Config, logger *logrus.Logger) (*Store, error) {
+	db, err := sql.Open("postgres", dsn(&cfg.Database))
+	if err != nil {
+		return nil, fmt.Errorf("error abriendo conexión con la base de datos (%w)", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error conectando con la base de datos %s:%d (%w)",
+			cfg.Database.Host, cfg.Database.Port, err)
+	}
+
+	return &Store{db: db, logger: logger}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// dsn compone la cadena de conexión clave=valor, entrecomillando los valores
+func dsn(cfg *config.DatabaseConfig) string {
+	params := []string{
+		"host=" + quote(cfg.Host),
+		fmt.Sprintf("port=%d", cfg.Port),
+		"dbname=" + quote(cfg.Name),
+	}
+	if cfg.User != "" {
+		params = append(params, "user="+quote(cfg.User))
+	}
+	if cfg.Password != "" {
+		params = append(params, "password="+quote(cfg.Password))
+	}
+	if cfg.SSLMode != "" {
+		params = append(params, "sslmode="+quote(cfg.SSLMode))
+	}
+	return strings.Join(params, " ")
+}
+
+func quote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
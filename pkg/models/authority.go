@@ -0,0 +1,119 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+// Tipos de registro de autoridad según la etiqueta del encabezamiento (1XX)
+const (
+	AuthorityPerson     = "person"
+	AuthorityCorporate  = "corporate"
+	AuthorityMeeting    = "meeting"
+	AuthorityTitle      = "title"
+	AuthoritySubject    = "subject"
+	AuthorityGeographic = "geographic"
+	AuthorityGenre      = "genre"
+)
+
+var authorityTypes = map[string]string{
+	"100": AuthorityPerson,
+	"110": AuthorityCorporate,
+	"111": AuthorityMeeting,
+	"130": AuthorityTitle,
+	"150": AuthoritySubject,
+	"151": AuthorityGeographic,
+	"155": AuthorityGenre,
+}
+
+var ErrMissingHeading = errors.New("registro de autoridad sin encabezamiento (1XX)")
+
+// Authority es un registro de autoridad: personas, entidades, materias...
+type Authority struct {
+	ControlNumber string       `json:"control_number"`
+	Category      string       `json:"category"`
+	Type          string       `json:"type"`
+	Heading       string       `json:"heading"`
+	HeadingKey    string       `json:"-"`
+	Variants      []Heading    `json:"variants,omitempty"`    // formas no aceptadas (4XX)
+	Related       []Heading    `json:"related,omitempty"`     // encabezamientos relacionados (5XX)
+	Identifiers   []Identifier `json:"identifiers,omitempty"` // VIAF, ISNI, Wikidata...
+	Record        *Record      `json:"record"`
+}
+
+type Heading struct {
+	Tag   string `json:"tag"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	Key   string `json:"-"`
+}
+
+// IsAuthority indica si el registro es de autoridad (posición 06 de la cabecera = 'z')
+func (r *Record) IsAuthority() bool {
+	return len(r.Leader) > 6 && r.Leader[6] == 'z'
+}
+
+func NewAuthority(category string, r *Record) (*Authority, error) {
+	authority := &Authority{
+		ControlNumber: r.ControlNumber(),
+		Category:      category,
+		Identifiers:   Identifiers(r),
+		Record:        r,
+	}
+
+	for _, field := range r.DataFields {
+		if len(field.Tag) != 3 {
+			continue
+		}
+		switch field.Tag[0] {
+		case '1':
+			if authorityType, exists := authorityTypes[field.Tag]; exists && authority.Heading == "" {
+				authority.Type = authorityType
+				authority.Heading = HeadingText(field)
+				authority.HeadingKey = HeadingKey(authorityType, field)
+			}
+		case '4':
+			if heading, exists := newHeading(field); exists {
+				authority.Variants = append(authority.Variants, heading)
+			}
+		case '5':
+			if heading, exists := newHeading(field); exists {
+				authority.Related = append(authority.Related, heading)
+			}
+		}
+	}
+
+	if authority.Heading == "" {
+		return nil, ErrMissingHeading
+	}
+	return authority, nil
+}
+
+// newHeading construye una forma variante o relacionada (4XX/5XX)
+func newHeading(field DataField) (Heading, bool) {
+	authorityType, exists := authorityTypes["1"+field.Tag[1:]]
+	if !exists {
+		return Heading{}, false
+	}
+	return Heading{
+		Tag:   field.Tag,
+		Type:  authorityType,
+		Value: HeadingText(field),
+		Key:   HeadingKey(authorityType, field),
+	}, true
+}
+
+// HeadingText compone el texto de un encabezamiento con sus subcampos
+// alfabéticos, sin los subcampos de control ($0-$9) ni la puntuación final
+func HeadingText(f DataField) string {
+	var parts []string
+	for _, subfield := range f.Subfields {
+		if subfield.Code == "" || subfield.Code[0] < 'a' || subfield.Code[0] > 'z' {
+			continue
+		}
+		if value := strings.TrimSpace(subfield.Value); value != "" {
+			parts = append(parts, value)
+		}
+	}
+	return strings.TrimRight(strings.Join(parts, " "), " ,.:;/")
+}
@@ -0,0 +1,50 @@
+package models
+
+import "strings"
+
+// Bibliographic es un registro bibliográfico con los datos más consultados
+// extraídos del registro MARC completo
+type Bibliographic struct {
+	ControlNumber  string                 `json:"control_number"`
+	Category       string                 `json:"category"`
+	Title          string                 `json:"title"`
+	Author         string                 `json:"author,omitempty"`
+	Date           *PublicationDate       `json:"date,omitempty"`
+	Languages      []Language             `json:"languages,omitempty"`
+	TranslatedFrom []Language             `json:"translated_from,omitempty"` // lenguas originales (041 $h)
+	Country        *Country               `json:"country,omitempty"`         // lugar de publicación (008/15-17)
+	Headings       []BibliographicHeading `json:"headings,omitempty"`        // candidatos a enlazar con autoridades
+	Identifiers    []Identifier           `json:"identifiers,omitempty"`
+	Numbers        []StandardNumber       `json:"standard_numbers,omitempty"` // ISBN e ISSN
+	Record         *Record                `json:"record"`
+}
+
+func NewBibliographic(category string, r *Record) *Bibliographic {
+	bibliographic := &Bibliographic{
+		ControlNumber: r.ControlNumber(),
+		Category:      category,
+		Date:          NewPublicationDate(r),
+		Country:       recordCountry(r),
+		Headings:      headings(r),
+		Identifiers:   Identifiers(r),
+		Numbers:       StandardNumbers(r),
+		Record:        r,
+	}
+
+	bibliographic.Languages, bibliographic.TranslatedFrom = recordLanguages(r)
+
+	if fields := r.Fields("245"); len(fields) > 0 {
+		title := strings.TrimSpace(fields[0].Subfield("a") + " " + fields[0].Subfield("b"))
+		bibliographic.Title = strings.TrimRight(title, " ,.:;/=")
+	}
+
+	// Autor principal: persona, entidad o congreso
+	for _, tag := range []string{"100", "110", "111"} {
+		if fields := r.Fields(tag); len(fields) > 0 {
+			bibliographic.Author = HeadingText(fields[0])
+			break
+		}
+	}
+
+	return bibliographic
+}
@@ -0,0 +1,211 @@
+package models
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"strings"
+)
+
+// Tablas de códigos MARC de lengua y país con sus nombres y equivalencias ISO
+//
+//go:embed codes/*.tsv
+var codeTables embed.FS
+
+// Language es una lengua resuelta a partir de su código MARC
+type Language struct {
+	Code     string `json:"code"` // código MARC vigente
+	ISO6391  string `json:"iso639_1,omitempty"`
+	ISO6393  string `json:"iso639_3,omitempty"`
+	Spanish  string `json:"es"`
+	English  string `json:"en"`
+	Obsolete string `json:"obsolete,omitempty"` // código obsoleto que figura en el registro
+}
+
+// Country es un lugar de publicación resuelto a partir de su código MARC
+type Country struct {
+	Code        string `json:"code"`
+	ISO3166     string `json:"iso3166,omitempty"`
+	Spanish     string `json:"es"`
+	English     string `json:"en"`
+	Subdivision string `json:"subdivision,omitempty"` // estado, provincia o región del código
+	Obsolete    string `json:"obsolete,omitempty"`
+}
+
+// subdivision es un estado, provincia o región con código MARC propio
+type subdivision struct {
+	country string
+	name    string
+}
+
+var (
+	languages         = make(map[string]Language)
+	countries         = make(map[string]Country)
+	obsoleteLanguages = make(map[string]string)
+	obsoleteCountries = make(map[string]string)
+	subdivisions      = make(map[string]subdivision)
+)
+
+func init() {
+	readTable("codes/languages.tsv", 5, func(columns []string) {
+		languages[columns[0]] = Language{
+			Code:    columns[0],
+			ISO6391: columns[1],
+			ISO6393: columns[2],
+			Spanish: columns[3],
+			English: columns[4],
+		}
+	})
+	readTable("codes/countries.tsv", 4, func(columns []string) {
+		countries[columns[0]] = Country{
+			Code:    columns[0],
+			ISO3166: columns[1],
+			Spanish: columns[2],
+			English: columns[3],
+		}
+	})
+	readTable("codes/subdivisions.tsv", 4, func(columns []string) {
+		subdivisions[columns[0]] = subdivision{country: columns[1], name: columns[2]}
+	})
+	readTable("codes/obsolete.tsv", 3, func(columns []string) {
+		switch columns[0] {
+		case "language":
+			obsoleteLanguages[columns[1]] = columns[2]
+		case "country":
+			obsoleteCountries[columns[1]] = columns[2]
+		}
+	})
+}
+
+// readTable recorre las filas de una tabla embebida. Al ser parte del binario,
+// una tabla mal formada es un error de programación
+func readTable(name string, width int, fn func([]string)) {
+	file, err := codeTables.Open(name)
+	if err != nil {
+		panic(fmt.Sprintf("tabla de códigos %s no encontrada (%v)", name, err))
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if text := scanner.Text(); text != "" && !strings.HasPrefix(text, "#") {
+			columns := strings.Split(text, "\t")
+			if len(columns) != width {
+				panic(fmt.Sprintf("tabla de códigos %s: línea %d con %d columnas", name, line, len(columns)))
+			}
+			fn(columns)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		panic(fmt.Sprintf("error leyendo tabla de códigos %s (%v)", name, err))
+	}
+}
+
+// ResolveLanguage devuelve la lengua de un código MARC, sustituyendo los
+// obsoletos por el vigente. Los códigos desconocidos se devuelven tal cual
+func ResolveLanguage(code string) (Language, bool) {
+	code = strings.ToLower(strings.TrimSpace(code))
+
+	current, obsolete := obsoleteLanguages[code]
+	if !obsolete {
+		current = code
+	}
+
+	language, exists := languages[current]
+	if !exists {
+		language = Language{Code: current, Spanish: current, English: current}
+	}
+	if obsolete {
+		language.Obsolete = code
+	}
+	return language, exists
+}
+
+// ResolveCountry devuelve el país de un código MARC. Los códigos de estados,
+// provincias y regiones de EE. UU., Reino Unido, Canadá y Australia (nyu, enk,
+// onc, xna) se resuelven al país, conservando el código original
+func ResolveCountry(code string) (Country, bool) {
+	code = strings.ToLower(strings.TrimSpace(code))
+
+	current, obsolete := obsoleteCountries[code]
+	if !obsolete {
+		current = code
+	}
+
+	country, exists := countries[current]
+	if region, subdivided := subdivisions[current]; !exists && subdivided {
+		country, exists = countries[region.country]
+		country.Code = current
+		country.Subdivision = region.name
+	}
+	if !exists {
+		country = Country{Code: current, Spanish: current, English: current}
+	}
+	if obsolete {
+		country.Obsolete = code
+	}
+	return country, exists
+}
+
+// recordLanguages devuelve las lenguas del 008/35-37 y del 041 $a/$d y las
+// lenguas originales de una traducción (041 $h), sin duplicados
+func recordLanguages(r *Record) ([]Language, []Language) {
+	var languages, original []Language
+	seen := make(map[string]bool)
+
+	add := func(list *[]Language, role, code string) {
+		if len(code) != 3 || strings.Trim(code, " |") == "" {
+			return
+		}
+		language, _ := ResolveLanguage(code)
+		if key := role + ":" + language.Code; !seen[key] {
+			seen[key] = true
+			*list = append(*list, language)
+		}
+	}
+
+	if value, exists := r.ControlField("008"); exists && len(value) >= 38 {
+		add(&languages, "text", value[35:38])
+	}
+
+	for _, field := range r.Fields("041") {
+		// Segundo indicador 7: códigos de otra norma indicada en $2
+		if field.Ind2 == "7" {
+			continue
+		}
+		for _, subfield := range field.Subfields {
+			var list *[]Language
+			role := "text"
+			switch subfield.Code {
+			case "a", "d":
+				list = &languages
+			case "h":
+				list, role = &original, "original"
+			default:
+				continue
+			}
+			// Registros antiguos concatenan varios códigos: spaeng
+			value := strings.TrimSpace(subfield.Value)
+			for i := 0; i+3 <= len(value); i += 3 {
+				add(list, role, value[i:i+3])
+			}
+		}
+	}
+
+	return languages, original
+}
+
+// recordCountry devuelve el lugar de publicación del 008/15-17
+func recordCountry(r *Record) *Country {
+	value, exists := r.ControlField("008")
+	if !exists || len(value) < 18 {
+		return nil
+	}
+
+	code := strings.TrimSpace(value[15:18])
+	if code == "" || strings.Contains(code, "|") {
+		return nil
+	}
+	country, _ := ResolveCountry(code)
+	return &country
+}
@@ -0,0 +1,83 @@
+package models
+
+import "testing"
+
+func TestResolveCountry(t *testing.T) {
+	tests := []struct {
+		code        string
+		want        string
+		iso         string
+		subdivision string
+		obsolete    string
+		exists      bool
+	}{
+		{code: "sp", want: "sp", iso: "ES", exists: true},
+		{code: "ht", want: "ht", iso: "HT", exists: true},
+		{code: "nyu", want: "nyu", iso: "US", subdivision: "Nueva York", exists: true},
+		{code: "onc", want: "onc", iso: "CA", subdivision: "Ontario", exists: true},
+		{code: "stk", want: "stk", iso: "GB", subdivision: "Escocia", exists: true},
+		{code: "xna", want: "xna", iso: "AU", subdivision: "Nueva Gales del Sur", exists: true},
+		{code: " NYU ", want: "nyu", iso: "US", subdivision: "Nueva York", exists: true},
+		{code: "us", want: "xxu", iso: "US", obsolete: "us", exists: true},
+		{code: "uik", want: "xxk", iso: "GB", obsolete: "uik", exists: true},
+		// Terminaciones de subdivisión sin código propio en la lista MARC
+		{code: "xyu", want: "xyu"},
+		{code: "nsa", want: "nsa"},
+		{code: "zzk", want: "zzk"},
+		{code: "ha", want: "ha"},
+	}
+
+	for _, test := range tests {
+		country, exists := ResolveCountry(test.code)
+		if exists != test.exists || country.Code != test.want || country.ISO3166 != test.iso ||
+			country.Subdivision != test.subdivision || country.Obsolete != test.obsolete {
+			t.Errorf("ResolveCountry(%q) = %+v, %v", test.code, country, exists)
+		}
+	}
+}
+
+func TestResolveLanguage(t *testing.T) {
+	tests := []struct {
+		code     string
+		want     string
+		iso6391  string
+		iso6393  string
+		obsolete string
+		exists   bool
+	}{
+		{code: "spa", want: "spa", iso6391: "es", iso6393: "spa", exists: true},
+		{code: "baq", want: "baq", iso6391: "eu", iso6393: "eus", exists: true},
+		{code: "cnr", want: "cnr", iso6393: "cnr", exists: true},
+		{code: "zxx", want: "zxx", exists: true},
+		{code: "tru", want: "chk", iso6393: "chk", obsolete: "tru", exists: true},
+		{code: "esk", want: "esk"},
+		{code: "qaa", want: "qaa"},
+	}
+
+	for _, test := range tests {
+		language, exists := ResolveLanguage(test.code)
+		if exists != test.exists || language.Code != test.want || language.ISO6391 != test.iso6391 ||
+			language.ISO6393 != test.iso6393 || language.Obsolete != test.obsolete {
+			t.Errorf("ResolveLanguage(%q) = %+v, %v", test.code, language, exists)
+		}
+	}
+}
+
+// Los códigos obsoletos deben apuntar a uno vigente de la tabla
+func TestObsoleteCodesResolve(t *testing.T) {
+	for obsolete, current := range obsoleteLanguages {
+		if _, exists := languages[current]; !exists {
+			t.Errorf("lengua obsoleta %s sustituida por %s, ausente de la tabla", obsolete, current)
+		}
+	}
+	for obsolete, current := range obsoleteCountries {
+		if _, exists := countries[current]; !exists {
+			t.Errorf("país obsoleto %s sustituido por %s, ausente de la tabla", obsolete, current)
+		}
+	}
+	for code, region := range subdivisions {
+		if _, exists := countries[region.country]; !exists {
+			t.Errorf("subdivisión %s de %s, ausente de la tabla", code, region.country)
+		}
+	}
+}
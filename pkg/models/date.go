@@ -0,0 +1,273 @@
+package models
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Precisión de la fecha normalizada
+const (
+	DatePrecisionYear    = "year"
+	DatePrecisionDecade  = "decade"
+	DatePrecisionCentury = "century"
+	DatePrecisionUnknown = "unknown"
+)
+
+// Límites de los años que se aceptan como fecha de publicación
+const (
+	minYear = 1000
+	maxYear = 2100
+)
+
+// PublicationDate es la fecha de publicación normalizada a partir del 008 y,
+// si este no la trae, de 264/260 $c. End 0 indica un rango abierto
+type PublicationDate struct {
+	Type        string `json:"type,omitempty"` // 008/06
+	Start       int    `json:"start,omitempty"`
+	End         int    `json:"end,omitempty"`
+	Precision   string `json:"precision"`
+	Approximate bool   `json:"approximate,omitempty"`
+	Source      string `json:"source,omitempty"`   // campo del que se toma la fecha
+	Text        string `json:"text,omitempty"`     // fecha de 26X $c tal como figura
+	Conflict    bool   `json:"conflict,omitempty"` // 008 y 26X no coinciden
+}
+
+type dateSpan struct {
+	start, end  int
+	precision   string
+	approximate bool
+}
+
+var (
+	yearRunPattern  = regexp.MustCompile(`[0-9]+-*`)
+	rangeGapPattern = regexp.MustCompile(`^[\s\[\]?]*-[\s\[\]?]*$`)
+	romanPattern    = regexp.MustCompile(`\b[MDCLXVI]{3,}\b`)
+	approxPattern   = regexp.MustCompile(`\b(ca\.|circa|hacia)|\?`)
+)
+
+// Tipos de fecha del 008/06 que expresan un rango en 07-10 y 11-14
+const rangeDateTypes = "mqikcdu"
+
+// NewPublicationDate normaliza la fecha de publicación del registro. Devuelve
+// nil si ni el 008 ni 26X contienen una fecha reconocible
+func NewPublicationDate(r *Record) *PublicationDate {
+	date := &PublicationDate{Precision: DatePrecisionUnknown}
+
+	fixed, hasFixed := parseFixedDate(r, date)
+	tag, text := publicationStatement(r)
+	date.Text = text
+	free, hasFree := parseDateText(text)
+
+	switch {
+	case hasFixed:
+		date.setSpan(fixed, "008")
+		date.Conflict = hasFree && !fixed.overlaps(free)
+	case hasFree:
+		date.setSpan(free, tag)
+	case date.Type == "" && text == "":
+		return nil
+	}
+	return date
+}
+
+func (d *PublicationDate) setSpan(span dateSpan, source string) {
+	d.Start = span.start
+	d.End = span.end
+	d.Precision = span.precision
+	d.Approximate = span.approximate
+	d.Source = source
+}
+
+func (s dateSpan) overlaps(other dateSpan) bool {
+	return s.start <= other.last() && other.start <= s.last()
+}
+
+func (s dateSpan) last() int {
+	if s.end == 0 {
+		return math.MaxInt
+	}
+	return s.end
+}
+
+// parseFixedDate interpreta las posiciones 06-14 del 008
+func parseFixedDate(r *Record, date *PublicationDate) (dateSpan, bool) {
+	value, exists := r.ControlField("008")
+	if !exists || len(value) < 15 {
+		return dateSpan{}, false
+	}
+
+	dateType := value[6:7]
+	if dateType != " " && dateType != "|" {
+		date.Type = dateType
+	}
+	if dateType == "n" || dateType == "b" {
+		return dateSpan{}, false
+	}
+
+	span, valid := fixedYear(value[7:11])
+	if !valid {
+		return dateSpan{}, false
+	}
+	span.approximate = dateType == "q"
+
+	if strings.Contains(rangeDateTypes, dateType) {
+		switch end := value[11:15]; {
+		case end == "9999":
+			span.end = 0
+		default:
+			if endSpan, valid := fixedYear(end); valid && endSpan.end >= span.start {
+				span.end = endSpan.end
+			} else if dateType == "c" || dateType == "u" {
+				span.end = 0
+			}
+		}
+	}
+	return span, true
+}
+
+// fixedYear interpreta un año del 008, con 'u' o '-' en las últimas cifras
+// desconocidas: 19uu es el siglo XX y 195u la década de 1950
+func fixedYear(value string) (dateSpan, bool) {
+	digits := strings.TrimRight(value, "u-")
+	if len(digits) < 2 || !digitsPattern.MatchString(digits) {
+		return dateSpan{}, false
+	}
+
+	return yearSpan(digits, 4-len(digits))
+}
+
+// yearSpan construye el rango de un año del que se desconocen las últimas cifras
+func yearSpan(digits string, unknown int) (dateSpan, bool) {
+	var precision string
+	switch unknown {
+	case 0:
+		precision = DatePrecisionYear
+	case 1:
+		precision = DatePrecisionDecade
+	case 2:
+		precision = DatePrecisionCentury
+	default:
+		return dateSpan{}, false
+	}
+
+	start, err := strconv.Atoi(digits + strings.Repeat("0", unknown))
+	if err != nil || start < minYear || start > maxYear {
+		return dateSpan{}, false
+	}
+	end := start + int(math.Pow10(unknown)) - 1
+	return dateSpan{start: start, end: end, precision: precision}, true
+}
+
+// publicationStatement devuelve la fecha de publicación (264 con segundo
+// indicador 1, o 260) y, en su defecto, la de copyright del 264
+func publicationStatement(r *Record) (string, string) {
+	var copyright string
+	for _, field := range r.DataFields {
+		if field.Tag != "260" && field.Tag != "264" {
+			continue
+		}
+		text := strings.TrimSpace(field.Subfield("c"))
+		if text == "" {
+			continue
+		}
+		if field.Tag == "264" && field.Ind2 == "4" {
+			if copyright == "" {
+				copyright = text
+			}
+			continue
+		}
+		if field.Tag == "260" || field.Ind2 == "1" {
+			return field.Tag, text
+		}
+	}
+	if copyright != "" {
+		return "264", copyright
+	}
+	return "", ""
+}
+
+// parseDateText interpreta fechas en texto libre: "1995-2001", "[ca. 1750]",
+// "[199-?]", "[18--]", "c1990" o "MDCCCXII". "s.a." no es una fecha
+func parseDateText(text string) (dateSpan, bool) {
+	approximate := approxPattern.MatchString(strings.ToLower(text))
+
+	runs := yearRunPattern.FindAllStringIndex(text, -1)
+	for i, run := range runs {
+		token := text[run[0]:run[1]]
+		digits := strings.TrimRight(token, "-")
+
+		var span dateSpan
+		var valid bool
+		switch {
+		case len(digits) == 4:
+			span, valid = yearSpan(digits, 0)
+		case len(digits) == 3 && len(token) > 3:
+			span, valid = yearSpan(digits, 1)
+		case len(digits) == 2 && len(token) > 3:
+			span, valid = yearSpan(digits, 2)
+		}
+		if !valid {
+			continue
+		}
+
+		// Rango: 1995-2001, [1995]-[2001], o abierto si el guion cierra el texto
+		if len(digits) == 4 {
+			if i+1 < len(runs) && rangeGapPattern.MatchString(text[run[0]+4:runs[i+1][0]]) {
+				next := text[runs[i+1][0]:runs[i+1][1]]
+				if end, valid := yearSpan(strings.TrimRight(next, "-"), 0); valid && end.start >= span.start {
+					span.end = end.end
+				}
+			} else if len(token) > 4 && strings.Trim(text[run[1]:], " .]") == "" {
+				span.end = 0
+			}
+		}
+
+		span.approximate = approximate
+		return span, true
+	}
+
+	// Años en números romanos, habituales en impresos antiguos
+	for _, roman := range romanPattern.FindAllString(strings.ToUpper(text), -1) {
+		if year, valid := romanToInt(roman); valid && year >= minYear && year <= maxYear {
+			return dateSpan{start: year, end: year, precision: DatePrecisionYear, approximate: approximate}, true
+		}
+	}
+	return dateSpan{}, false
+}
+
+var romanValues = map[byte]int{'I': 1, 'V': 5, 'X': 10, 'L': 50, 'C': 100, 'D': 500, 'M': 1000}
+
+// romanToInt convierte un número romano y comprueba que esté bien formado
+func romanToInt(roman string) (int, bool) {
+	total := 0
+	for i := 0; i < len(roman); i++ {
+		value := romanValues[roman[i]]
+		if i+1 < len(roman) && value < romanValues[roman[i+1]] {
+			total -= value
+		} else {
+			total += value
+		}
+	}
+	return total, total > 0 && intToRoman(total) == roman
+}
+
+func intToRoman(value int) string {
+	numerals := []struct {
+		value  int
+		symbol string
+	}{
+		{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"}, {100, "C"}, {90, "XC"},
+		{50, "L"}, {40, "XL"}, {10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+	}
+
+	var roman strings.Builder
+	for _, numeral := range numerals {
+		for value >= numeral.value {
+			roman.WriteString(numeral.symbol)
+			value -= numeral.value
+		}
+	}
+	return roman.String()
+}
@@ -0,0 +1,209 @@
+package models
+
+import "testing"
+
+// fixedField compone un 008 con el tipo de fecha y las dos fechas (06-14)
+func fixedField(dates string) ControlField {
+	return ControlField{Tag: "008", Value: "850101" + dates + "sp            000 0 spa d"}
+}
+
+func statement(tag, ind2, date string) DataField {
+	return DataField{Tag: tag, Ind1: " ", Ind2: ind2, Subfields: []Subfield{
+		{Code: "a", Value: "Madrid :"},
+		{Code: "b", Value: "Cátedra,"},
+		{Code: "c", Value: date},
+	}}
+}
+
+func TestNewPublicationDate(t *testing.T) {
+	tests := []struct {
+		name   string
+		fixed  string // 008/06-14; vacío sin 008
+		fields []DataField
+		want   *PublicationDate
+	}{
+		{
+			name:  "año del 008",
+			fixed: "s1985    ",
+			want:  &PublicationDate{Type: "s", Start: 1985, End: 1985, Precision: DatePrecisionYear, Source: "008"},
+		},
+		{
+			name:  "rango de publicación múltiple",
+			fixed: "m19952001",
+			want:  &PublicationDate{Type: "m", Start: 1995, End: 2001, Precision: DatePrecisionYear, Source: "008"},
+		},
+		{
+			name:  "publicación en curso",
+			fixed: "c19929999",
+			want:  &PublicationDate{Type: "c", Start: 1992, Precision: DatePrecisionYear, Source: "008"},
+		},
+		{
+			name:  "estado desconocido sin fecha final",
+			fixed: "u1992uuuu",
+			want:  &PublicationDate{Type: "u", Start: 1992, Precision: DatePrecisionYear, Source: "008"},
+		},
+		{
+			name:  "fecha final anterior a la inicial",
+			fixed: "m19951990",
+			want:  &PublicationDate{Type: "m", Start: 1995, End: 1995, Precision: DatePrecisionYear, Source: "008"},
+		},
+		{
+			name:  "fecha dudosa",
+			fixed: "q17501760",
+			want: &PublicationDate{Type: "q", Start: 1750, End: 1760, Precision: DatePrecisionYear,
+				Approximate: true, Source: "008"},
+		},
+		{
+			name:  "década",
+			fixed: "s195u    ",
+			want:  &PublicationDate{Type: "s", Start: 1950, End: 1959, Precision: DatePrecisionDecade, Source: "008"},
+		},
+		{
+			name:  "siglo",
+			fixed: "s19--    ",
+			want:  &PublicationDate{Type: "s", Start: 1900, End: 1999, Precision: DatePrecisionCentury, Source: "008"},
+		},
+		{
+			name:   "008 sin fecha: se toma el 260",
+			fixed:  "nuuuuuuuu",
+			fields: []DataField{statement("260", " ", "1990.")},
+			want: &PublicationDate{Type: "n", Start: 1990, End: 1990, Precision: DatePrecisionYear,
+				Source: "260", Text: "1990."},
+		},
+		{
+			name:   "008 en blanco: se toma el 264 de publicación",
+			fixed:  "s    uuuu",
+			fields: []DataField{statement("264", "1", "[ca. 1750]")},
+			want: &PublicationDate{Type: "s", Start: 1750, End: 1750, Precision: DatePrecisionYear,
+				Approximate: true, Source: "264", Text: "[ca. 1750]"},
+		},
+		{
+			name: "264 de publicación antes que el de copyright",
+			fields: []DataField{
+				statement("264", "4", "©2015"),
+				statement("264", "3", "2010"),
+				statement("264", "1", "[2016]"),
+			},
+			want: &PublicationDate{Start: 2016, End: 2016, Precision: DatePrecisionYear, Source: "264", Text: "[2016]"},
+		},
+		{
+			name:   "solo copyright",
+			fields: []DataField{statement("264", "4", "c1990")},
+			want:   &PublicationDate{Start: 1990, End: 1990, Precision: DatePrecisionYear, Source: "264", Text: "c1990"},
+		},
+		{
+			name:   "años en números romanos",
+			fields: []DataField{statement("260", " ", "Año MDCCCXII")},
+			want: &PublicationDate{Start: 1812, End: 1812, Precision: DatePrecisionYear, Source: "260",
+				Text: "Año MDCCCXII"},
+		},
+		{
+			name:   "008 y 26X coinciden",
+			fixed:  "s1985    ",
+			fields: []DataField{statement("260", " ", "[1985?]")},
+			want: &PublicationDate{Type: "s", Start: 1985, End: 1985, Precision: DatePrecisionYear, Source: "008",
+				Text: "[1985?]"},
+		},
+		{
+			name:   "el 26X cae dentro de la década del 008",
+			fixed:  "s198u    ",
+			fields: []DataField{statement("264", "1", "1987")},
+			want: &PublicationDate{Type: "s", Start: 1980, End: 1989, Precision: DatePrecisionDecade, Source: "008",
+				Text: "1987"},
+		},
+		{
+			name:   "008 y 26X no coinciden",
+			fixed:  "s1985    ",
+			fields: []DataField{statement("264", "1", "2001")},
+			want: &PublicationDate{Type: "s", Start: 1985, End: 1985, Precision: DatePrecisionYear, Source: "008",
+				Text: "2001", Conflict: true},
+		},
+		{
+			name:   "sin fecha reconocible",
+			fields: []DataField{statement("260", " ", "[s.a.]")},
+			want:   &PublicationDate{Precision: DatePrecisionUnknown, Text: "[s.a.]"},
+		},
+		{
+			name:  "año fuera de los límites",
+			fixed: "s0999    ",
+			want:  &PublicationDate{Type: "s", Precision: DatePrecisionUnknown},
+		},
+		{name: "sin 008 ni 26X"},
+		{name: "008 sin tipo ni fecha", fixed: "|||||||||"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			record := &Record{DataFields: test.fields}
+			if test.fixed != "" {
+				record.ControlFields = []ControlField{fixedField(test.fixed)}
+			}
+
+			date := NewPublicationDate(record)
+			switch {
+			case test.want == nil && date != nil:
+				t.Errorf("NewPublicationDate = %+v, se esperaba nil", *date)
+			case test.want != nil && date == nil:
+				t.Errorf("NewPublicationDate = nil, se esperaba %+v", *test.want)
+			case test.want != nil && *date != *test.want:
+				t.Errorf("NewPublicationDate = %+v\nse esperaba %+v", *date, *test.want)
+			}
+		})
+	}
+}
+
+func TestParseDateText(t *testing.T) {
+	tests := []struct {
+		text  string
+		want  dateSpan
+		valid bool
+	}{
+		{text: "1995-2001", want: dateSpan{start: 1995, end: 2001, precision: DatePrecisionYear}, valid: true},
+		{text: "[1995]-[2001]", want: dateSpan{start: 1995, end: 2001, precision: DatePrecisionYear}, valid: true},
+		{text: "1995-", want: dateSpan{start: 1995, precision: DatePrecisionYear}, valid: true},
+		{text: "[199-?]", want: dateSpan{start: 1990, end: 1999, precision: DatePrecisionDecade, approximate: true}, valid: true},
+		{text: "[18--]", want: dateSpan{start: 1800, end: 1899, precision: DatePrecisionCentury}, valid: true},
+		{text: "hacia 1600", want: dateSpan{start: 1600, end: 1600, precision: DatePrecisionYear, approximate: true}, valid: true},
+		// Un número que no es un año no impide leer el siguiente
+		{text: "3a ed., 1987", want: dateSpan{start: 1987, end: 1987, precision: DatePrecisionYear}, valid: true},
+		{text: "MDCCCXII", want: dateSpan{start: 1812, end: 1812, precision: DatePrecisionYear}, valid: true},
+		{text: "mdccxc", want: dateSpan{start: 1790, end: 1790, precision: DatePrecisionYear}, valid: true},
+		// Romanos mal formados o fuera de los límites
+		{text: "MDCCCXIIII"},
+		{text: "XVII"},
+		{text: "s.a."},
+		{text: "0850"},
+		{text: ""},
+	}
+
+	for _, test := range tests {
+		span, valid := parseDateText(test.text)
+		if valid != test.valid || span != test.want {
+			t.Errorf("parseDateText(%q) = %+v, %v; se esperaba %+v, %v", test.text, span, valid, test.want, test.valid)
+		}
+	}
+}
+
+func TestRomanToInt(t *testing.T) {
+	tests := []struct {
+		roman string
+		want  int
+		valid bool
+	}{
+		{roman: "MDCCCXII", want: 1812, valid: true},
+		{roman: "MCMXCIX", want: 1999, valid: true},
+		{roman: "MMXXIV", want: 2024, valid: true},
+		{roman: "MDXLIV", want: 1544, valid: true},
+		// Formas no canónicas
+		{roman: "MCMXCVIIII", want: 1999},
+		{roman: "MIM", want: 1999},
+		{roman: "IIII", want: 4},
+	}
+
+	for _, test := range tests {
+		value, valid := romanToInt(test.roman)
+		if valid != test.valid || value != test.want {
+			t.Errorf("romanToInt(%q) = %d, %v; se esperaba %d, %v", test.roman, value, valid, test.want, test.valid)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Espacios de nombres de oai_dc
+const (
+	OAIDCNamespace = "http://www.openarchives.org/OAI/2.0/oai_dc/"
+	DCNamespace    = "http://purl.org/dc/elements/1.1/"
+)
+
+// DublinCore es la representación oai_dc (Dublin Core simple) de un registro,
+// según la correspondencia MARC a Dublin Core de la Library of Congress
+type DublinCore struct {
+	XMLName        xml.Name `xml:"oai_dc:dc"`
+	OAIDC          string   `xml:"xmlns:oai_dc,attr"`
+	DC             string   `xml:"xmlns:dc,attr"`
+	XSI            string   `xml:"xmlns:xsi,attr"`
+	SchemaLocation string   `xml:"xsi:schemaLocation,attr"`
+
+	Title       []string `xml:"dc:title"`
+	Creator     []string `xml:"dc:creator"`
+	Contributor []string `xml:"dc:contributor"`
+	Subject     []string `xml:"dc:subject"`
+	Description []string `xml:"dc:description"`
+	Publisher   []string `xml:"dc:publisher"`
+	Date        []string `xml:"dc:date"`
+	Type        []string `xml:"dc:type"`
+	Language    []string `xml:"dc:language"`
+	Identifier  []string `xml:"dc:identifier"`
+	Relation    []string `xml:"dc:relation"`
+	Rights      []string `xml:"dc:rights"`
+}
+
+// Tipos DCMI según la posición 06 de la cabecera
+var dcmiTypes = map[byte]string{
+	'a': "Text", 't': "Text", 'c': "Text", 'd': "Text",
+	'e': "Image", 'f': "Image", 'k': "Image",
+	'g': "MovingImage",
+	'i': "Sound", 'j': "Sound",
+	'm': "Software",
+	'o': "Collection", 'p': "Collection",
+	'r': "PhysicalObject",
+}
+
+// DublinCore devuelve el registro en oai_dc. De los registros de autoridad
+// se publican el encabezamiento, sus variantes y los identificadores externos
+func (r *Record) DublinCore() *DublinCore {
+	dc := &DublinCore{
+		OAIDC:          OAIDCNamespace,
+		DC:             DCNamespace,
+		XSI:            "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLocation: OAIDCNamespace + " http://www.openarchives.org/OAI/2.0/oai_dc.xsd",
+	}
+	add := func(list *[]string, value string) {
+		if value = strings.TrimRight(strings.TrimSpace(value), " ,:;/="); value != "" {
+			*list = append(*list, value)
+		}
+	}
+
+	if r.IsAuthority() {
+		for _, field := range r.DataFields {
+			switch {
+			case strings.HasPrefix(field.Tag, "1"):
+				add(&dc.Title, HeadingText(field))
+			case strings.HasPrefix(field.Tag, "4"):
+				add(&dc.Relation, HeadingText(field))
+			case field.Tag == "670":
+				add(&dc.Description, field.Subfield("a"))
+			}
+		}
+		for _, identifier := range Identifiers(r) {
+			add(&dc.Identifier, identifier.Scheme+":"+identifier.Value)
+		}
+		return dc
+	}
+
+	bibliographic := NewBibliographic("", r)
+	add(&dc.Title, bibliographic.Title)
+
+	for _, field := range r.DataFields {
+		switch tag := field.Tag; {
+		case tag == "100" || tag == "110" || tag == "111":
+			add(&dc.Creator, HeadingText(field))
+		case tag == "700" || tag == "710" || tag == "711" || tag == "720":
+			add(&dc.Contributor, HeadingText(field))
+		case tag == "600" || tag == "610" || tag == "611" || tag == "630" || tag == "650" || tag == "651":
+			add(&dc.Subject, subjectText(field))
+		case tag == "260" || tag == "264" && field.Ind2 == "1":
+			for _, publisher := range field.SubfieldValues("b") {
+				add(&dc.Publisher, publisher)
+			}
+		case tag == "500" || tag == "505" || tag == "520":
+			add(&dc.Description, field.Subfield("a"))
+		case tag == "540" || tag == "506":
+			add(&dc.Rights, field.Subfield("a"))
+		case tag == "490" || tag == "830":
+			add(&dc.Relation, field.Subfield("a"))
+		case tag == "856":
+			for _, url := range field.SubfieldValues("u") {
+				add(&dc.Identifier, url)
+			}
+		}
+	}
+
+	if date := bibliographic.Date; date != nil && date.Start != 0 {
+		switch {
+		case date.End == 0:
+			add(&dc.Date, fmt.Sprintf("%d-", date.Start))
+		case date.Precision == DatePrecisionYear && date.End != date.Start:
+			add(&dc.Date, fmt.Sprintf("%d-%d", date.Start, date.End))
+		default:
+			add(&dc.Date, fmt.Sprint(date.Start))
+		}
+	}
+
+	if len(r.Leader) > 6 {
+		if dcmiType, exists := dcmiTypes[r.Leader[6]]; exists {
+			add(&dc.Type, dcmiType)
+		}
+	}
+	for _, language := range bibliographic.Languages {
+		add(&dc.Language, language.Code)
+	}
+	for _, number := range bibliographic.Numbers {
+		if number.Valid {
+			add(&dc.Identifier, fmt.Sprintf("URN:%s:%s", strings.ToUpper(number.Type), number.Value))
+		}
+	}
+	return dc
+}
+
+// subjectText une el término de materia con sus subdivisiones ($v, $x, $y, $z)
+func subjectText(f DataField) string {
+	var parts []string
+	for _, subfield := range f.Subfields {
+		value := strings.TrimRight(strings.TrimSpace(subfield.Value), " ,.:;/")
+		if value == "" || subfield.Code == "" || subfield.Code[0] < 'a' || subfield.Code[0] > 'z' {
+			continue
+		}
+		if strings.Contains("vxyz", subfield.Code) && len(parts) > 0 {
+			parts = append(parts, "--", value)
+		} else {
+			parts = append(parts, value)
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+)
+
+// Delimitadores ISO 2709
+const (
+	fieldTerminator   = 0x1E
+	recordTerminator  = 0x1D
+	subfieldDelimiter = 0x1F
+	leaderLength      = 24
+)
+
+// Espacio de nombres de MARCXML
+const MARCXMLNamespace = "http://www.loc.gov/MARC21/slim"
+
+// MARCXML es la representación de un registro en MARCXML
+type MARCXML struct {
+	XMLName       xml.Name              `xml:"http://www.loc.gov/MARC21/slim record"`
+	Type          string                `xml:"type,attr,omitempty"`
+	Leader        string                `xml:"leader"`
+	ControlFields []marcXMLControlField `xml:"controlfield"`
+	DataFields    []marcXMLDataField    `xml:"datafield"`
+}
+
+type marcXMLControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type marcXMLDataField struct {
+	Tag       string            `xml:"tag,attr"`
+	Ind1      string            `xml:"ind1,attr"`
+	Ind2      string            `xml:"ind2,attr"`
+	Subfields []marcXMLSubfield `xml:"subfield"`
+}
+
+type marcXMLSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+// MARCXML devuelve el registro en MARCXML
+func (r *Record) MARCXML() *MARCXML {
+	record := &MARCXML{Type: "Bibliographic", Leader: r.Leader}
+	if r.IsAuthority() {
+		record.Type = "Authority"
+	}
+
+	for _, field := range r.ControlFields {
+		record.ControlFields = append(record.ControlFields, marcXMLControlField{Tag: field.Tag, Value: field.Value})
+	}
+	for _, field := range r.DataFields {
+		dataField := marcXMLDataField{Tag: field.Tag, Ind1: field.Ind1, Ind2: field.Ind2}
+		for _, subfield := range field.Subfields {
+			dataField.Subfields = append(dataField.Subfields, marcXMLSubfield{Code: subfield.Code, Value: subfield.Value})
+		}
+		record.DataFields = append(record.DataFields, dataField)
+	}
+	return record
+}
+
+// MARCJSON es la representación MARC-in-JSON de un registro: cada campo es
+// un objeto con la etiqueta como única clave
+type MARCJSON struct {
+	Leader string           `json:"leader"`
+	Fields []map[string]any `json:"fields"`
+}
+
+type marcJSONDataField struct {
+	Ind1      string              `json:"ind1"`
+	Ind2      string              `json:"ind2"`
+	Subfields []map[string]string `json:"subfields"`
+}
+
+// MARCJSON devuelve el registro en MARC-in-JSON
+func (r *Record) MARCJSON() *MARCJSON {
+	record := &MARCJSON{Leader: r.Leader, Fields: []map[string]any{}}
+
+	for _, field := range r.ControlFields {
+		record.Fields = append(record.Fields, map[string]any{field.Tag: field.Value})
+	}
+	for _, field := range r.DataFields {
+		dataField := marcJSONDataField{Ind1: field.Ind1, Ind2: field.Ind2, Subfields: []map[string]string{}}
+		for _, subfield := range field.Subfields {
+			dataField.Subfields = append(dataField.Subfields, map[string]string{subfield.Code: subfield.Value})
+		}
+		record.Fields = append(record.Fields, map[string]any{field.Tag: dataField})
+	}
+	return record
+}
+
+// ISO2709 codifica el registro en ISO 2709, recalculando la longitud del
+// registro y la dirección base de la cabecera
+func (r *Record) ISO2709() ([]byte, error) {
+	var directory, data bytes.Buffer
+
+	addField := func(tag string, value []byte) error {
+		if len(tag) != 3 {
+			return fmt.Errorf("etiqueta inválida (%q)", tag)
+		}
+		length := len(value) + 1
+		if length > 9999 || data.Len() > 99999 {
+			return fmt.Errorf("campo %s demasiado largo para ISO 2709", tag)
+		}
+		fmt.Fprintf(&directory, "%s%04d%05d", tag, length, data.Len())
+		data.Write(value)
+		data.WriteByte(fieldTerminator)
+		return nil
+	}
+
+	for _, field := range r.ControlFields {
+		if err := addField(field.Tag, []byte(field.Value)); err != nil {
+			return nil, err
+		}
+	}
+	for _, field := range r.DataFields {
+		var value bytes.Buffer
+		value.WriteString(indicator(field.Ind1))
+		value.WriteString(indicator(field.Ind2))
+		for _, subfield := range field.Subfields {
+			value.WriteByte(subfieldDelimiter)
+			value.WriteString(subfield.Code)
+			value.WriteString(subfield.Value)
+		}
+		if err := addField(field.Tag, value.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	directory.WriteByte(fieldTerminator)
+
+	baseAddress := leaderLength + directory.Len()
+	length := baseAddress + data.Len() + 1
+	if length > 99999 {
+		return nil, fmt.Errorf("registro demasiado largo para ISO 2709 (%d bytes)", length)
+	}
+
+	leader := []byte(fmt.Sprintf("%-24s", r.Leader))[:leaderLength]
+	copy(leader[0:5], fmt.Sprintf("%05d", length))
+	copy(leader[12:17], fmt.Sprintf("%05d", baseAddress))
+
+	record := make([]byte, 0, length)
+	record = append(record, leader...)
+	record = append(record, directory.Bytes()...)
+	record = append(record, data.Bytes()...)
+	return append(record, recordTerminator), nil
+}
+
+func indicator(value string) string {
+	if len(value) != 1 {
+		return " "
+	}
+	return value
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"net/url"
+	"path"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// LinkedTags son los encabezamientos bibliográficos que se enlazan con
+// registros de autoridad, con el tipo de autoridad que les corresponde
+var LinkedTags = map[string]string{
+	"100": AuthorityPerson,
+	"110": AuthorityCorporate,
+	"600": AuthorityPerson,
+	"650": AuthoritySubject,
+	"700": AuthorityPerson,
+}
+
+// Subcampos que identifican el encabezamiento, sin subdivisiones ni funciones
+var keySubfields = map[string]string{
+	AuthorityPerson:     "abcdq",
+	AuthorityCorporate:  "abn",
+	AuthorityMeeting:    "acdn",
+	AuthorityTitle:      "adfklmnoprs",
+	AuthoritySubject:    "a",
+	AuthorityGeographic: "a",
+	AuthorityGenre:      "a",
+}
+
+// BibliographicHeading es un encabezamiento de un registro bibliográfico
+// candidato a enlazarse con una autoridad
+type BibliographicHeading struct {
+	Tag          string `json:"tag"`
+	Position     int    `json:"position"` // orden entre los campos con la misma etiqueta
+	Type         string `json:"type"`
+	Heading      string `json:"heading"`
+	Key          string `json:"-"`
+	AuthorityRef string `json:"authority_ref,omitempty"` // identificador del $0
+}
+
+// HeadingKey devuelve la forma normalizada del encabezamiento para compararlo
+// con los de otros registros
+func HeadingKey(authorityType string, f DataField) string {
+	codes := keySubfields[authorityType]
+
+	var parts []string
+	for _, subfield := range f.Subfields {
+		if subfield.Code != "" && strings.Contains(codes, subfield.Code) {
+			parts = append(parts, subfield.Value)
+		}
+	}
+	return NormalizeHeading(strings.Join(parts, " "))
+}
+
+// NormalizeHeading pasa el texto a minúsculas sin diacríticos ni puntuación
+func NormalizeHeading(value string) string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn))), value)
+	if err != nil {
+		stripped = value
+	}
+
+	fields := strings.FieldsFunc(strings.ToLower(stripped), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// Prefijo de institución y dominio de los identificadores de la BNE en $0
+const (
+	bnePrefix = "(ES-MaBNE)"
+	bneHost   = "datos.bne.es"
+)
+
+// AuthorityID extrae el número de control de la BNE de un $0, con el prefijo
+// de la institución, (ES-MaBNE)XX1234, o como URI de datos.bne.es. Devuelve
+// una cadena vacía para cualquier otro identificador
+func AuthorityID(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= len(bnePrefix) && strings.EqualFold(value[:len(bnePrefix)], bnePrefix) {
+		return strings.TrimSpace(value[len(bnePrefix):])
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || strings.TrimPrefix(strings.ToLower(u.Host), "www.") != bneHost {
+		return ""
+	}
+	id := path.Base(strings.TrimRight(u.Path, "/"))
+	id = strings.TrimSuffix(id, path.Ext(id)) // datos.bne.es/persona/XX1234.html
+	if id == "." || id == "/" {
+		return ""
+	}
+	return id
+}
+
+func headings(r *Record) []BibliographicHeading {
+	var headings []BibliographicHeading
+	positions := make(map[string]int)
+
+	for _, field := range r.DataFields {
+		authorityType, linked := LinkedTags[field.Tag]
+		if !linked {
+			continue
+		}
+
+		heading := BibliographicHeading{
+			Tag:      field.Tag,
+			Position: positions[field.Tag],
+			Type:     authorityType,
+			Heading:  HeadingText(field),
+			Key:      HeadingKey(authorityType, field),
+		}
+		positions[field.Tag]++
+
+		// El $0 puede llevar también identificadores de VIAF, ISNI, etc.
+		for _, value := range field.SubfieldValues("0") {
+			if _, _, external := ParseIdentifier(value); external {
+				continue
+			}
+			if id := AuthorityID(value); id != "" {
+				heading.AuthorityRef = id
+				break
+			}
+		}
+
+		if heading.Key != "" || heading.AuthorityRef != "" {
+			headings = append(headings, heading)
+		}
+	}
+
+	return headings
+}
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Esquemas de identificadores externos reconocidos
+const (
+	SchemeVIAF     = "viaf"
+	SchemeISNI     = "isni"
+	SchemeWikidata = "wikidata"
+	SchemeORCID    = "orcid"
+	SchemeLCNAF    = "lcnaf"
+	SchemeGND      = "gnd"
+	SchemeBNF      = "bnf"
+)
+
+// Identifier es un identificador externo de un registro o de uno de sus
+// encabezamientos (Tag indica el campo del que procede)
+type Identifier struct {
+	Scheme string `json:"scheme"`
+	Value  string `json:"value"`
+	Tag    string `json:"tag"`
+}
+
+var (
+	digitsPattern   = regexp.MustCompile(`^[0-9]+$`)
+	isniPattern     = regexp.MustCompile(`^[0-9]{15}[0-9X]$`)
+	wikidataPattern = regexp.MustCompile(`^Q[0-9]+$`)
+	lcnafPattern    = regexp.MustCompile(`^n[bors]?[0-9]+$`)
+	bnfPattern      = regexp.MustCompile(`^cb[0-9]{8}[0-9bcdfghjkmnpqrstvwxz]$`)
+
+	// Prefijos de institución en $0, (VIAF)12345
+	prefixPattern = regexp.MustCompile(`^\(([^)]+)\)\s*(.+)$`)
+)
+
+// Alias de las fuentes del 024 $2 y de los prefijos entre paréntesis
+var schemeAliases = map[string]string{
+	"viaf":     SchemeVIAF,
+	"isni":     SchemeISNI,
+	"wikidata": SchemeWikidata,
+	"wd":       SchemeWikidata,
+	"orcid":    SchemeORCID,
+	"lcnaf":    SchemeLCNAF,
+	"dlc":      SchemeLCNAF,
+	"gnd":      SchemeGND,
+	"de-588":   SchemeGND,
+	"bnf":      SchemeBNF,
+	"frbnf":    SchemeBNF,
+}
+
+// Identifiers extrae los identificadores externos del 024 (primer indicador 7)
+// y de los URI o prefijos de $0/$1, normalizados y sin duplicados
+func Identifiers(r *Record) []Identifier {
+	var identifiers []Identifier
+	seen := make(map[string]bool)
+
+	add := func(scheme, value, tag string) {
+		value, valid := normalizeIdentifier(scheme, value)
+		if !valid || seen[scheme+":"+value] {
+			return
+		}
+		seen[scheme+":"+value] = true
+		identifiers = append(identifiers, Identifier{Scheme: scheme, Value: value, Tag: tag})
+	}
+
+	for _, field := range r.DataFields {
+		if field.Tag == "024" && field.Ind1 == "7" {
+			source := strings.ToLower(strings.TrimSpace(field.Subfield("2")))
+			if scheme, known := schemeAliases[source]; known {
+				add(scheme, field.Subfield("a"), field.Tag)
+			}
+			continue
+		}
+
+		for _, subfield := range field.Subfields {
+			if subfield.Code != "0" && subfield.Code != "1" {
+				continue
+			}
+			if scheme, value, found := ParseIdentifier(subfield.Value); found {
+				add(scheme, value, field.Tag)
+			}
+		}
+	}
+
+	return identifiers
+}
+
+// ParseIdentifier reconoce un identificador externo expresado como URI o con
+// prefijo de fuente entre paréntesis. Los identificadores de la BNE no se
+// consideran externos
+func ParseIdentifier(raw string) (string, string, bool) {
+	raw = strings.TrimSpace(raw)
+
+	if match := prefixPattern.FindStringSubmatch(raw); match != nil {
+		scheme, known := schemeAliases[strings.ToLower(match[1])]
+		if !known {
+			return "", "", false
+		}
+		return scheme, match[2], true
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "", "", false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	last := segments[len(segments)-1]
+	last = strings.TrimSuffix(last, path.Ext(last)) // id.loc.gov/.../n79021164.html
+
+	switch {
+	case host == "viaf.org":
+		return SchemeVIAF, last, true
+	case host == "isni.org":
+		return SchemeISNI, last, true
+	case host == "wikidata.org":
+		return SchemeWikidata, last, true
+	case host == "orcid.org":
+		return SchemeORCID, last, true
+	case host == "id.loc.gov":
+		return SchemeLCNAF, last, true
+	case host == "d-nb.info":
+		return SchemeGND, last, true
+	case host == "data.bnf.fr" || host == "catalogue.bnf.fr":
+		for _, segment := range segments {
+			if strings.HasPrefix(segment, "cb") {
+				return SchemeBNF, segment, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// normalizeIdentifier devuelve la forma canónica del identificador y si es válido
+func normalizeIdentifier(scheme, value string) (string, bool) {
+	value = strings.TrimSpace(value)
+
+	switch scheme {
+	case SchemeVIAF:
+		return value, digitsPattern.MatchString(value)
+	case SchemeISNI:
+		value = strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(value))
+		return value, isniPattern.MatchString(value) && checkMod11(value)
+	case SchemeORCID:
+		compact := strings.ToUpper(strings.ReplaceAll(value, "-", ""))
+		if !isniPattern.MatchString(compact) || !checkMod11(compact) {
+			return "", false
+		}
+		return compact[0:4] + "-" + compact[4:8] + "-" + compact[8:12] + "-" + compact[12:16], true
+	case SchemeWikidata:
+		value = strings.ToUpper(value)
+		return value, wikidataPattern.MatchString(value)
+	case SchemeLCNAF:
+		value = strings.ToLower(strings.ReplaceAll(value, " ", ""))
+		return value, lcnafPattern.MatchString(value)
+	case SchemeBNF:
+		value = strings.ToLower(value)
+		return value, bnfPattern.MatchString(value)
+	default:
+		return value, value != ""
+	}
+}
+
+// checkMod11 comprueba el dígito de control ISO 7064 MOD 11-2 de ISNI y ORCID
+func checkMod11(value string) bool {
+	total := 0
+	for _, digit := range value[:len(value)-1] {
+		total = (total + int(digit-'0')) * 2
+	}
+	check := (12 - total%11) % 11
+
+	expected := byte('0' + check)
+	if check == 10 {
+		expected = 'X'
+	}
+	return value[len(value)-1] == expected
+}
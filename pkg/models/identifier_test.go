@@ -0,0 +1,127 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseIdentifier(t *testing.T) {
+	tests := []struct {
+		raw    string
+		scheme string
+		value  string
+		found  bool
+	}{
+		{raw: "(VIAF)17220427", scheme: SchemeVIAF, value: "17220427", found: true},
+		{raw: "(DE-588)118520539", scheme: SchemeGND, value: "118520539", found: true},
+		{raw: "(DLC)n  79021164", scheme: SchemeLCNAF, value: "n  79021164", found: true},
+		{raw: "(isni) 0000 0001 2103 2683", scheme: SchemeISNI, value: "0000 0001 2103 2683", found: true},
+		{raw: "http://viaf.org/viaf/17220427", scheme: SchemeVIAF, value: "17220427", found: true},
+		{raw: " https://www.viaf.org/viaf/17220427/ ", scheme: SchemeVIAF, value: "17220427", found: true},
+		{raw: "https://isni.org/isni/0000000121032683", scheme: SchemeISNI, value: "0000000121032683", found: true},
+		{raw: "http://www.wikidata.org/entity/Q5682", scheme: SchemeWikidata, value: "Q5682", found: true},
+		{raw: "https://orcid.org/0000-0002-1825-0097", scheme: SchemeORCID, value: "0000-0002-1825-0097", found: true},
+		{raw: "http://id.loc.gov/authorities/names/n79021164.html", scheme: SchemeLCNAF, value: "n79021164", found: true},
+		{raw: "https://d-nb.info/gnd/118520539", scheme: SchemeGND, value: "118520539", found: true},
+		{raw: "https://data.bnf.fr/ark:/12148/cb11888978p", scheme: SchemeBNF, value: "cb11888978p", found: true},
+		{raw: "https://catalogue.bnf.fr/ark:/12148/cb11888978p/PUBLIC", scheme: SchemeBNF, value: "cb11888978p", found: true},
+		// Identificadores propios de la BNE y fuentes desconocidas
+		{raw: "XX1718747"},
+		{raw: "(SpMaBN)XX1718747"},
+		{raw: "https://datos.bne.es/persona/XX1718747"},
+		{raw: "https://data.bnf.fr/fr/auteurs"},
+		{raw: ""},
+	}
+
+	for _, test := range tests {
+		scheme, value, found := ParseIdentifier(test.raw)
+		if found != test.found || scheme != test.scheme || value != test.value {
+			t.Errorf("ParseIdentifier(%q) = %q, %q, %v; se esperaba %q, %q, %v",
+				test.raw, scheme, value, found, test.scheme, test.value, test.found)
+		}
+	}
+}
+
+func TestNormalizeIdentifier(t *testing.T) {
+	tests := []struct {
+		scheme string
+		value  string
+		want   string
+		valid  bool
+	}{
+		{scheme: SchemeVIAF, value: " 17220427 ", want: "17220427", valid: true},
+		{scheme: SchemeVIAF, value: "viaf17220427", want: "viaf17220427"},
+		{scheme: SchemeISNI, value: "0000 0001 2103 2683", want: "0000000121032683", valid: true},
+		{scheme: SchemeISNI, value: "0000-0001-2146-438x", want: "000000012146438X", valid: true},
+		{scheme: SchemeISNI, value: "0000 0001 2103 2684", want: "0000000121032684"},
+		{scheme: SchemeISNI, value: "0000 0001 2103 268", want: "000000012103268"},
+		{scheme: SchemeORCID, value: "0000000218250097", want: "0000-0002-1825-0097", valid: true},
+		{scheme: SchemeORCID, value: "0000-0002-1694-233x", want: "0000-0002-1694-233X", valid: true},
+		{scheme: SchemeORCID, value: "0000-0002-1825-0098"},
+		{scheme: SchemeORCID, value: "0000-0002-1825"},
+		{scheme: SchemeWikidata, value: "q5682", want: "Q5682", valid: true},
+		{scheme: SchemeWikidata, value: "P31", want: "P31"},
+		{scheme: SchemeLCNAF, value: "n  79021164", want: "n79021164", valid: true},
+		{scheme: SchemeLCNAF, value: "NO2001012345", want: "no2001012345", valid: true},
+		{scheme: SchemeLCNAF, value: "sh85076502", want: "sh85076502"},
+		{scheme: SchemeGND, value: "118520539", want: "118520539", valid: true},
+		{scheme: SchemeGND, value: " ", want: ""},
+		{scheme: SchemeBNF, value: "CB11888978P", want: "cb11888978p", valid: true},
+		{scheme: SchemeBNF, value: "cb11888978a", want: "cb11888978a"},
+		{scheme: SchemeBNF, value: "cb1188897p", want: "cb1188897p"},
+	}
+
+	for _, test := range tests {
+		value, valid := normalizeIdentifier(test.scheme, test.value)
+		if valid != test.valid || value != test.want {
+			t.Errorf("normalizeIdentifier(%s, %q) = %q, %v; se esperaba %q, %v",
+				test.scheme, test.value, value, valid, test.want, test.valid)
+		}
+	}
+}
+
+func TestCheckMod11(t *testing.T) {
+	for value, want := range map[string]bool{
+		"0000000218250097": true,
+		"000000021694233X": true,
+		"0000000121032683": true,
+		"0000000121032684": false,
+		"0000000121225149": false,
+		"000000021825009X": false,
+	} {
+		if got := checkMod11(value); got != want {
+			t.Errorf("checkMod11(%s) = %v, se esperaba %v", value, got, want)
+		}
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	record := &Record{DataFields: []DataField{
+		{Tag: "024", Ind1: "7", Subfields: []Subfield{{Code: "a", Value: "0000 0001 2103 2683"}, {Code: "2", Value: "isni"}}},
+		{Tag: "024", Ind1: "7", Subfields: []Subfield{{Code: "a", Value: "q5682"}, {Code: "2", Value: "WD"}}},
+		// Fuente desconocida, primer indicador distinto de 7 e ISNI con control erróneo
+		{Tag: "024", Ind1: "7", Subfields: []Subfield{{Code: "a", Value: "12345"}, {Code: "2", Value: "local"}}},
+		{Tag: "024", Ind1: "3", Subfields: []Subfield{{Code: "a", Value: "9788437604947"}}},
+		{Tag: "024", Ind1: "7", Subfields: []Subfield{{Code: "a", Value: "0000000121032684"}, {Code: "2", Value: "isni"}}},
+		{Tag: "100", Subfields: []Subfield{
+			{Code: "a", Value: "Cervantes Saavedra, Miguel de,"},
+			{Code: "0", Value: "(VIAF)17220427"},
+			{Code: "1", Value: "http://www.wikidata.org/entity/Q5682"},
+		}},
+		{Tag: "700", Subfields: []Subfield{
+			{Code: "a", Value: "Rico, Francisco,"},
+			{Code: "0", Value: "https://isni.org/isni/0000000121032683"},
+			{Code: "0", Value: "http://id.loc.gov/authorities/names/n79021164"},
+		}},
+	}}
+
+	want := []Identifier{
+		{Scheme: SchemeISNI, Value: "0000000121032683", Tag: "024"},
+		{Scheme: SchemeWikidata, Value: "Q5682", Tag: "024"},
+		{Scheme: SchemeVIAF, Value: "17220427", Tag: "100"},
+		{Scheme: SchemeLCNAF, Value: "n79021164", Tag: "700"},
+	}
+	if got := Identifiers(record); !reflect.DeepEqual(got, want) {
+		t.Errorf("Identifiers = %+v\nse esperaba %+v", got, want)
+	}
+}
@@ -0,0 +1,72 @@
+package models
+
+import "strings"
+
+// Record representa un registro MARC 21 leído de un fichero ISO 2709
+type Record struct {
+	Leader        string         `json:"leader"`
+	ControlFields []ControlField `json:"control_fields"`
+	DataFields    []DataField    `json:"data_fields"`
+}
+
+type ControlField struct {
+	Tag   string `json:"tag"`
+	Value string `json:"value"`
+}
+
+type DataField struct {
+	Tag       string     `json:"tag"`
+	Ind1      string     `json:"ind1"`
+	Ind2      string     `json:"ind2"`
+	Subfields []Subfield `json:"subfields"`
+}
+
+type Subfield struct {
+	Code  string `json:"code"`
+	Value string `json:"value"`
+}
+
+// ControlNumber devuelve el número de control (001) del registro
+func (r *Record) ControlNumber() string {
+	value, _ := r.ControlField("001")
+	return strings.TrimSpace(value)
+}
+
+func (r *Record) ControlField(tag string) (string, bool) {
+	for _, field := range r.ControlFields {
+		if field.Tag == tag {
+			return field.Value, true
+		}
+	}
+	return "", false
+}
+
+func (r *Record) Fields(tag string) []DataField {
+	var fields []DataField
+	for _, field := range r.DataFields {
+		if field.Tag == tag {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// Subfield devuelve el primer valor del subcampo indicado
+func (f DataField) Subfield(code string) string {
+	for _, subfield := range f.Subfields {
+		if subfield.Code == code {
+			return subfield.Value
+		}
+	}
+	return ""
+}
+
+func (f DataField) SubfieldValues(code string) []string {
+	var values []string
+	for _, subfield := range f.Subfields {
+		if subfield.Code == code {
+			values = append(values, subfield.Value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Tipos de número normalizado
+const (
+	NumberISBN = "isbn"
+	NumberISSN = "issn"
+)
+
+// StandardNumber es un ISBN (020) o ISSN (022) de un registro bibliográfico
+type StandardNumber struct {
+	Type      string `json:"type"`
+	Value     string `json:"value"`            // ISBN-13 o NNNN-NNNN si es válido; si no, el texto sin guiones
+	ISBN10    string `json:"isbn10,omitempty"` // forma original cuando era un ISBN-10
+	Qualifier string `json:"qualifier,omitempty"`
+	Tag       string `json:"tag"`
+	Subfield  string `json:"subfield"` // $a vigente; $y/$z erróneo o cancelado
+	Valid     bool   `json:"valid"`
+}
+
+// Número al inicio del subcampo seguido de calificadores: 84-376-0494-X (rúst.)
+var numberPattern = regexp.MustCompile(`^([0-9Xx][0-9Xx\- ]*[0-9Xx])\s*(.*)$`)
+
+// Invalid indica un número vigente ($a) que no supera la validación. Los de
+// $y/$z ya están marcados como erróneos en el propio registro
+func (n StandardNumber) Invalid() bool {
+	return !n.Valid && n.Subfield == "a"
+}
+
+// StandardNumbers extrae los ISBN y los ISSN del registro, sin duplicados
+func StandardNumbers(r *Record) []StandardNumber {
+	var numbers []StandardNumber
+	seen := make(map[string]bool)
+
+	for _, field := range r.DataFields {
+		var numberType, codes string
+		switch field.Tag {
+		case "020":
+			numberType, codes = NumberISBN, "az"
+		case "022":
+			numberType, codes = NumberISSN, "ayz"
+		default:
+			continue
+		}
+
+		// Calificadores en $q (formato actual) o entre paréntesis tras el número
+		qualifiers := field.SubfieldValues("q")
+
+		for _, subfield := range field.Subfields {
+			if subfield.Code == "" || !strings.Contains(codes, subfield.Code) {
+				continue
+			}
+
+			number, exists := parseStandardNumber(numberType, subfield.Value, qualifiers)
+			if !exists {
+				continue
+			}
+			number.Tag = field.Tag
+			number.Subfield = subfield.Code
+
+			key := number.Type + ":" + number.Value + ":" + number.Subfield + ":" + number.Qualifier
+			if !seen[key] {
+				seen[key] = true
+				numbers = append(numbers, number)
+			}
+		}
+	}
+
+	return numbers
+}
+
+func parseStandardNumber(numberType, raw string, qualifiers []string) (StandardNumber, bool) {
+	match := numberPattern.FindStringSubmatch(strings.TrimSpace(raw))
+	if match == nil {
+		return StandardNumber{}, false
+	}
+
+	number := StandardNumber{
+		Type:  numberType,
+		Value: strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(match[1])),
+	}
+
+	var parts []string
+	for _, qualifier := range append([]string{match[2]}, qualifiers...) {
+		qualifier = strings.Trim(qualifier, " :;,")
+		if strings.HasPrefix(qualifier, "(") && strings.HasSuffix(qualifier, ")") {
+			qualifier = strings.TrimSpace(qualifier[1 : len(qualifier)-1])
+		}
+		if qualifier != "" {
+			parts = append(parts, qualifier)
+		}
+	}
+	number.Qualifier = strings.Join(parts, "; ")
+
+	switch numberType {
+	case NumberISBN:
+		switch {
+		case len(number.Value) == 10 && validISBN10(number.Value):
+			number.ISBN10 = number.Value
+			number.Value = ISBN13(number.Value)
+			number.Valid = true
+		case len(number.Value) == 13:
+			number.Valid = validISBN13(number.Value)
+		}
+	case NumberISSN:
+		if len(number.Value) == 8 && validISSN(number.Value) {
+			number.Value = number.Value[:4] + "-" + number.Value[4:]
+			number.Valid = true
+		}
+	}
+
+	return number, true
+}
+
+// ISBN13 convierte un ISBN-10 válido y sin guiones a ISBN-13
+func ISBN13(isbn10 string) string {
+	digits := "978" + isbn10[:9]
+	return digits + string(ean13Check(digits))
+}
+
+func validISBN10(value string) bool {
+	total := 0
+	for i, digit := range value {
+		weight := 10 - i
+		switch {
+		case digit >= '0' && digit <= '9':
+			total += int(digit-'0') * weight
+		case digit == 'X' && i == 9:
+			total += 10
+		default:
+			return false
+		}
+	}
+	return total%11 == 0
+}
+
+func validISBN13(value string) bool {
+	if !digitsPattern.MatchString(value) ||
+		(!strings.HasPrefix(value, "978") && !strings.HasPrefix(value, "979")) {
+		return false
+	}
+	return value[12] == ean13Check(value[:12])
+}
+
+// ean13Check calcula el dígito de control EAN-13 de los 12 primeros dígitos
+func ean13Check(digits string) byte {
+	total := 0
+	for i, digit := range digits {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		total += int(digit-'0') * weight
+	}
+	return byte('0' + (10-total%10)%10)
+}
+
+func validISSN(value string) bool {
+	total := 0
+	for i, digit := range value {
+		weight := 8 - i
+		switch {
+		case digit >= '0' && digit <= '9' && i < 7:
+			total += int(digit-'0') * weight
+		case i == 7:
+			check := (11 - total%11) % 11
+			expected := byte('0' + check)
+			if check == 10 {
+				expected = 'X'
+			}
+			return byte(digit) == expected
+		default:
+			return false
+		}
+	}
+	return false
+}
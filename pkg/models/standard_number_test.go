@@ -0,0 +1,149 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStandardNumber(t *testing.T) {
+	tests := []struct {
+		name       string
+		numberType string
+		raw        string
+		qualifiers []string
+		want       StandardNumber
+		exists     bool
+	}{
+		{
+			name: "ISBN-10 con X de control", numberType: NumberISBN, raw: "84-376-0494-X (rúst.)",
+			want:   StandardNumber{Type: NumberISBN, Value: "9788437604947", ISBN10: "843760494X", Qualifier: "rúst.", Valid: true},
+			exists: true,
+		},
+		{
+			name: "ISBN-10 con x minúscula", numberType: NumberISBN, raw: "843760494x",
+			want:   StandardNumber{Type: NumberISBN, Value: "9788437604947", ISBN10: "843760494X", Valid: true},
+			exists: true,
+		},
+		{
+			name: "ISBN-10 con espacios", numberType: NumberISBN, raw: "0 306 40615 2",
+			want:   StandardNumber{Type: NumberISBN, Value: "9780306406157", ISBN10: "0306406152", Valid: true},
+			exists: true,
+		},
+		{
+			name: "ISBN-10 con dígito de control erróneo", numberType: NumberISBN, raw: "84-376-0494-1",
+			want:   StandardNumber{Type: NumberISBN, Value: "8437604941"},
+			exists: true,
+		},
+		{
+			name: "ISBN-10 con X fuera del control", numberType: NumberISBN, raw: "84X7604941",
+			want:   StandardNumber{Type: NumberISBN, Value: "84X7604941"},
+			exists: true,
+		},
+		{
+			name: "ISBN-13", numberType: NumberISBN, raw: "978-84-376-0494-7",
+			want:   StandardNumber{Type: NumberISBN, Value: "9788437604947", Valid: true},
+			exists: true,
+		},
+		{
+			name: "ISBN-13 con prefijo 979", numberType: NumberISBN, raw: "979-10-90636-07-1",
+			want:   StandardNumber{Type: NumberISBN, Value: "9791090636071", Valid: true},
+			exists: true,
+		},
+		{
+			name: "ISBN-13 con dígito de control erróneo", numberType: NumberISBN, raw: "9788437604940",
+			want:   StandardNumber{Type: NumberISBN, Value: "9788437604940"},
+			exists: true,
+		},
+		{
+			name: "ISBN-13 sin prefijo de libro", numberType: NumberISBN, raw: "9771234567898",
+			want:   StandardNumber{Type: NumberISBN, Value: "9771234567898"},
+			exists: true,
+		},
+		{
+			name: "ISBN de longitud incorrecta", numberType: NumberISBN, raw: "84-376-049",
+			want:   StandardNumber{Type: NumberISBN, Value: "84376049"},
+			exists: true,
+		},
+		{
+			name: "calificadores en $q y tras el número", numberType: NumberISBN, raw: "9788437604947 (o.c.) :",
+			qualifiers: []string{"(t. 1)", "rústica"},
+			want:       StandardNumber{Type: NumberISBN, Value: "9788437604947", Qualifier: "o.c.; t. 1; rústica", Valid: true},
+			exists:     true,
+		},
+		{
+			name: "ISSN", numberType: NumberISSN, raw: "0378-5955",
+			want:   StandardNumber{Type: NumberISSN, Value: "0378-5955", Valid: true},
+			exists: true,
+		},
+		{
+			name: "ISSN con X de control", numberType: NumberISSN, raw: "2434-561x",
+			want:   StandardNumber{Type: NumberISSN, Value: "2434-561X", Valid: true},
+			exists: true,
+		},
+		{
+			name: "ISSN con dígito de control erróneo", numberType: NumberISSN, raw: "0378-5954",
+			want:   StandardNumber{Type: NumberISSN, Value: "03785954"},
+			exists: true,
+		},
+		{
+			name: "ISSN con X fuera del control", numberType: NumberISSN, raw: "X378-5955",
+			want:   StandardNumber{Type: NumberISSN, Value: "X3785955"},
+			exists: true,
+		},
+		{name: "sin número", numberType: NumberISBN, raw: "(rúst.)"},
+		{name: "vacío", numberType: NumberISSN, raw: "  "},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			number, exists := parseStandardNumber(test.numberType, test.raw, test.qualifiers)
+			if exists != test.exists || number != test.want {
+				t.Errorf("parseStandardNumber(%q) = %+v, %v; se esperaba %+v, %v",
+					test.raw, number, exists, test.want, test.exists)
+			}
+		})
+	}
+}
+
+func TestStandardNumbers(t *testing.T) {
+	record := &Record{DataFields: []DataField{
+		{Tag: "020", Subfields: []Subfield{{Code: "a", Value: "84-376-0494-X"}, {Code: "q", Value: "(rúst.)"}}},
+		// El mismo ISBN en forma de ISBN-13 no se repite
+		{Tag: "020", Subfields: []Subfield{{Code: "a", Value: "9788437604947"}, {Code: "q", Value: "rúst."}}},
+		{Tag: "020", Subfields: []Subfield{{Code: "z", Value: "8437604941"}, {Code: "c", Value: "12 €"}}},
+		{Tag: "022", Subfields: []Subfield{{Code: "a", Value: "0378-5955"}, {Code: "y", Value: "0378-5954"}, {Code: "l", Value: "0378-5955"}}},
+		{Tag: "024", Subfields: []Subfield{{Code: "a", Value: "9788437604947"}}},
+	}}
+
+	want := []StandardNumber{
+		{Type: NumberISBN, Value: "9788437604947", ISBN10: "843760494X", Qualifier: "rúst.", Tag: "020", Subfield: "a", Valid: true},
+		{Type: NumberISBN, Value: "8437604941", Tag: "020", Subfield: "z"},
+		{Type: NumberISSN, Value: "0378-5955", Tag: "022", Subfield: "a", Valid: true},
+		{Type: NumberISSN, Value: "03785954", Tag: "022", Subfield: "y"},
+	}
+	numbers := StandardNumbers(record)
+	if !reflect.DeepEqual(numbers, want) {
+		t.Fatalf("StandardNumbers = %+v\nse esperaba %+v", numbers, want)
+	}
+
+	// Solo los $a inválidos son errores de calidad del registro
+	for _, number := range numbers {
+		if number.Invalid() {
+			t.Errorf("%+v marcado como inválido", number)
+		}
+	}
+	if invalid := (StandardNumber{Subfield: "a"}); !invalid.Invalid() {
+		t.Error("$a no válido sin marcar como inválido")
+	}
+}
+
+func TestISBN13(t *testing.T) {
+	for isbn10, want := range map[string]string{
+		"843760494X": "9788437604947",
+		"0306406152": "9780306406157",
+	} {
+		if got := ISBN13(isbn10); got != want {
+			t.Errorf("ISBN13(%s) = %s, se esperaba %s", isbn10, got, want)
+		}
+	}
+}